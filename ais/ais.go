@@ -17,12 +17,11 @@ func (c *Client) GetAis() (StreamResponse[AisMultiple], error) {
 
 // GetAisContext carries out GET against /v1/ais with a context for cancellation.
 func (c *Client) GetAisContext(ctx context.Context) (StreamResponse[AisMultiple], error) {
-	req, err := http.NewRequest("GET", c.urls.AIS(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.urls.AIS(), nil)
 	if err != nil {
 		return StreamResponse[AisMultiple]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
 	return StreamResponse[AisMultiple]{Response: res, ctx: ctx, streamType: Simple}, err
 }
@@ -38,12 +37,11 @@ func (c *Client) PostAisContext(ctx context.Context, filterInput FilterInput) (S
 	if err := json.NewEncoder(body).Encode(filterInput); err != nil {
 		return StreamResponse[AisMultiple]{}, err
 	}
-	req, err := http.NewRequest("POST", c.urls.AIS(), body)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.urls.AIS(), body)
 	if err != nil {
 		return StreamResponse[AisMultiple]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
 	return StreamResponse[AisMultiple]{Response: res, ctx: ctx, streamType: Simple}, err
 }
@@ -55,12 +53,11 @@ func (c *Client) GetSSEAis() (StreamResponse[AisMultiple], error) {
 
 // GetSSEAisContext carries out GET against /v1/sse/ais with a context for cancellation.
 func (c *Client) GetSSEAisContext(ctx context.Context) (StreamResponse[AisMultiple], error) {
-	req, err := http.NewRequest("GET", c.urls.SSEAIS(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.urls.SSEAIS(), nil)
 	if err != nil {
 		return StreamResponse[AisMultiple]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
 	return StreamResponse[AisMultiple]{Response: res, ctx: ctx, streamType: SSE}, err
 }
@@ -75,12 +72,11 @@ func (c *Client) PostSSEAisContext(ctx context.Context, filterInput FilterInput)
 	if err := json.NewEncoder(body).Encode(filterInput); err != nil {
 		return StreamResponse[AisMultiple]{}, err
 	}
-	req, err := http.NewRequest("POST", c.urls.AIS(), body)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.urls.AIS(), body)
 	if err != nil {
 		return StreamResponse[AisMultiple]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
 	return StreamResponse[AisMultiple]{Response: res, ctx: ctx, streamType: Simple}, err
 }
@@ -92,12 +88,11 @@ func (c *Client) GetCombined() (StreamResponse[CombinedSimpleJson], error) {
 
 // GetCombinedContext carries out GET against /v1/combined with a context for cancellation.
 func (c *Client) GetCombinedContext(ctx context.Context) (StreamResponse[CombinedSimpleJson], error) {
-	req, err := http.NewRequest("GET", c.urls.Combined(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.urls.Combined(), nil)
 	if err != nil {
 		return StreamResponse[CombinedSimpleJson]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
 	return StreamResponse[CombinedSimpleJson]{Response: res, ctx: ctx, streamType: Simple}, err
 }
@@ -108,19 +103,25 @@ func (c *Client) PostCombined(filterInput CombinedFilterInput) (StreamResponse[C
 }
 
 // PostCombinedContext carries out POST against /v1/combined with a context for cancellation.
+//
+// The messages of the stream are decoded into the type requested by the filter's ModelType and ModelFormat. If both are
+// empty, the type is deduced from every message, and if only one is, or either is unknown, an error is returned.
 func (c *Client) PostCombinedContext(ctx context.Context, filterInput CombinedFilterInput) (StreamResponse[CombinedMultiple], error) {
+	typ, err := filterInput.combinedType()
+	if err != nil {
+		return StreamResponse[CombinedMultiple]{}, err
+	}
 	body := &bytes.Buffer{}
 	if err := json.NewEncoder(body).Encode(filterInput); err != nil {
 		return StreamResponse[CombinedMultiple]{}, err
 	}
-	req, err := http.NewRequest("POST", c.urls.Combined(), body)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.urls.Combined(), body)
 	if err != nil {
 		return StreamResponse[CombinedMultiple]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
-	return StreamResponse[CombinedMultiple]{Response: res, ctx: ctx, streamType: Simple}, err
+	return StreamResponse[CombinedMultiple]{Response: res, ctx: ctx, streamType: Simple, combinedType: typ}, err
 }
 
 // GetSSECombined carries out GET against /v1/combined
@@ -130,12 +131,11 @@ func (c *Client) GetSSECombined() (StreamResponse[CombinedSimpleJson], error) {
 
 // GetSSECombinedContext carries out GET against /v1/combined with a context for cancellation.
 func (c *Client) GetSSECombinedContext(ctx context.Context) (StreamResponse[CombinedSimpleJson], error) {
-	req, err := http.NewRequest("GET", c.urls.SSECombined(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.urls.SSECombined(), nil)
 	if err != nil {
 		return StreamResponse[CombinedSimpleJson]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
 	return StreamResponse[CombinedSimpleJson]{Response: res, ctx: ctx, streamType: SSE}, err
 }
@@ -146,19 +146,25 @@ func (c *Client) PostSSECombined(filterInput CombinedFilterInput) (StreamRespons
 }
 
 // PostSSECombinedContext carries out POST against /v1/combined with a context for cancellation.
+//
+// The messages of the stream are decoded into the type requested by the filter's ModelType and ModelFormat. If both are
+// empty, the type is deduced from every message, and if only one is, or either is unknown, an error is returned.
 func (c *Client) PostSSECombinedContext(ctx context.Context, filterInput CombinedFilterInput) (StreamResponse[CombinedMultiple], error) {
+	typ, err := filterInput.combinedType()
+	if err != nil {
+		return StreamResponse[CombinedMultiple]{}, err
+	}
 	body := &bytes.Buffer{}
 	if err := json.NewEncoder(body).Encode(filterInput); err != nil {
 		return StreamResponse[CombinedMultiple]{}, err
 	}
-	req, err := http.NewRequest("POST", c.urls.SSECombined(), body)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.urls.SSECombined(), body)
 	if err != nil {
 		return StreamResponse[CombinedMultiple]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
-	return StreamResponse[CombinedMultiple]{Response: res, ctx: ctx, streamType: SSE}, err
+	return StreamResponse[CombinedMultiple]{Response: res, ctx: ctx, streamType: SSE, combinedType: typ}, err
 }
 
 // GetLatestAis carries out GET against /v1/latest/ais
@@ -168,12 +174,11 @@ func (c *Client) GetLatestAis(opts ...option.Option) (Response[[]AisMultiple], e
 
 // GetLatestAisContext carries out GET against /v1/latest/ais with a context for cancellation.
 func (c *Client) GetLatestAisContext(ctx context.Context, opts ...option.Option) (Response[[]AisMultiple], error) {
-	req, err := http.NewRequest("GET", c.urls.LatestAIS(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.urls.LatestAIS(), nil)
 	if err != nil {
 		return Response[[]AisMultiple]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	for _, opt := range opts {
 		opt(req)
 	}
@@ -192,12 +197,11 @@ func (c *Client) PostLatestAisContext(ctx context.Context, filter LatestAisFilte
 	if err := json.NewEncoder(body).Encode(filter); err != nil {
 		return Response[[]AisMultiple]{}, err
 	}
-	req, err := http.NewRequest("POST", c.urls.LatestAIS(), body)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.urls.LatestAIS(), body)
 	if err != nil {
 		return Response[[]AisMultiple]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
 	return Response[[]AisMultiple]{res}, err
 }
@@ -209,12 +213,11 @@ func (c *Client) GetLatestCombined(opts ...option.Option) (Response[[]CombinedSi
 
 // GetLatestCombinedContext carries out GET against /v1/latest/combined with a context for cancellation.
 func (c *Client) GetLatestCombinedContext(ctx context.Context, opts ...option.Option) (Response[[]CombinedSimpleJson], error) {
-	req, err := http.NewRequest("GET", c.urls.LatestCombined(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.urls.LatestCombined(), nil)
 	if err != nil {
 		return Response[[]CombinedSimpleJson]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	for _, opt := range opts {
 		opt(req)
 	}
@@ -222,14 +225,14 @@ func (c *Client) GetLatestCombinedContext(ctx context.Context, opts ...option.Op
 	return Response[[]CombinedSimpleJson]{res}, err
 }
 
-// GetOpenAisArea carries out GET against /v1/openaisarea with a context for cancellation.
+// GetOpenAisArea carries out GET against /v1/openaisarea with a context for cancellation. OpenArea returns the area
+// decoded, and caches it.
 func (c *Client) GetOpenAisArea(ctx context.Context) (Response[geojson.Geometry], error) {
-	req, err := http.NewRequest("GET", c.urls.OpenAISArea(), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.urls.OpenAISArea(), nil)
 	if err != nil {
 		return Response[geojson.Geometry]{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.WithContext(ctx)
 	res, err := c.httpClient.Do(req)
 	return Response[geojson.Geometry]{res}, err
 }
@@ -1,9 +1,13 @@
 package ais_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"iter"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -13,6 +17,8 @@ import (
 
 	"github.com/ilder-as/go-barentswatch-ais/ais"
 	"github.com/ilder-as/go-barentswatch-ais/ais/option"
+	"github.com/ilder-as/go-barentswatch-ais/modelformat"
+	"github.com/ilder-as/go-barentswatch-ais/modeltype"
 	"github.com/ilder-as/go-barentswatch-ais/responsetype"
 	"golang.org/x/oauth2"
 )
@@ -409,3 +415,455 @@ func TestClient_GetLatestAis(t *testing.T) {
 		t.Errorf("expected response to be non-empty list, list had %d members", len(data))
 	}
 }
+
+func TestStreamResponse_All(t *testing.T) {
+	filename := "testdata/get_sse_ais.txt"
+	sv := fixtureServer(t, filename)
+	defer sv.Close()
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	client := ais.NewClient("", "", urls)
+
+	stream, err := client.GetSSEAis()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	num := 0
+	for data, err := range stream.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.IsZero() {
+			t.Error("got zero data")
+		}
+		num++
+	}
+
+	if num <= 0 {
+		t.Errorf("expected > 0 results, got %d", num)
+	}
+
+	if err = stream.Error(); !ais.IsEOF(err) {
+		t.Fatalf("expected EOF, got \"%s\"", err)
+	}
+}
+
+func TestStreamResponse_All_Broken(t *testing.T) {
+	filename := "testdata/combined_broken.txt"
+	sv := fixtureServer(t, filename)
+	defer sv.Close()
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	client := ais.NewClient("", "", urls)
+
+	stream, err := client.PostCombined(ais.CombinedFilterInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lastErr error
+	for _, err := range stream.All() {
+		lastErr = err
+	}
+
+	if lastErr == nil || lastErr != stream.Error() {
+		t.Fatalf("expected final element to carry the stream error, got \"%s\"", lastErr)
+	}
+}
+
+func TestElements(t *testing.T) {
+	filename := "testdata/get_latest_ais.txt"
+	sv := hijackServer(t, filename)
+	defer sv.Close()
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	client := ais.NewClient("", "", urls)
+
+	res, err := client.GetLatestAis()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	num := 0
+	for data, err := range ais.Elements(res) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.IsZero() {
+			t.Error("got zero data")
+		}
+		num++
+	}
+
+	if num <= 0 {
+		t.Errorf("expected response to be non-empty list, list had %d members", num)
+	}
+}
+
+// silentHandler writes a single message and then goes silent without closing the connection, until the request is
+// cancelled or the test ends.
+func silentHandler(done <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"Position","messageType":1,"mmsi":257161000,"msgtime":"2023-02-20T13:13:55.6209511+00:00"}` + "\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}
+}
+
+func TestStreamResponse_Stalled(t *testing.T) {
+	done := make(chan struct{})
+	sv := server(t, oauthSpoofMW(silentHandler(done)))
+	defer sv.Close()
+	defer close(done)
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	client := ais.NewClient("", "", urls)
+
+	stream, err := client.GetAis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.IdleTimeout = 100 * time.Millisecond
+
+	ch, err := stream.UnmarshalStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	num := 0
+	for range ch {
+		num++
+	}
+
+	if num != 1 {
+		t.Errorf("expected 1 result, got %d", num)
+	}
+	if err = stream.Error(); !errors.Is(err, ais.ErrStalled) {
+		t.Fatalf("expected stalled stream, got \"%s\"", err)
+	}
+}
+
+func TestStreamResponse_SlowConsumer(t *testing.T) {
+	sv := server(t, oauthSpoofMW(func(w http.ResponseWriter, r *http.Request) {
+		for range 30 {
+			w.Write([]byte(`{"type":"Position","messageType":1,"mmsi":257161000,"msgtime":"2023-02-20T13:13:55.6209511+00:00"}` + "\n"))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}))
+	defer sv.Close()
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	stream, err := ais.NewClient("", "", urls).GetAis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.IdleTimeout = 200 * time.Millisecond
+
+	// The consumer pauses for longer than the idle timeout while messages keep arriving
+	num := 0
+	for _, err := range stream.All() {
+		if err != nil {
+			break
+		}
+		num++
+		if num == 5 {
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+
+	if num != 30 {
+		t.Errorf("expected 30 results, got %d", num)
+	}
+	if err = stream.Error(); !ais.IsEOF(err) {
+		t.Fatalf("expected EOF, got \"%s\"", err)
+	}
+}
+
+func TestStreamResponse_CancelBlockedRead(t *testing.T) {
+	done := make(chan struct{})
+	sv := server(t, oauthSpoofMW(silentHandler(done)))
+	defer sv.Close()
+	defer close(done)
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	client := ais.NewClient("", "", urls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.GetAisContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ended := make(chan struct{})
+	go func() {
+		defer close(ended)
+		for range stream.All() {
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-ended:
+	case <-time.After(time.Second):
+		t.Fatal("expected cancellation to interrupt blocked read")
+	}
+
+	if err = stream.Error(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context cancellation, got \"%s\"", err)
+	}
+}
+
+func TestStreamResponse_SkipLine(t *testing.T) {
+	filename := "testdata/combined_broken.txt"
+	sv := fixtureServer(t, filename)
+	defer sv.Close()
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	client := ais.NewClient("", "", urls)
+
+	stream, err := client.PostCombined(ais.CombinedFilterInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.LinePolicy = ais.SkipLine
+	var lineErrs []*ais.LineError
+	stream.OnLineError = func(err *ais.LineError) {
+		lineErrs = append(lineErrs, err)
+	}
+
+	num := 0
+	for _, err := range stream.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		num++
+	}
+
+	if num <= 0 {
+		t.Errorf("expected > 0 results, got %d", num)
+	}
+	if len(lineErrs) != 1 || lineErrs[0].Line != num+1 || len(lineErrs[0].Data) == 0 {
+		t.Errorf("expected the last line to be reported as broken, got %v", lineErrs)
+	}
+	if err = stream.Error(); !ais.IsEOF(err) {
+		t.Fatalf("expected EOF, got \"%s\"", err)
+	}
+}
+
+func TestStreamResponse_MaxLineSize(t *testing.T) {
+	filename := "testdata/get_ais.txt"
+	sv := fixtureServer(t, filename)
+	defer sv.Close()
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	client := ais.NewClient("", "", urls)
+
+	// Position messages in the fixture are shorter than 300 bytes, whereas static data messages are longer
+	stream, err := client.GetAis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.MaxLineSize = 300
+	stream.LinePolicy = ais.SkipLine
+	tooLong := 0
+	stream.OnLineError = func(err *ais.LineError) {
+		if !errors.Is(err, bufio.ErrTooLong) {
+			t.Errorf("expected line to be too long, got %s", err)
+		}
+		tooLong++
+	}
+
+	num := 0
+	for data, err := range stream.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data.IsZero() {
+			t.Error("got zero data")
+		}
+		num++
+	}
+
+	if num <= 0 || tooLong <= 0 {
+		t.Errorf("expected both decoded and skipped lines, got %d and %d", num, tooLong)
+	}
+	if err = stream.Error(); !ais.IsEOF(err) {
+		t.Fatalf("expected EOF, got \"%s\"", err)
+	}
+
+	// The fixture server only serves its content once
+	sv2 := fixtureServer(t, filename)
+	defer sv2.Close()
+	urls.OAuthBase = sv2.URL
+	urls.APIBase = sv2.URL
+
+	stream, err = ais.NewClient("", "", urls).GetAis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.MaxLineSize = 300
+	for range stream.All() {
+	}
+
+	var lineErr *ais.LineError
+	if err = stream.Error(); !errors.As(err, &lineErr) || !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatalf("expected stream to be aborted by a line which is too long, got \"%s\"", err)
+	}
+}
+
+func TestPostCombined_RequestedType(t *testing.T) {
+	filename := "testdata/combined_full_json.txt"
+	filter, err := ais.NewCombinedFilterInput().ModelType(modeltype.ModelTypeS).ModelFormat(modelformat.Json).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sv := fixtureServer(t, filename)
+	defer sv.Close()
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	stream, err := ais.NewClient("", "", urls).PostCombined(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	num := 0
+	for a, err := range stream.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Type != responsetype.SimpleJson {
+			t.Fatalf("expected requested type SimpleJson, got %s", a.Type)
+		}
+		if a.AsSimpleJson().IsZero() || !a.AsFullJson().IsZero() {
+			t.Fatal("expected message to be unmarshalled into CombinedSimpleJson only")
+		}
+		num++
+	}
+	if num <= 0 {
+		t.Errorf("expected to find results, found %d", num)
+	}
+
+	// In strict mode, the fields of the full type are not accepted in the simple type
+	sv2 := fixtureServer(t, filename)
+	defer sv2.Close()
+	urls.OAuthBase = sv2.URL
+	urls.APIBase = sv2.URL
+
+	stream, err = ais.NewClient("", "", urls).PostCombined(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Strict = true
+	for range stream.All() {
+	}
+	var lineErr *ais.LineError
+	if err := stream.Error(); !errors.As(err, &lineErr) || lineErr.Line != 1 || !strings.Contains(err.Error(), "unknown field") {
+		t.Errorf("expected unknown field error on line 1, got %v", err)
+	}
+}
+
+func TestStreamResponse_Strict(t *testing.T) {
+	client := func(filename string) *ais.Client {
+		sv := fixtureServer(t, filename)
+		t.Cleanup(sv.Close)
+		urls := ais.DefaultURLs()
+		urls.OAuthBase = sv.URL
+		urls.APIBase = sv.URL
+		return ais.NewClient("", "", urls)
+	}
+
+	aisStream, err := client("testdata/get_ais.txt").GetAis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	aisStream.Strict = true
+	if n := count(aisStream.All()); n <= 0 || !ais.IsEOF(aisStream.Error()) {
+		t.Errorf("expected strict AIS stream to end with EOF after results, got %d results and %v", n, aisStream.Error())
+	}
+
+	filter := ais.CombinedFilterInput{ModelType: modeltype.ModelTypeFull, ModelFormat: modelformat.Geojson}
+	combinedStream, err := client("testdata/combined_full_geojson.txt").PostCombined(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	combinedStream.Strict = true
+	if n := count(combinedStream.All()); n <= 0 || !ais.IsEOF(combinedStream.Error()) {
+		t.Errorf("expected strict combined stream to end with EOF after results, got %d results and %v", n, combinedStream.Error())
+	}
+
+	// The type is deduced from the messages when it is not requested
+	combinedStream, err = client("testdata/combined_simple_json.txt").PostCombined(ais.CombinedFilterInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	combinedStream.Strict = true
+	if n := count(combinedStream.All()); n <= 0 || !ais.IsEOF(combinedStream.Error()) {
+		t.Errorf("expected strict combined stream to end with EOF after results, got %d results and %v", n, combinedStream.Error())
+	}
+}
+
+func TestClient_PostCombined_UnknownType(t *testing.T) {
+	client := ais.NewClient("", "", ais.DefaultURLs())
+	for _, filter := range []ais.CombinedFilterInput{
+		{ModelType: modeltype.ModelTypeFull},
+		{ModelType: "Extended", ModelFormat: modelformat.Json},
+	} {
+		if _, err := client.PostCombined(filter); err == nil {
+			t.Errorf("expected an error for the model type and format of %+v", filter)
+		}
+		if _, err := client.PostSSECombined(filter); err == nil {
+			t.Errorf("expected an error for the model type and format of %+v", filter)
+		}
+	}
+}
+
+// count counts the elements of an iterator until the first error.
+func count[T any](seq iter.Seq2[T, error]) int {
+	n := 0
+	for _, err := range seq {
+		if err != nil {
+			break
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,367 @@
+// Package behaviour labels the tracks of vessels with their behaviour: transit, loitering or trawling.
+//
+// A Classifier keeps a sliding window of the latest positions of every vessel, and classifies the window every time a
+// vessel reports its position, from the speed, the net displacement and straightness of its track, and how often it
+// reverses its course:
+//
+//   - Transit is sailing at speed along a fairly straight track.
+//   - Loitering is staying in a small area at low speed for a long time, as when waiting for a rendezvous.
+//   - Trawling is sailing at trawling speed with frequent reversals of course, as when towing a trawl back and forth
+//     over a fishing ground.
+//
+// Consecutive positions with the same label are merged into segments, which are reported with the mean confidence of
+// their classifications:
+//
+//	c := behaviour.NewClassifier()
+//	c.Categories[shiptype.CategoryFishing] = fishingThresholds
+//	for _, msg := range msgs {
+//		segments = append(segments, c.Add(msg.Vessel())...)
+//	}
+//	segments = append(segments, c.Flush()...)
+package behaviour
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/navmath"
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+)
+
+// Label is a behaviour.
+type Label int
+
+const (
+	// Unknown is behaviour which none of the other labels fit with enough confidence.
+	Unknown Label = iota
+	Transit
+	Loitering
+	Trawling
+)
+
+func (l Label) String() string {
+	switch l {
+	case Unknown:
+		return "Unknown"
+	case Transit:
+		return "Transit"
+	case Loitering:
+		return "Loitering"
+	case Trawling:
+		return "Trawling"
+	}
+	return fmt.Sprintf("Label(%d)", int(l))
+}
+
+// Segment is a part of the track of a vessel with the same label.
+type Segment struct {
+	Mmsi  int
+	Label Label
+	// Start and End are the times of the first and last positions with the label.
+	Start time.Time
+	End   time.Time
+	// Confidence is the mean confidence of the classifications of the positions, between 0 and 1. The confidence of
+	// Unknown is the confidence that none of the other labels fit.
+	Confidence float64
+	// Positions is the number of positions with the label.
+	Positions int
+}
+
+// Thresholds are the thresholds for classifying the behaviour of vessels. Zero fields take the values of
+// DefaultThresholds.
+type Thresholds struct {
+	// Window is the length of the sliding window of positions which is classified.
+	Window time.Duration
+	// MinConfidence is the lowest confidence of a label. Positions whose best label has a lower confidence are
+	// labelled Unknown.
+	MinConfidence float64
+
+	// TransitSpeed is the lowest mean speed over ground of transit, in knots.
+	TransitSpeed float64
+	// TransitStraightness is the lowest ratio of net displacement to distance sailed of transit, between 0 and 1.
+	TransitStraightness float64
+
+	// LoiterDisplacement is the largest net displacement of loitering over the window, in metres.
+	LoiterDisplacement float64
+	// LoiterSpeed is the highest mean speed over ground of loitering, in knots.
+	LoiterSpeed float64
+
+	// TrawlMinSpeed and TrawlMaxSpeed are the range of mean speeds over ground of trawling, in knots.
+	TrawlMinSpeed float64
+	TrawlMaxSpeed float64
+	// TrawlReversals is the lowest number of reversals of course per hour of trawling.
+	TrawlReversals float64
+	// ReversalAngle is the smallest change of course which is a reversal, in degrees.
+	ReversalAngle float64
+}
+
+// DefaultThresholds are the thresholds of vessels whose category has no thresholds of its own.
+var DefaultThresholds = Thresholds{
+	Window:              2 * time.Hour,
+	MinConfidence:       0.5,
+	TransitSpeed:        6,
+	TransitStraightness: 0.8,
+	LoiterDisplacement:  2000,
+	LoiterSpeed:         2,
+	TrawlMinSpeed:       2,
+	TrawlMaxSpeed:       5,
+	TrawlReversals:      1,
+	ReversalAngle:       150,
+}
+
+// withDefaults returns the thresholds with zero fields replaced by those of DefaultThresholds.
+func (t Thresholds) withDefaults() Thresholds {
+	d := DefaultThresholds
+	return Thresholds{
+		Window:              cmp.Or(t.Window, d.Window),
+		MinConfidence:       cmp.Or(t.MinConfidence, d.MinConfidence),
+		TransitSpeed:        cmp.Or(t.TransitSpeed, d.TransitSpeed),
+		TransitStraightness: cmp.Or(t.TransitStraightness, d.TransitStraightness),
+		LoiterDisplacement:  cmp.Or(t.LoiterDisplacement, d.LoiterDisplacement),
+		LoiterSpeed:         cmp.Or(t.LoiterSpeed, d.LoiterSpeed),
+		TrawlMinSpeed:       cmp.Or(t.TrawlMinSpeed, d.TrawlMinSpeed),
+		TrawlMaxSpeed:       cmp.Or(t.TrawlMaxSpeed, d.TrawlMaxSpeed),
+		TrawlReversals:      cmp.Or(t.TrawlReversals, d.TrawlReversals),
+		ReversalAngle:       cmp.Or(t.ReversalAngle, d.ReversalAngle),
+	}
+}
+
+// sample is a position in a window.
+type sample struct {
+	time     time.Time
+	lon, lat float64
+	sog      *float64
+	// course is the course over ground, or the bearing from the previous sample, if known
+	course *float64
+}
+
+func (s sample) point() navmath.Point {
+	return navmath.Point{Latitude: s.lat, Longitude: s.lon}
+}
+
+// vessel is what a Classifier knows about a vessel.
+type vessel struct {
+	shipType *int
+	window   []sample
+	// segment is the segment in progress, if any, and confidence the sum of the confidences of its positions
+	segment    *Segment
+	confidence float64
+}
+
+// Classifier labels the behaviour of vessels.
+//
+// Positions are only labelled once the window of a vessel spans at least half of Window, so the first positions of
+// every vessel, and those after a gap of half of Window or more, are not labelled.
+//
+// Messages of each vessel must be given in order of time, and older messages are ignored.
+//
+// A Classifier must be constructed with the NewClassifier factory function. It is not safe for concurrent use, and
+// its fields must be set before it is used.
+type Classifier struct {
+	vessels map[int]*vessel
+
+	// Categories are the thresholds of vessels of each ship type category. Vessels whose ship type is not known, or
+	// whose category has no thresholds, use DefaultThresholds.
+	Categories map[shiptype.Category]Thresholds
+}
+
+// NewClassifier creates a Classifier.
+func NewClassifier() *Classifier {
+	return &Classifier{
+		vessels:    make(map[int]*vessel),
+		Categories: make(map[shiptype.Category]Thresholds),
+	}
+}
+
+func (c *Classifier) thresholds(vs *vessel) Thresholds {
+	if vs.shipType != nil {
+		if t, ok := c.Categories[shiptype.ShipType(*vs.shipType).Category()]; ok {
+			return t.withDefaults()
+		}
+	}
+	return DefaultThresholds.withDefaults()
+}
+
+// Add updates what the Classifier knows about the vessel, and returns the segment which ended, if any. Static data
+// updates the ship type of the vessel, and positions are labelled.
+func (c *Classifier) Add(v ais.Vessel) []Segment {
+	vs, ok := c.vessels[v.Mmsi]
+	if !ok {
+		vs = &vessel{}
+		c.vessels[v.Mmsi] = vs
+	}
+	if v.ShipType != nil {
+		vs.shipType = v.ShipType
+	}
+	if v.Latitude == nil || v.Longitude == nil {
+		return nil
+	}
+	if n := len(vs.window); n > 0 && v.Msgtime.Before(vs.window[n-1].time) {
+		return nil
+	}
+
+	t := c.thresholds(vs)
+	s := sample{time: v.Msgtime, lon: *v.Longitude, lat: *v.Latitude, sog: v.SpeedOverGround, course: v.CourseOverGround}
+	if n := len(vs.window); n > 0 && s.course == nil {
+		prev := vs.window[n-1]
+		if prev.lon != s.lon || prev.lat != s.lat {
+			course := navmath.InitialBearing(prev.point(), s.point())
+			s.course = &course
+		}
+	}
+
+	// A gap of half the window or more starts a new window
+	var res []Segment
+	if n := len(vs.window); n > 0 && s.time.Sub(vs.window[n-1].time) >= t.Window/2 {
+		vs.window = vs.window[:0]
+		res = append(res, c.end(vs)...)
+	}
+	vs.window = append(vs.window, s)
+	i := 0
+	for s.time.Sub(vs.window[i].time) > t.Window {
+		i++
+	}
+	vs.window = slices.Delete(vs.window, 0, i)
+	if s.time.Sub(vs.window[0].time) < t.Window/2 {
+		return res
+	}
+
+	label, confidence := classify(vs.window, t)
+	if vs.segment != nil && vs.segment.Label != label {
+		res = append(res, c.end(vs)...)
+	}
+	if vs.segment == nil {
+		vs.segment = &Segment{Mmsi: v.Mmsi, Label: label, Start: s.time}
+	}
+	vs.segment.End = s.time
+	vs.segment.Positions++
+	vs.confidence += confidence
+	vs.segment.Confidence = vs.confidence / float64(vs.segment.Positions)
+	return res
+}
+
+// end ends the segment in progress, if any, and returns it.
+func (c *Classifier) end(vs *vessel) []Segment {
+	if vs.segment == nil {
+		return nil
+	}
+	s := *vs.segment
+	vs.segment, vs.confidence = nil, 0
+	return []Segment{s}
+}
+
+// Flush ends the segments in progress, and returns them ordered by MMSI.
+func (c *Classifier) Flush() []Segment {
+	var res []Segment
+	for _, vs := range c.vessels {
+		res = append(res, c.end(vs)...)
+	}
+	slices.SortFunc(res, func(a, b Segment) int { return cmp.Compare(a.Mmsi, b.Mmsi) })
+	return res
+}
+
+// ramp returns 0 for x at most lo, 1 for x at least hi, and rises linearly in between.
+func ramp(x, lo, hi float64) float64 {
+	if hi <= lo {
+		if x >= hi {
+			return 1
+		}
+		return 0
+	}
+	return max(0, min(1, (x-lo)/(hi-lo)))
+}
+
+// Metrics are the measures of a window of positions which it is classified by.
+type Metrics struct {
+	Duration time.Duration
+	// Distance is the distance sailed between the positions, and Displacement the distance from the first to the
+	// last, in metres.
+	Distance     float64
+	Displacement float64
+	// MeanSpeed is the mean speed over ground reported, in knots, or the speed made good if none is reported.
+	MeanSpeed float64
+	// Reversals is the number of reversals of course per hour.
+	Reversals float64
+}
+
+// Straightness returns the ratio of displacement to distance, or 1 if the vessel did not move.
+func (m Metrics) Straightness() float64 {
+	if m.Distance == 0 {
+		return 1
+	}
+	return m.Displacement / m.Distance
+}
+
+func measure(window []sample, reversalAngle float64) Metrics {
+	first, last := window[0], window[len(window)-1]
+	m := Metrics{
+		Duration:     last.time.Sub(first.time),
+		Displacement: navmath.Distance(first.point(), last.point()),
+	}
+
+	var speedSum float64
+	var speeds, reversals int
+	var reference *float64
+	for i, s := range window {
+		if i > 0 {
+			m.Distance += navmath.Distance(window[i-1].point(), s.point())
+		}
+		if s.sog != nil {
+			speedSum += *s.sog
+			speeds++
+		}
+		if s.course != nil {
+			if reference == nil {
+				reference = s.course
+			} else if math.Abs(math.Remainder(*s.course-*reference, 360)) >= reversalAngle {
+				reversals++
+				reference = s.course
+			}
+		}
+	}
+
+	hours := m.Duration.Hours()
+	switch {
+	case speeds > 0:
+		m.MeanSpeed = speedSum / float64(speeds)
+	case hours > 0:
+		m.MeanSpeed = navmath.MetresToNauticalMiles(m.Distance) / hours
+	}
+	if hours > 0 {
+		m.Reversals = float64(reversals) / hours
+	}
+	return m
+}
+
+// Scores returns the confidence of each label for the metrics, between 0 and 1.
+func (t Thresholds) Scores(m Metrics) map[Label]float64 {
+	t = t.withDefaults()
+	trawlSpeed := ramp(m.MeanSpeed, t.TrawlMinSpeed-1, t.TrawlMinSpeed) *
+		(1 - ramp(m.MeanSpeed, t.TrawlMaxSpeed, t.TrawlMaxSpeed+1))
+	return map[Label]float64{
+		Transit: ramp(m.MeanSpeed, t.TransitSpeed/2, t.TransitSpeed) *
+			ramp(m.Straightness(), t.TransitStraightness/2, t.TransitStraightness),
+		Loitering: (1 - ramp(m.Displacement, t.LoiterDisplacement, 2*t.LoiterDisplacement)) *
+			(1 - ramp(m.MeanSpeed, t.LoiterSpeed, t.LoiterSpeed+1)),
+		Trawling: trawlSpeed * ramp(m.Reversals, t.TrawlReversals/2, t.TrawlReversals),
+	}
+}
+
+// classify returns the label of the window with the highest confidence, or Unknown.
+func classify(window []sample, t Thresholds) (Label, float64) {
+	scores := t.Scores(measure(window, t.ReversalAngle))
+	label, confidence := Unknown, 0.0
+	for _, l := range []Label{Trawling, Loitering, Transit} {
+		if scores[l] > confidence {
+			label, confidence = l, scores[l]
+		}
+	}
+	if confidence < t.MinConfidence {
+		return Unknown, 1 - confidence
+	}
+	return label, confidence
+}
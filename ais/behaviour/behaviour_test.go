@@ -0,0 +1,140 @@
+package behaviour_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/behaviour"
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/aistest"
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+)
+
+// leg is a part of a synthetic track, sailed at a constant speed and course for a number of minutes.
+type leg struct {
+	minutes int
+	speed   float64
+	course  float64
+}
+
+// synthetic returns a track with one position a minute, starting at 70°N, 20°E at from after aistest.Start, along
+// the legs.
+func synthetic(mmsi int, from time.Duration, legs ...leg) []ais.Vessel {
+	var res []ais.Vessel
+	lon, lat := 20.0, 70.0
+	at := from
+	for _, l := range legs {
+		for range l.minutes {
+			metres := l.speed * 1852 / 60
+			lat += metres * math.Cos(l.course*math.Pi/180) / 111195
+			lon += metres * math.Sin(l.course*math.Pi/180) / (111195 * math.Cos(lat*math.Pi/180))
+			at += time.Minute
+			p := aistest.Position(mmsi, at, lon, lat, l.speed)
+			p.CourseOverGround = &l.course
+			res = append(res, p.Vessel())
+		}
+	}
+	return res
+}
+
+// transit sails north-east at 12 knots.
+func transit(minutes int) []leg {
+	return []leg{{minutes, 12, 45}}
+}
+
+// loiter circles slowly in an area a few hundred metres across.
+func loiter(minutes int) []leg {
+	var res []leg
+	for i := range minutes {
+		res = append(res, leg{1, 0.8, float64(i*12) + 0.5})
+	}
+	return res
+}
+
+// trawl tows back and forth over a fishing ground at 3.5 knots, reversing every 20 minutes.
+func trawl(minutes int) []leg {
+	var res []leg
+	for i := range minutes / 20 {
+		res = append(res, leg{20, 3.5, float64(i%2) * 180})
+	}
+	return res
+}
+
+func classify(c *behaviour.Classifier, tracks ...[]ais.Vessel) []behaviour.Segment {
+	var res []behaviour.Segment
+	for _, track := range tracks {
+		for _, v := range track {
+			res = append(res, c.Add(v)...)
+		}
+	}
+	return append(res, c.Flush()...)
+}
+
+func TestClassifier(t *testing.T) {
+	tests := []struct {
+		name string
+		legs []leg
+		want behaviour.Label
+	}{
+		{"transit", transit(240), behaviour.Transit},
+		{"loiter", loiter(240), behaviour.Loitering},
+		{"trawl", trawl(240), behaviour.Trawling},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			segments := classify(behaviour.NewClassifier(), synthetic(1, 0, test.legs...))
+			if len(segments) != 1 {
+				t.Fatalf("expected a single segment, got %+v", segments)
+			}
+			s := segments[0]
+			if s.Label != test.want || s.Confidence < 0.5 {
+				t.Errorf("expected %s, got %s with confidence %f", test.want, s.Label, s.Confidence)
+			}
+			// Positions are labelled once the window spans an hour
+			if !s.Start.Equal(aistest.Start.Add(61*time.Minute)) || !s.End.Equal(aistest.Start.Add(240*time.Minute)) ||
+				s.Positions != 180 {
+				t.Errorf("unexpected extent of %+v", s)
+			}
+		})
+	}
+}
+
+func TestClassifier_Changes(t *testing.T) {
+	segments := classify(behaviour.NewClassifier(), synthetic(1, 0, append(transit(180), trawl(240)...)...))
+	var labels []behaviour.Label
+	for _, s := range segments {
+		if s.Label != behaviour.Unknown {
+			labels = append(labels, s.Label)
+		}
+	}
+	if len(labels) != 2 || labels[0] != behaviour.Transit || labels[1] != behaviour.Trawling {
+		t.Errorf("expected transit followed by trawling, got %+v", segments)
+	}
+	for i := 1; i < len(segments); i++ {
+		if !segments[i].Start.After(segments[i-1].End) {
+			t.Errorf("expected segments not to overlap, got %+v", segments)
+		}
+	}
+
+	// A gap of an hour or more starts a new window, and ends the segment
+	later := synthetic(1, 10*time.Hour, transit(120)...)
+	if segments := classify(behaviour.NewClassifier(), synthetic(1, 0, transit(120)...), later); len(segments) != 2 {
+		t.Errorf("expected the gap to split the track, got %+v", segments)
+	}
+}
+
+func TestClassifier_Categories(t *testing.T) {
+	c := behaviour.NewClassifier()
+	c.Categories[shiptype.CategoryFishing] = behaviour.Thresholds{TrawlReversals: 10}
+	fishing := int(shiptype.Fishing)
+	static := aistest.Staticdata(2, 0, "TRAWLER")
+	static.ShipType = &fishing
+	trawler := append([]ais.Vessel{static.Vessel()}, synthetic(2, 0, trawl(240)...)...)
+
+	for _, s := range classify(c, synthetic(1, 0, trawl(240)...), trawler) {
+		if s.Mmsi == 1 && s.Label != behaviour.Trawling || s.Mmsi == 2 && s.Label == behaviour.Trawling {
+			t.Errorf("expected the thresholds of fishing vessels to apply only to them, got %+v", s)
+		}
+	}
+}
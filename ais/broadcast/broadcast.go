@@ -0,0 +1,333 @@
+// Package broadcast fans out a single upstream stream to many independent subscribers, so that several consumers can
+// share one connection to the API.
+package broadcast
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+)
+
+// Policy decides what happens when a subscriber's buffer is full.
+type Policy int
+
+const (
+	// Block waits for the subscriber to make room in its buffer. Note that this holds back delivery to every other
+	// subscriber, though not Subscribe or Subscribers.
+	Block Policy = iota
+	// DropOldest discards the oldest value in the subscriber's buffer to make room for the new value.
+	DropOldest
+	// DropNewest discards the new value.
+	DropNewest
+	// Disconnect closes the subscription with ErrSlowSubscriber.
+	Disconnect
+)
+
+var (
+	// ErrSlowSubscriber is the reason a subscription with the Disconnect policy is closed when its buffer is full.
+	ErrSlowSubscriber = errors.New("slow subscriber")
+	// ErrUnsubscribed is the reason a subscription is closed when Unsubscribe is called.
+	ErrUnsubscribed = errors.New("unsubscribed")
+)
+
+// Tracker keeps track of the state of the stream, such as the latest message of each vessel. A tracker attached to a
+// Broadcaster is updated with every message, and its snapshot is sent to subscribers which join late.
+type Tracker[T any] interface {
+	// Update is called by the broadcaster with every message.
+	Update(msg T)
+	// Snapshot returns the current state as a list of messages.
+	Snapshot() []T
+}
+
+// Broadcaster owns a single StreamResponse and hands out subscriptions to it.
+//
+// A Broadcaster must be constructed with the New factory function, and started with Run.
+type Broadcaster[T any] struct {
+	stream *ais.StreamResponse[T]
+
+	mu      sync.Mutex
+	subs    map[*Subscription[T]]struct{}
+	tracker Tracker[T]
+	done    bool
+	err     error
+}
+
+// New creates a new Broadcaster for the stream. The stream must not be consumed by anything else.
+func New[T any](stream *ais.StreamResponse[T]) *Broadcaster[T] {
+	return &Broadcaster[T]{
+		stream: stream,
+		subs:   make(map[*Subscription[T]]struct{}),
+	}
+}
+
+// Attach attaches a tracker, which is updated with every message and provides snapshots for late subscribers. It
+// should be called before Run.
+func (b *Broadcaster[T]) Attach(tracker Tracker[T]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tracker = tracker
+}
+
+// Run consumes the stream and sends every message to the subscribers, until the stream ends. It then closes all
+// subscriptions and returns the reason the stream ended, as reported by StreamResponse.Error.
+//
+// To stop the broadcaster, cancel the context of the request which returned the stream.
+func (b *Broadcaster[T]) Run() error {
+	var blocking []*Subscription[T]
+	for msg, err := range b.stream.All() {
+		if err != nil {
+			break
+		}
+
+		b.mu.Lock()
+		if b.tracker != nil {
+			b.tracker.Update(msg)
+		}
+		for s := range b.subs {
+			if s.policy.blocks() {
+				blocking = append(blocking, s)
+			} else if !s.deliver(msg) {
+				b.remove(s, ErrSlowSubscriber)
+			}
+		}
+		b.mu.Unlock()
+
+		// Subscribers with the Block policy are waited for without holding the lock, so that subscriptions can be
+		// made and ended meanwhile
+		for _, s := range blocking {
+			s.deliverBlocking(msg)
+		}
+		clear(blocking)
+		blocking = blocking[:0]
+	}
+
+	err := b.stream.Error()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done = true
+	b.err = err
+	for s := range b.subs {
+		b.remove(s, err)
+	}
+
+	return err
+}
+
+// Subscribe creates a new subscription with room for buffer values, and the given policy for when the buffer is full.
+//
+// If a tracker is attached, the subscription starts with the tracker's snapshot. The buffer is enlarged to hold the
+// snapshot in addition to the requested size. If the broadcaster has already stopped, the subscription is closed,
+// with the reason the stream ended.
+func (b *Broadcaster[T]) Subscribe(buffer int, policy Policy) *Subscription[T] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var snapshot []T
+	if b.tracker != nil {
+		snapshot = b.tracker.Snapshot()
+	}
+	if buffer < 0 {
+		buffer = 0
+	}
+
+	s := &Subscription[T]{
+		b:      b,
+		ch:     make(chan T, buffer+len(snapshot)),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	for _, msg := range snapshot {
+		s.ch <- msg
+	}
+
+	if b.done {
+		s.close(b.err)
+		return s
+	}
+
+	b.subs[s] = struct{}{}
+	return s
+}
+
+// Subscribers returns the number of active subscriptions.
+func (b *Broadcaster[T]) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// remove closes the subscription and removes it from the broadcaster. The caller must hold b.mu.
+func (b *Broadcaster[T]) remove(s *Subscription[T], reason error) {
+	if _, ok := b.subs[s]; !ok {
+		return
+	}
+	delete(b.subs, s)
+	s.close(reason)
+}
+
+// blocks is true if the policy waits for room in the buffer. Unknown policies behave as Block.
+func (p Policy) blocks() bool {
+	switch p {
+	case DropOldest, DropNewest, Disconnect:
+		return false
+	}
+	return true
+}
+
+// Subscription is a single consumer's view of a Broadcaster.
+type Subscription[T any] struct {
+	b      *Broadcaster[T]
+	ch     chan T
+	policy Policy
+
+	// done is closed when the subscription is closed or unsubscribed, and unblocks the Block policy
+	done     chan struct{}
+	doneOnce sync.Once
+	err      error
+
+	// sendMu is held while sending to ch without holding b.mu, and while closing ch, and closed is true once ch is
+	// closed
+	sendMu sync.Mutex
+	closed bool
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+// C returns the channel on which the subscription receives messages. The channel is closed when the subscription
+// ends, and Err returns the reason.
+func (s *Subscription[T]) C() <-chan T {
+	return s.ch
+}
+
+// Err returns the reason the subscription ended: ErrUnsubscribed, ErrSlowSubscriber, or the reason the stream ended.
+// It returns nil while the subscription is active.
+func (s *Subscription[T]) Err() error {
+	select {
+	case <-s.done:
+		s.b.mu.Lock()
+		defer s.b.mu.Unlock()
+		if s.err == nil {
+			// Unsubscribe has closed done, but not yet recorded the reason
+			return ErrUnsubscribed
+		}
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Unsubscribe ends the subscription and closes its channel.
+func (s *Subscription[T]) Unsubscribe() {
+	// Unblock the broadcaster if it is waiting for room in the buffer, before waiting for its lock
+	s.doneOnce.Do(func() { close(s.done) })
+
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	if _, ok := s.b.subs[s]; ok {
+		delete(s.b.subs, s)
+		s.err = ErrUnsubscribed
+		s.closeChannel()
+	}
+}
+
+// close closes the subscription. The caller must hold s.b.mu.
+func (s *Subscription[T]) close(reason error) {
+	s.err = reason
+	s.doneOnce.Do(func() { close(s.done) })
+	s.closeChannel()
+}
+
+// closeChannel closes ch once any send in progress, which is unblocked by closing done, has returned.
+func (s *Subscription[T]) closeChannel() {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.closed = true
+	close(s.ch)
+}
+
+// deliverBlocking sends the message, waiting for room in the buffer until the subscription ends. It must be called
+// without holding s.b.mu.
+func (s *Subscription[T]) deliverBlocking(msg T) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- msg:
+		s.delivered.Add(1)
+	case <-s.done:
+	}
+}
+
+// deliver sends the message according to the subscription's policy, unless the policy blocks, see deliverBlocking.
+// It returns false if the subscriber should be disconnected. The caller must hold s.b.mu.
+func (s *Subscription[T]) deliver(msg T) bool {
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.ch <- msg:
+			s.delivered.Add(1)
+		default:
+			s.dropped.Add(1)
+		}
+	case DropOldest:
+		if cap(s.ch) == 0 {
+			// There is no oldest value to drop in an unbuffered channel
+			select {
+			case s.ch <- msg:
+				s.delivered.Add(1)
+			default:
+				s.dropped.Add(1)
+			}
+			return true
+		}
+		for {
+			select {
+			case s.ch <- msg:
+				s.delivered.Add(1)
+				return true
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	case Disconnect:
+		select {
+		case s.ch <- msg:
+			s.delivered.Add(1)
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Stats holds delivery metrics of a subscription.
+type Stats struct {
+	// Delivered is the number of messages put in the subscriber's buffer, excluding any initial snapshot.
+	Delivered uint64
+	// Dropped is the number of messages discarded by the DropOldest and DropNewest policies.
+	Dropped uint64
+	// Lag is the number of messages waiting in the subscriber's buffer.
+	Lag int
+	// Capacity is the size of the subscriber's buffer.
+	Capacity int
+}
+
+// Stats returns the current delivery metrics of the subscription.
+func (s *Subscription[T]) Stats() Stats {
+	return Stats{
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+		Lag:       len(s.ch),
+		Capacity:  cap(s.ch),
+	}
+}
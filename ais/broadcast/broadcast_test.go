@@ -0,0 +1,160 @@
+package broadcast_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/broadcast"
+	"golang.org/x/oauth2"
+)
+
+func stream(t *testing.T, filename string) ais.StreamResponse[ais.AisMultiple] {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "token") {
+			w.Header().Add("Content-Type", "application/json")
+			token, _ := json.Marshal(oauth2.Token{AccessToken: "x", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)})
+			w.Write(token)
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(sv.Close)
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+
+	s, err := ais.NewClient("", "", urls).GetAis()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// latest tracks the latest message by MMSI
+type latest struct {
+	msgs map[int]ais.AisMultiple
+}
+
+func (l *latest) Update(msg ais.AisMultiple) {
+	l.msgs[msg.Position.Mmsi+msg.Aton.Mmsi+msg.Staticdata.Mmsi] = msg
+}
+
+func (l *latest) Snapshot() []ais.AisMultiple {
+	var res []ais.AisMultiple
+	for _, msg := range l.msgs {
+		res = append(res, msg)
+	}
+	return res
+}
+
+func TestBroadcaster(t *testing.T) {
+	s := stream(t, "../testdata/get_ais.txt")
+	b := broadcast.New(&s)
+	tracker := &latest{msgs: make(map[int]ais.AisMultiple)}
+	b.Attach(tracker)
+
+	blocking := b.Subscribe(0, broadcast.Block)
+	dropNewest := b.Subscribe(1, broadcast.DropNewest)
+	dropOldest := b.Subscribe(2, broadcast.DropOldest)
+	disconnect := b.Subscribe(0, broadcast.Disconnect)
+	unsubscribed := b.Subscribe(0, broadcast.Block)
+	unsubscribed.Unsubscribe()
+
+	if n := b.Subscribers(); n != 4 {
+		t.Errorf("expected 4 subscribers, got %d", n)
+	}
+
+	runErr := make(chan error)
+	go func() {
+		runErr <- b.Run()
+	}()
+
+	num := 0
+	for range blocking.C() {
+		num++
+	}
+	if err := <-runErr; !ais.IsEOF(err) {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+
+	if num <= 0 {
+		t.Errorf("expected blocking subscriber to receive messages, got %d", num)
+	}
+	if stats := blocking.Stats(); stats.Delivered != uint64(num) || stats.Dropped != 0 {
+		t.Errorf("unexpected stats for blocking subscriber %+v", stats)
+	}
+	if !ais.IsEOF(blocking.Err()) {
+		t.Errorf("expected blocking subscriber to end with EOF, got %v", blocking.Err())
+	}
+
+	if stats := dropNewest.Stats(); stats.Lag != 1 || stats.Delivered != 1 || stats.Dropped != uint64(num-1) {
+		t.Errorf("unexpected stats for drop newest subscriber %+v", stats)
+	}
+	if stats := dropOldest.Stats(); stats.Lag != 2 || stats.Dropped != uint64(num-2) {
+		t.Errorf("unexpected stats for drop oldest subscriber %+v", stats)
+	}
+
+	if err := disconnect.Err(); err != broadcast.ErrSlowSubscriber {
+		t.Errorf("expected slow subscriber to be disconnected, got %v", err)
+	}
+	if err := unsubscribed.Err(); err != broadcast.ErrUnsubscribed {
+		t.Errorf("expected unsubscribed subscriber to end with ErrUnsubscribed, got %v", err)
+	}
+
+	late := b.Subscribe(0, broadcast.Block)
+	snapshot := 0
+	for range late.C() {
+		snapshot++
+	}
+	if snapshot != len(tracker.msgs) || snapshot == 0 {
+		t.Errorf("expected late subscriber to receive snapshot of %d messages, got %d", len(tracker.msgs), snapshot)
+	}
+	if !ais.IsEOF(late.Err()) {
+		t.Errorf("expected late subscriber to end with EOF, got %v", late.Err())
+	}
+}
+
+func TestBroadcaster_BlockDoesNotHoldSubscribe(t *testing.T) {
+	s := stream(t, "../testdata/get_ais.txt")
+	b := broadcast.New(&s)
+	stuck := b.Subscribe(0, broadcast.Block)
+
+	runErr := make(chan error)
+	go func() {
+		runErr <- b.Run()
+	}()
+	// Wait for the broadcaster to block on the first message
+	time.Sleep(50 * time.Millisecond)
+
+	subscribed := make(chan *broadcast.Subscription[ais.AisMultiple])
+	go func() {
+		sub := b.Subscribe(100, broadcast.DropNewest)
+		b.Subscribers()
+		subscribed <- sub
+	}()
+	var late *broadcast.Subscription[ais.AisMultiple]
+	select {
+	case late = <-subscribed:
+	case <-time.After(time.Second):
+		t.Fatal("expected Subscribe not to wait for the blocked subscriber")
+	}
+
+	stuck.Unsubscribe()
+	if err := <-runErr; !ais.IsEOF(err) {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+	if late.Stats().Delivered == 0 {
+		t.Error("expected the late subscriber to receive the messages after the blocked subscriber left")
+	}
+}
@@ -3,6 +3,7 @@ package ais
 import (
 	"context"
 	"net/http"
+	"sync"
 
 	"golang.org/x/oauth2/clientcredentials"
 )
@@ -14,6 +15,10 @@ import (
 type Client struct {
 	urls       URLs
 	httpClient *http.Client
+
+	// openArea is the Open AIS Area, once fetched by OpenArea
+	openArea   *OpenArea
+	openAreaMu sync.Mutex
 }
 
 // NewClient creates a new Client.
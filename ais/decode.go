@@ -0,0 +1,1039 @@
+package ais
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/bits"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+)
+
+// This file implements a single pass decoder for AisMultiple and CombinedMultiple, which are decoded for every message
+// of a stream. The decoder reads the fields of a message into a pooled scratch space in one pass, and then assigns
+// them to the concrete type of the message.
+//
+// The decoder only handles messages as they are sent by the API: valid JSON objects with field names in the expected
+// case, and field values of the expected types. Whenever it encounters anything else, such as a field name in
+// another case, a number where a string is expected or malformed JSON, it gives up without modifying the message,
+// and the message is decoded by encoding/json instead. This guarantees that both decoders give the same result.
+
+// fastUnmarshaler is implemented by types which have a single pass decoder.
+type fastUnmarshaler interface {
+	// unmarshalFast decodes data into the receiver, and returns true on success. If it returns false, the receiver is
+	// unmodified, and data must be decoded with encoding/json.
+	unmarshalFast(data []byte) bool
+}
+
+// unmarshal decodes data into v like json.Unmarshal, using the single pass decoder if v has one.
+func unmarshal(data []byte, v any) error {
+	if u, ok := v.(fastUnmarshaler); ok && u.unmarshalFast(data) {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// field is a JSON field known by the decoder.
+type field uint8
+
+const (
+	fieldMessageType field = iota
+	fieldMmsi
+	fieldMsgtime
+	fieldAltitude
+	fieldLongitude
+	fieldLatitude
+	fieldCourseOverGround
+	fieldAisClass
+	fieldNavigationalStatus
+	fieldRateOfTurn
+	fieldSpeedOverGround
+	fieldTrueHeading
+	fieldName
+	fieldDimensionA
+	fieldDimensionB
+	fieldDimensionC
+	fieldDimensionD
+	fieldTypeOfAidsToNavigation
+	fieldTypeOfElectronicFixingDevice
+	fieldImoNumber
+	fieldCallSign
+	fieldDestination
+	fieldEta
+	fieldDraught
+	fieldShipLength
+	fieldShipWidth
+	fieldShipType
+	fieldPositionFixingDeviceType
+	fieldReportClass
+
+	// numValues is the number of fields which hold message data. The fields after it describe the structure of
+	// GeoJSON messages.
+	numValues
+
+	fieldType
+	fieldGeometry
+	fieldProperties
+	fieldCoordinates
+)
+
+var fieldNames = [...]string{
+	fieldMessageType:                  "messageType",
+	fieldMmsi:                         "mmsi",
+	fieldMsgtime:                      "msgtime",
+	fieldAltitude:                     "altitude",
+	fieldLongitude:                    "longitude",
+	fieldLatitude:                     "latitude",
+	fieldCourseOverGround:             "courseOverGround",
+	fieldAisClass:                     "aisClass",
+	fieldNavigationalStatus:           "navigationalStatus",
+	fieldRateOfTurn:                   "rateOfTurn",
+	fieldSpeedOverGround:              "speedOverGround",
+	fieldTrueHeading:                  "trueHeading",
+	fieldName:                         "name",
+	fieldDimensionA:                   "dimensionA",
+	fieldDimensionB:                   "dimensionB",
+	fieldDimensionC:                   "dimensionC",
+	fieldDimensionD:                   "dimensionD",
+	fieldTypeOfAidsToNavigation:       "typeOfAidsToNavigation",
+	fieldTypeOfElectronicFixingDevice: "typeOfElectronicFixingDevice",
+	fieldImoNumber:                    "imoNumber",
+	fieldCallSign:                     "callSign",
+	fieldDestination:                  "destination",
+	fieldEta:                          "eta",
+	fieldDraught:                      "draught",
+	fieldShipLength:                   "shipLength",
+	fieldShipWidth:                    "shipWidth",
+	fieldShipType:                     "shipType",
+	fieldPositionFixingDeviceType:     "positionFixingDeviceType",
+	fieldReportClass:                  "reportClass",
+	fieldType:                         "type",
+	fieldGeometry:                     "geometry",
+	fieldProperties:                   "properties",
+	fieldCoordinates:                  "coordinates",
+}
+
+// kind is the Go type of a field. A field has the same type in every message type it is part of.
+type kind uint8
+
+const (
+	kindInt kind = iota
+	kindIntPtr
+	kindFloatPtr
+	kindString
+	kindTime
+)
+
+var fieldKinds = [numValues]kind{
+	fieldMessageType:                  kindInt,
+	fieldMmsi:                         kindInt,
+	fieldMsgtime:                      kindTime,
+	fieldAltitude:                     kindIntPtr,
+	fieldLongitude:                    kindFloatPtr,
+	fieldLatitude:                     kindFloatPtr,
+	fieldCourseOverGround:             kindFloatPtr,
+	fieldAisClass:                     kindString,
+	fieldNavigationalStatus:           kindInt,
+	fieldRateOfTurn:                   kindFloatPtr,
+	fieldSpeedOverGround:              kindFloatPtr,
+	fieldTrueHeading:                  kindIntPtr,
+	fieldName:                         kindString,
+	fieldDimensionA:                   kindIntPtr,
+	fieldDimensionB:                   kindIntPtr,
+	fieldDimensionC:                   kindIntPtr,
+	fieldDimensionD:                   kindIntPtr,
+	fieldTypeOfAidsToNavigation:       kindInt,
+	fieldTypeOfElectronicFixingDevice: kindInt,
+	fieldImoNumber:                    kindIntPtr,
+	fieldCallSign:                     kindString,
+	fieldDestination:                  kindString,
+	fieldEta:                          kindString,
+	fieldDraught:                      kindIntPtr,
+	fieldShipLength:                   kindIntPtr,
+	fieldShipWidth:                    kindIntPtr,
+	fieldShipType:                     kindIntPtr,
+	fieldPositionFixingDeviceType:     kindInt,
+	fieldReportClass:                  kindString,
+}
+
+// intPtrFields and floatPtrFields have a bit set for every field of the respective kind.
+var intPtrFields, floatPtrFields uint64
+
+// scope is the kind of JSON object being decoded, which decides the fields it can hold.
+type scope int
+
+const (
+	scopeAis scope = iota
+	scopeCombined
+	scopeProperties
+	scopeGeometry
+)
+
+// scopeFields maps the field names of every scope to fields.
+var scopeFields [scopeGeometry + 1]map[string]field
+
+func init() {
+	for f := field(0); f < numValues; f++ {
+		switch fieldKinds[f] {
+		case kindIntPtr:
+			intPtrFields |= bit(f)
+		case kindFloatPtr:
+			floatPtrFields |= bit(f)
+		}
+	}
+
+	fieldMap := func(extra ...field) map[string]field {
+		m := make(map[string]field)
+		for f := field(0); f < numValues; f++ {
+			m[fieldNames[f]] = f
+		}
+		for _, f := range extra {
+			m[fieldNames[f]] = f
+		}
+		return m
+	}
+	scopeFields[scopeAis] = fieldMap(fieldType)
+	scopeFields[scopeCombined] = fieldMap(fieldType, fieldGeometry, fieldProperties)
+	scopeFields[scopeProperties] = fieldMap()
+	scopeFields[scopeGeometry] = map[string]field{
+		fieldNames[fieldType]:        fieldType,
+		fieldNames[fieldCoordinates]: fieldCoordinates,
+	}
+}
+
+func bit(f field) uint64 {
+	return 1 << f
+}
+
+// values holds the data fields of a JSON object, until they are assigned to a message.
+type values struct {
+	// set has a bit for every field which has been assigned a value. A pointer field which is null has its bit set in
+	// both set and null. Other fields which are null are not assigned, like in encoding/json.
+	set, null uint64
+	ints      [numValues]int
+	floats    [numValues]float64
+	strs      [numValues]string
+	msgtime   time.Time
+
+	// The pointer fields of a message point into these, so that they share a single allocation of each type.
+	intSlab   []int
+	floatSlab []float64
+}
+
+func (v *values) has(f field) bool {
+	return v.set&bit(f) != 0
+}
+
+func (v *values) int(f field, dst *int) {
+	if v.has(f) {
+		*dst = v.ints[f]
+	}
+}
+
+func (v *values) string(f field, dst *string) {
+	if v.has(f) {
+		*dst = v.strs[f]
+	}
+}
+
+func (v *values) time(f field, dst *time.Time) {
+	if v.has(f) {
+		*dst = v.msgtime
+	}
+}
+
+// intPtr assigns a pointer field. Like encoding/json, it reuses the value dst points to, if any.
+func (v *values) intPtr(f field, dst **int) {
+	switch {
+	case !v.has(f):
+	case v.null&bit(f) != 0:
+		*dst = nil
+	case *dst != nil:
+		**dst = v.ints[f]
+	default:
+		if v.intSlab == nil {
+			v.intSlab = make([]int, 0, bits.OnesCount64(v.set&^v.null&intPtrFields))
+		}
+		v.intSlab = append(v.intSlab, v.ints[f])
+		*dst = &v.intSlab[len(v.intSlab)-1]
+	}
+}
+
+// floatPtr assigns a pointer field. Like encoding/json, it reuses the value dst points to, if any.
+func (v *values) floatPtr(f field, dst **float64) {
+	switch {
+	case !v.has(f):
+	case v.null&bit(f) != 0:
+		*dst = nil
+	case *dst != nil:
+		**dst = v.floats[f]
+	default:
+		if v.floatSlab == nil {
+			v.floatSlab = make([]float64, 0, bits.OnesCount64(v.set&^v.null&floatPtrFields))
+		}
+		v.floatSlab = append(v.floatSlab, v.floats[f])
+		*dst = &v.floatSlab[len(v.floatSlab)-1]
+	}
+}
+
+func (v *values) position(p *Position) {
+	v.int(fieldMessageType, &p.MessageType)
+	v.int(fieldMmsi, &p.Mmsi)
+	v.time(fieldMsgtime, &p.Msgtime)
+	v.intPtr(fieldAltitude, &p.Altitude)
+	v.floatPtr(fieldLongitude, &p.Longitude)
+	v.floatPtr(fieldLatitude, &p.Latitude)
+	v.floatPtr(fieldCourseOverGround, &p.CourseOverGround)
+	v.string(fieldAisClass, &p.AisClass)
+	v.int(fieldNavigationalStatus, &p.NavigationalStatus)
+	v.floatPtr(fieldRateOfTurn, &p.RateOfTurn)
+	v.floatPtr(fieldSpeedOverGround, &p.SpeedOverGround)
+	v.intPtr(fieldTrueHeading, &p.TrueHeading)
+}
+
+func (v *values) aton(a *Aton) {
+	v.int(fieldMessageType, &a.MessageType)
+	v.int(fieldMmsi, &a.Mmsi)
+	v.time(fieldMsgtime, &a.Msgtime)
+	v.floatPtr(fieldLongitude, &a.Longitude)
+	v.floatPtr(fieldLatitude, &a.Latitude)
+	v.string(fieldName, &a.Name)
+	v.intPtr(fieldDimensionA, &a.DimensionA)
+	v.intPtr(fieldDimensionB, &a.DimensionB)
+	v.intPtr(fieldDimensionC, &a.DimensionC)
+	v.intPtr(fieldDimensionD, &a.DimensionD)
+	v.int(fieldTypeOfAidsToNavigation, &a.TypeOfAidsToNavigation)
+	v.int(fieldTypeOfElectronicFixingDevice, &a.TypeOfElectronicFixingDevice)
+}
+
+func (v *values) staticdata(s *Staticdata) {
+	v.int(fieldMessageType, &s.MessageType)
+	v.int(fieldMmsi, &s.Mmsi)
+	v.time(fieldMsgtime, &s.Msgtime)
+	v.string(fieldName, &s.Name)
+	v.intPtr(fieldDimensionA, &s.DimensionA)
+	v.intPtr(fieldDimensionB, &s.DimensionB)
+	v.intPtr(fieldDimensionC, &s.DimensionC)
+	v.intPtr(fieldDimensionD, &s.DimensionD)
+	v.intPtr(fieldImoNumber, &s.ImoNumber)
+	v.string(fieldCallSign, &s.CallSign)
+	v.string(fieldDestination, &s.Destination)
+	v.string(fieldEta, &s.Eta)
+	v.intPtr(fieldDraught, &s.Draught)
+	v.intPtr(fieldShipLength, &s.ShipLength)
+	v.intPtr(fieldShipWidth, &s.ShipWidth)
+	v.intPtr(fieldShipType, &s.ShipType)
+	v.int(fieldPositionFixingDeviceType, &s.PositionFixingDeviceType)
+	v.string(fieldReportClass, &s.ReportClass)
+}
+
+func (v *values) simpleJson(c *CombinedSimpleJson) {
+	v.floatPtr(fieldCourseOverGround, &c.CourseOverGround)
+	v.floatPtr(fieldLatitude, &c.Latitude)
+	v.floatPtr(fieldLongitude, &c.Longitude)
+	v.string(fieldName, &c.Name)
+	v.floatPtr(fieldRateOfTurn, &c.RateOfTurn)
+	v.intPtr(fieldShipType, &c.ShipType)
+	v.floatPtr(fieldSpeedOverGround, &c.SpeedOverGround)
+	v.intPtr(fieldTrueHeading, &c.TrueHeading)
+	v.int(fieldMmsi, &c.Mmsi)
+	v.time(fieldMsgtime, &c.Msgtime)
+}
+
+func (v *values) fullJson(c *CombinedFullJson) {
+	v.floatPtr(fieldCourseOverGround, &c.CourseOverGround)
+	v.floatPtr(fieldLatitude, &c.Latitude)
+	v.floatPtr(fieldLongitude, &c.Longitude)
+	v.string(fieldName, &c.Name)
+	v.floatPtr(fieldRateOfTurn, &c.RateOfTurn)
+	v.intPtr(fieldShipType, &c.ShipType)
+	v.floatPtr(fieldSpeedOverGround, &c.SpeedOverGround)
+	v.intPtr(fieldTrueHeading, &c.TrueHeading)
+	v.int(fieldMmsi, &c.Mmsi)
+	v.time(fieldMsgtime, &c.Msgtime)
+	v.intPtr(fieldAltitude, &c.Altitude)
+	v.int(fieldNavigationalStatus, &c.NavigationalStatus)
+	v.intPtr(fieldImoNumber, &c.ImoNumber)
+	v.string(fieldCallSign, &c.CallSign)
+	v.string(fieldDestination, &c.Destination)
+	v.string(fieldEta, &c.Eta)
+	v.intPtr(fieldDraught, &c.Draught)
+	v.intPtr(fieldShipLength, &c.ShipLength)
+	v.intPtr(fieldShipWidth, &c.ShipWidth)
+	v.intPtr(fieldDimensionA, &c.DimensionA)
+	v.intPtr(fieldDimensionB, &c.DimensionB)
+	v.intPtr(fieldDimensionC, &c.DimensionC)
+	v.intPtr(fieldDimensionD, &c.DimensionD)
+	v.int(fieldPositionFixingDeviceType, &c.PositionFixingDeviceType)
+	v.string(fieldReportClass, &c.ReportClass)
+}
+
+// maxDepth is the deepest nesting of JSON values the decoder handles. Deeper values are left to encoding/json, which
+// has its own limit.
+const maxDepth = 64
+
+// decoder holds the state of decoding a single message. Decoders are pooled, so that the scratch space is reused
+// between messages.
+type decoder struct {
+	data  []byte
+	pos   int
+	depth int
+
+	top   values
+	props values
+
+	typ     string
+	hasType bool
+
+	geometryType     string
+	hasGeometryType  bool
+	coordinates      []float64
+	hasCoordinates   bool
+	nullCoordinates  bool
+	numProperties    int
+	topEta, propsEta bool
+
+	// buf holds unescaped strings
+	buf []byte
+}
+
+var decoders = sync.Pool{
+	New: func() any {
+		return new(decoder)
+	},
+}
+
+func newDecoder(data []byte) *decoder {
+	d := decoders.Get().(*decoder)
+	d.data = data
+	return d
+}
+
+// release resets the decoder and returns it to the pool. The buffers are kept, but nothing which is part of a
+// decoded message.
+func (d *decoder) release() {
+	coordinates, buf := d.coordinates[:0], d.buf[:0]
+	*d = decoder{coordinates: coordinates, buf: buf}
+	decoders.Put(d)
+}
+
+func (a *AisMultiple) unmarshalFast(data []byte) bool {
+	d := newDecoder(data)
+	defer d.release()
+
+	if !d.document(scopeAis) {
+		return false
+	}
+
+	switch responsetype.Ais(d.typ) {
+	case responsetype.Position:
+		a.Type = responsetype.Position
+		d.top.position(&a.Position)
+	case responsetype.Aton:
+		a.Type = responsetype.Aton
+		d.top.aton(&a.Aton)
+	case responsetype.Staticdata:
+		a.Type = responsetype.Staticdata
+		d.top.staticdata(&a.Staticdata)
+	default:
+		return false
+	}
+	return true
+}
+
+func (c *CombinedMultiple) unmarshalFast(data []byte) bool {
+	return c.unmarshalFastAs(data, "")
+}
+
+// unmarshalFastAs is like unmarshalFast, but decodes data into the given type instead of deducing the type from the
+// data, unless the type is empty.
+func (c *CombinedMultiple) unmarshalFastAs(data []byte, typ responsetype.Combined) bool {
+	d := newDecoder(data)
+	defer d.release()
+
+	if !d.document(scopeCombined) {
+		return false
+	}
+
+	if typ == "" {
+		// Like UnmarshalJSON, the "properties" field exists iff the message is GeoJSON, and the "eta" field exists
+		// only on the full types
+		switch {
+		case d.numProperties > 0 && d.propsEta:
+			typ = responsetype.FullGeojson
+		case d.numProperties > 0:
+			typ = responsetype.SimpleGeojson
+		case d.topEta:
+			typ = responsetype.FullJson
+		default:
+			typ = responsetype.SimpleJson
+		}
+	}
+
+	switch typ {
+	case responsetype.FullGeojson:
+		c.Type = responsetype.FullGeojson
+		g := &c.CombinedFullGeojson
+		if d.hasType {
+			g.Type = d.typ
+		}
+		d.geometry(&g.Geometry.Type, &g.Geometry.Coordinates)
+
+		p := &g.Properties
+		d.props.int(fieldMmsi, &p.Mmsi)
+		d.props.string(fieldName, &p.Name)
+		d.props.time(fieldMsgtime, &p.Msgtime)
+		d.props.floatPtr(fieldSpeedOverGround, &p.SpeedOverGround)
+		d.props.floatPtr(fieldCourseOverGround, &p.CourseOverGround)
+		d.props.int(fieldNavigationalStatus, &p.NavigationalStatus)
+		d.props.floatPtr(fieldRateOfTurn, &p.RateOfTurn)
+		d.props.intPtr(fieldShipType, &p.ShipType)
+		d.props.intPtr(fieldTrueHeading, &p.TrueHeading)
+		d.props.string(fieldCallSign, &p.CallSign)
+		d.props.string(fieldDestination, &p.Destination)
+		d.props.string(fieldEta, &p.Eta)
+		d.props.intPtr(fieldImoNumber, &p.ImoNumber)
+		d.props.intPtr(fieldDimensionA, &p.DimensionA)
+		d.props.intPtr(fieldDimensionB, &p.DimensionB)
+		d.props.intPtr(fieldDimensionC, &p.DimensionC)
+		d.props.intPtr(fieldDimensionD, &p.DimensionD)
+		d.props.intPtr(fieldDraught, &p.Draught)
+		d.props.intPtr(fieldShipLength, &p.ShipLength)
+		d.props.intPtr(fieldShipWidth, &p.ShipWidth)
+		d.props.int(fieldPositionFixingDeviceType, &p.PositionFixingDeviceType)
+		d.props.string(fieldReportClass, &p.ReportClass)
+	case responsetype.SimpleGeojson:
+		c.Type = responsetype.SimpleGeojson
+		g := &c.CombinedSimpleGeojson
+		if d.hasType {
+			g.Type = d.typ
+		}
+		d.geometry(&g.Geometry.Type, &g.Geometry.Coordinates)
+
+		p := &g.Properties
+		d.props.int(fieldMmsi, &p.Mmsi)
+		d.props.string(fieldName, &p.Name)
+		d.props.time(fieldMsgtime, &p.Msgtime)
+		d.props.floatPtr(fieldSpeedOverGround, &p.SpeedOverGround)
+		d.props.floatPtr(fieldCourseOverGround, &p.CourseOverGround)
+		d.props.floatPtr(fieldRateOfTurn, &p.RateOfTurn)
+		d.props.intPtr(fieldShipType, &p.ShipType)
+		d.props.intPtr(fieldTrueHeading, &p.TrueHeading)
+	case responsetype.FullJson:
+		c.Type = responsetype.FullJson
+		d.top.fullJson(&c.CombinedFullJson)
+	case responsetype.SimpleJson:
+		c.Type = responsetype.SimpleJson
+		d.top.simpleJson(&c.CombinedSimpleJson)
+	default:
+		return false
+	}
+	return true
+}
+
+// geometry assigns the geometry of a GeoJSON message. Like encoding/json, it reuses the coordinates slice, if any.
+func (d *decoder) geometry(typ *string, coordinates *[]float64) {
+	if d.hasGeometryType {
+		*typ = d.geometryType
+	}
+	if !d.hasCoordinates {
+		return
+	}
+	if d.nullCoordinates {
+		*coordinates = nil
+		return
+	}
+	s := append((*coordinates)[:0], d.coordinates...)
+	if s == nil {
+		s = []float64{}
+	}
+	*coordinates = s
+}
+
+// document decodes a message, which must be a single JSON object, optionally surrounded by whitespace.
+func (d *decoder) document(s scope) bool {
+	d.skipSpace()
+	if !d.object(s) {
+		return false
+	}
+	d.skipSpace()
+	return d.pos == len(d.data)
+}
+
+func (d *decoder) skipSpace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\n', '\r':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (d *decoder) peek() byte {
+	if d.pos < len(d.data) {
+		return d.data[d.pos]
+	}
+	return 0
+}
+
+// consume consumes the byte c, and returns false if the next byte is something else.
+func (d *decoder) consume(c byte) bool {
+	if d.peek() != c {
+		return false
+	}
+	d.pos++
+	return true
+}
+
+// object decodes the members of a JSON object in the given scope.
+func (d *decoder) object(s scope) bool {
+	if !d.consume('{') {
+		return false
+	}
+	d.skipSpace()
+	if d.consume('}') {
+		return true
+	}
+
+	for {
+		d.skipSpace()
+		key, escaped, ok := d.str()
+		if !ok || escaped {
+			return false
+		}
+		d.skipSpace()
+		if !d.consume(':') {
+			return false
+		}
+		d.skipSpace()
+
+		if f, known := scopeFields[s][string(key)]; known {
+			if !d.member(s, f) {
+				return false
+			}
+		} else if !ignorable(key, s) || !d.skip() {
+			return false
+		}
+
+		d.skipSpace()
+		if d.consume('}') {
+			return true
+		}
+		if !d.consume(',') {
+			return false
+		}
+	}
+}
+
+// ignorable is true iff encoding/json would ignore the key in the given scope. encoding/json matches keys to fields
+// case-insensitively, with Unicode case folding.
+func ignorable(key []byte, s scope) bool {
+	for _, c := range key {
+		if c >= utf8.RuneSelf {
+			return false
+		}
+	}
+	for name := range scopeFields[s] {
+		if bytes.EqualFold(key, []byte(name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// member decodes the value of a known field.
+func (d *decoder) member(s scope, f field) bool {
+	switch f {
+	case fieldType:
+		if s == scopeGeometry {
+			return d.stringValue(&d.geometryType, &d.hasGeometryType)
+		}
+		return d.stringValue(&d.typ, &d.hasType)
+	case fieldCoordinates:
+		return d.coordinateArray()
+	case fieldGeometry:
+		return d.nullOr(func() bool { return d.nested(scopeGeometry) })
+	case fieldProperties:
+		// With several "properties" fields, UnmarshalJSON would merge them, but only check the last one for "eta"
+		d.numProperties++
+		if d.numProperties > 1 {
+			return false
+		}
+		return d.nullOr(func() bool { return d.nested(scopeProperties) })
+	}
+
+	v := &d.top
+	if s == scopeProperties {
+		v = &d.props
+		d.propsEta = d.propsEta || f == fieldEta
+	} else {
+		d.topEta = d.topEta || f == fieldEta
+	}
+	return d.value(v, f)
+}
+
+// nullOr consumes a null value, or decodes a value with fn.
+func (d *decoder) nullOr(fn func() bool) bool {
+	if d.peek() == 'n' {
+		return d.literal("null")
+	}
+	return fn()
+}
+
+func (d *decoder) nested(s scope) bool {
+	d.depth++
+	defer func() { d.depth-- }()
+	return d.depth <= maxDepth && d.object(s)
+}
+
+// value decodes the value of a data field into v.
+func (d *decoder) value(v *values, f field) bool {
+	k := fieldKinds[f]
+	if d.peek() == 'n' {
+		if !d.literal("null") {
+			return false
+		}
+		if k == kindIntPtr || k == kindFloatPtr {
+			v.set |= bit(f)
+			v.null |= bit(f)
+		}
+		return true
+	}
+
+	switch k {
+	case kindInt, kindIntPtr:
+		num, ok := d.number()
+		if !ok {
+			return false
+		}
+		n, err := strconv.ParseInt(string(num), 10, strconv.IntSize)
+		if err != nil {
+			return false
+		}
+		v.ints[f] = int(n)
+	case kindFloatPtr:
+		num, ok := d.number()
+		if !ok {
+			return false
+		}
+		n, err := strconv.ParseFloat(string(num), 64)
+		if err != nil {
+			return false
+		}
+		v.floats[f] = n
+	case kindString:
+		str, ok := d.stringLiteral()
+		if !ok {
+			return false
+		}
+		v.strs[f] = str
+	case kindTime:
+		start := d.pos
+		if _, _, ok := d.str(); !ok {
+			return false
+		}
+		if err := v.msgtime.UnmarshalJSON(d.data[start:d.pos]); err != nil {
+			return false
+		}
+	}
+
+	v.set |= bit(f)
+	v.null &^= bit(f)
+	return true
+}
+
+// stringValue decodes a string or null. A null value leaves dst unmodified.
+func (d *decoder) stringValue(dst *string, has *bool) bool {
+	if d.peek() == 'n' {
+		return d.literal("null")
+	}
+	str, ok := d.stringLiteral()
+	if !ok {
+		return false
+	}
+	*dst, *has = str, true
+	return true
+}
+
+// coordinateArray decodes an array of numbers, or null.
+func (d *decoder) coordinateArray() bool {
+	d.hasCoordinates = true
+	d.nullCoordinates = false
+	d.coordinates = d.coordinates[:0]
+	if d.peek() == 'n' {
+		d.nullCoordinates = true
+		return d.literal("null")
+	}
+
+	if !d.consume('[') {
+		return false
+	}
+	d.skipSpace()
+	if d.consume(']') {
+		return true
+	}
+	for {
+		d.skipSpace()
+		num, ok := d.number()
+		if !ok {
+			return false
+		}
+		n, err := strconv.ParseFloat(string(num), 64)
+		if err != nil {
+			return false
+		}
+		d.coordinates = append(d.coordinates, n)
+
+		d.skipSpace()
+		if d.consume(']') {
+			return true
+		}
+		if !d.consume(',') {
+			return false
+		}
+	}
+}
+
+// skip skips any JSON value.
+func (d *decoder) skip() bool {
+	switch c := d.peek(); {
+	case c == '"':
+		_, _, ok := d.str()
+		return ok
+	case c == '-' || '0' <= c && c <= '9':
+		_, ok := d.number()
+		return ok
+	case c == 't':
+		return d.literal("true")
+	case c == 'f':
+		return d.literal("false")
+	case c == 'n':
+		return d.literal("null")
+	case c != '{' && c != '[':
+		return false
+	}
+
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxDepth {
+		return false
+	}
+
+	if d.consume('[') {
+		d.skipSpace()
+		if d.consume(']') {
+			return true
+		}
+		for {
+			d.skipSpace()
+			if !d.skip() {
+				return false
+			}
+			d.skipSpace()
+			if d.consume(']') {
+				return true
+			}
+			if !d.consume(',') {
+				return false
+			}
+		}
+	}
+
+	d.pos++
+	d.skipSpace()
+	if d.consume('}') {
+		return true
+	}
+	for {
+		d.skipSpace()
+		if _, _, ok := d.str(); !ok {
+			return false
+		}
+		d.skipSpace()
+		if !d.consume(':') {
+			return false
+		}
+		d.skipSpace()
+		if !d.skip() {
+			return false
+		}
+		d.skipSpace()
+		if d.consume('}') {
+			return true
+		}
+		if !d.consume(',') {
+			return false
+		}
+	}
+}
+
+func (d *decoder) literal(lit string) bool {
+	if !bytes.HasPrefix(d.data[d.pos:], []byte(lit)) {
+		return false
+	}
+	d.pos += len(lit)
+	return true
+}
+
+// number consumes a JSON number, and returns its text.
+func (d *decoder) number() ([]byte, bool) {
+	start := d.pos
+	digits := func() int {
+		n := 0
+		for d.pos < len(d.data) && '0' <= d.data[d.pos] && d.data[d.pos] <= '9' {
+			d.pos++
+			n++
+		}
+		return n
+	}
+
+	d.consume('-')
+	if d.consume('0') {
+		// No leading zeros
+	} else if digits() == 0 {
+		return nil, false
+	}
+	if d.consume('.') && digits() == 0 {
+		return nil, false
+	}
+	if d.consume('e') || d.consume('E') {
+		if !d.consume('+') {
+			d.consume('-')
+		}
+		if digits() == 0 {
+			return nil, false
+		}
+	}
+	return d.data[start:d.pos], true
+}
+
+// str consumes a JSON string, and returns its contents without the quotes. The contents are only unescaped if
+// escaped is false.
+func (d *decoder) str() (contents []byte, escaped bool, ok bool) {
+	if !d.consume('"') {
+		return nil, false, false
+	}
+	start := d.pos
+	for d.pos < len(d.data) {
+		c := d.data[d.pos]
+		switch {
+		case c == '"':
+			d.pos++
+			return d.data[start : d.pos-1], escaped, true
+		case c < 0x20:
+			return nil, false, false
+		case c == '\\':
+			escaped = true
+			d.pos++
+			switch d.peek() {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				d.pos++
+			case 'u':
+				if _, ok := hex4(d.data[d.pos+1:]); !ok {
+					return nil, false, false
+				}
+				d.pos += 5
+			default:
+				return nil, false, false
+			}
+		default:
+			d.pos++
+		}
+	}
+	return nil, false, false
+}
+
+// stringLiteral consumes a JSON string, and returns its unescaped value.
+func (d *decoder) stringLiteral() (string, bool) {
+	start := d.pos
+	contents, escaped, ok := d.str()
+	if !ok {
+		return "", false
+	}
+	if !utf8.Valid(contents) {
+		// Leave the replacement of invalid UTF-8 to encoding/json
+		var s string
+		err := json.Unmarshal(d.data[start:d.pos], &s)
+		return s, err == nil
+	}
+	if !escaped {
+		return string(contents), true
+	}
+	d.buf = unescape(d.buf[:0], contents)
+	return string(d.buf), true
+}
+
+// unescape appends the unescaped contents of a valid JSON string to buf, the same way as encoding/json.
+func unescape(buf, contents []byte) []byte {
+	for i := 0; i < len(contents); {
+		c := contents[i]
+		if c != '\\' {
+			buf = append(buf, c)
+			i++
+			continue
+		}
+
+		switch contents[i+1] {
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+		case 'u':
+			r, _ := hex4(contents[i+2:])
+			i += 6
+			if utf16.IsSurrogate(r) {
+				if i+1 < len(contents) && contents[i] == '\\' && contents[i+1] == 'u' {
+					r2, _ := hex4(contents[i+2:])
+					if dec := utf16.DecodeRune(r, r2); dec != unicode.ReplacementChar {
+						buf = utf8.AppendRune(buf, dec)
+						i += 6
+						continue
+					}
+				}
+				r = unicode.ReplacementChar
+			}
+			buf = utf8.AppendRune(buf, r)
+			continue
+		default:
+			// '"', '\\' and '/' stand for themselves
+			buf = append(buf, contents[i+1])
+		}
+		i += 2
+	}
+	return buf
+}
+
+// hex4 parses the four hexadecimal digits at the start of b.
+func hex4(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
@@ -0,0 +1,184 @@
+package ais
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// fixtureLines returns the JSON messages of a fixture. Server Sent Events are stripped of their "data:" prefix, and
+// other lines are left out.
+func fixtureLines(tb testing.TB, filename string) [][]byte {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	var lines [][]byte
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if match := sseData.FindSubmatch(line); match != nil {
+			line = match[1]
+		}
+		if bytes.HasPrefix(line, []byte("{")) {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestAisMultiple_unmarshalFast(t *testing.T) {
+	for _, filename := range []string{"testdata/get_ais.txt", "testdata/get_sse_ais.txt"} {
+		for i, line := range fixtureLines(t, filename) {
+			var fast, std AisMultiple
+			if !fast.unmarshalFast(line) {
+				t.Fatalf("%s:%d: expected fast decoding of %s", filename, i+1, line)
+			}
+			if err := std.unmarshalJSON(line); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fast, std) {
+				t.Fatalf("%s:%d: expected %+v, got %+v", filename, i+1, std, fast)
+			}
+		}
+	}
+}
+
+func TestCombinedMultiple_unmarshalFast(t *testing.T) {
+	filenames := []string{
+		"testdata/combined_full_geojson.txt",
+		"testdata/combined_full_json.txt",
+		"testdata/combined_simple_geojson.txt",
+		"testdata/combined_simple_json.txt",
+	}
+	for _, filename := range filenames {
+		for i, line := range fixtureLines(t, filename) {
+			var fast, std CombinedMultiple
+			if !fast.unmarshalFast(line) {
+				t.Fatalf("%s:%d: expected fast decoding of %s", filename, i+1, line)
+			}
+			if err := std.unmarshalJSON(line); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fast, std) {
+				t.Fatalf("%s:%d: expected %+v, got %+v", filename, i+1, std, fast)
+			}
+		}
+	}
+}
+
+// TestUnmarshalJSON_Semantics checks that messages the single pass decoder either handles or gives up on decode the
+// same as with encoding/json.
+func TestUnmarshalJSON_Semantics(t *testing.T) {
+	ais := []string{
+		`{"type":"Position","mmsi":1,"latitude":null,"longitude":2.5,"trueHeading":12,"msgtime":"2023-02-20T13:13:55.620904+00:00"}`,
+		`  {"mmsi":1,"type":"Aton","name":"ÆGIR \"😀\" \ud83d","dimensionA":null} `,
+		`{"type":"Staticdata","name":"ABC","unknown":{"a":[1,2,{"b":null}],"c":true},"shipType":30,"shipType":null}`,
+		`{"type":"Staticdata","Name":"ABC"}`,
+		`{"TYPE":"Position","mmsi":1}`,
+		`{"type":"Position","mmsi":1.5}`,
+		`{"type":"Position","mmsi":"1"}`,
+		`{"type":"Position","speedOverGround":1e400}`,
+		`{"type":"Position","msgtime":"yesterday"}`,
+		"{\"type\":\"Position\",\"aisClass\":\"\xff\"}",
+		`{"type":"Unknown","mmsi":1}`,
+		`{"mmsi":1}`,
+		`{"type":"Position","mmsi":1`,
+		`{"type":"Position","mmsi":01}`,
+		`{"type":"Position"} {}`,
+		`null`,
+	}
+	for _, data := range ais {
+		var fast, std AisMultiple
+		fastErr := fast.UnmarshalJSON([]byte(data))
+		stdErr := std.unmarshalJSON([]byte(data))
+		if (fastErr == nil) != (stdErr == nil) || !reflect.DeepEqual(fast, std) {
+			t.Errorf("%s: expected %+v (%v), got %+v (%v)", data, std, stdErr, fast, fastErr)
+		}
+	}
+
+	combined := []string{
+		`{"mmsi":1,"eta":null,"latitude":1}`,
+		`{"mmsi":1,"Eta":"x"}`,
+		`{"type":"Feature","geometry":{"type":"Point","coordinates":[]},"properties":null}`,
+		`{"type":"Feature","geometry":{"type":"Point","coordinates":null},"properties":{"eta":"x","name":"A"}}`,
+		`{"type":"Feature","geometry":null,"properties":{"mmsi":1},"properties":{"eta":"x"}}`,
+		`{"type":"Feature","Properties":{"mmsi":1}}`,
+		`{"type":"Feature","geometry":{"coordinates":[1,null]},"properties":{}}`,
+		`{"type":"Feature","geometry":{"coordinates":[1,"2"]},"properties":{}}`,
+	}
+	for _, data := range combined {
+		var fast, std CombinedMultiple
+		fastErr := fast.UnmarshalJSON([]byte(data))
+		stdErr := std.unmarshalJSON([]byte(data))
+		if (fastErr == nil) != (stdErr == nil) || !reflect.DeepEqual(fast, std) {
+			t.Errorf("%s: expected %+v (%v), got %+v (%v)", data, std, stdErr, fast, fastErr)
+		}
+	}
+}
+
+// TestUnmarshalJSON_Reuse checks that decoding into a message which already holds data behaves like encoding/json.
+func TestUnmarshalJSON_Reuse(t *testing.T) {
+	first := []byte(`{"type":"Position","mmsi":1,"latitude":1,"longitude":2,"trueHeading":3}`)
+	second := []byte(`{"type":"Position","latitude":4,"longitude":null}`)
+
+	var fast, std AisMultiple
+	for _, data := range [][]byte{first, second} {
+		if err := fast.UnmarshalJSON(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := std.unmarshalJSON(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(fast, std) {
+		t.Errorf("expected %+v, got %+v", std, fast)
+	}
+}
+
+func benchmarkUnmarshal[T any](b *testing.B, filenames []string, unmarshal func(*T, []byte) error) {
+	var lines [][]byte
+	size := 0
+	for _, filename := range filenames {
+		for _, line := range fixtureLines(b, filename) {
+			lines = append(lines, line)
+			size += len(line)
+		}
+	}
+
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, line := range lines {
+			var res T
+			if err := unmarshal(&res, line); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkAisMultiple_UnmarshalJSON(b *testing.B) {
+	filenames := []string{"testdata/get_ais.txt"}
+	b.Run("fast", func(b *testing.B) {
+		benchmarkUnmarshal(b, filenames, (*AisMultiple).UnmarshalJSON)
+	})
+	b.Run("std", func(b *testing.B) {
+		benchmarkUnmarshal(b, filenames, (*AisMultiple).unmarshalJSON)
+	})
+}
+
+func BenchmarkCombinedMultiple_UnmarshalJSON(b *testing.B) {
+	filenames := []string{
+		"testdata/combined_full_geojson.txt",
+		"testdata/combined_full_json.txt",
+		"testdata/combined_simple_geojson.txt",
+		"testdata/combined_simple_json.txt",
+	}
+	b.Run("fast", func(b *testing.B) {
+		benchmarkUnmarshal(b, filenames, (*CombinedMultiple).UnmarshalJSON)
+	})
+	b.Run("std", func(b *testing.B) {
+		benchmarkUnmarshal(b, filenames, (*CombinedMultiple).unmarshalJSON)
+	})
+}
@@ -0,0 +1,213 @@
+// Package density aggregates vessel positions into traffic density maps, for route planning and for seeing where and
+// when vessels of each kind travel.
+//
+// An Aggregator counts positions in the cells of a Grid, which is either a SquareGrid of degrees of longitude and
+// latitude or a HexGrid of hexagons, separately for every time bucket and ship type category. Along with the number
+// of positions, it counts the number of unique vessels and the mean speed over ground in every cell:
+//
+//	grid, err := density.NewHexGrid(storage.Bounds{MinLon: 0, MinLat: 55, MaxLon: 35, MaxLat: 75}, 0.1)
+//	// ...
+//	agg := density.NewAggregator(grid, 24*time.Hour)
+//	for msg, err := range res.All() {
+//		if err != nil {
+//			// ...
+//		}
+//		agg.Add(msg.Vessel())
+//	}
+//	err = density.Heatmap{Width: 1024, Log: true}.WritePNG(f, grid, agg.Layer(time.Time{}))
+//
+// Layers, which merge the cells of chosen buckets and categories, are written as PNG heatmaps or GeoJSON grids, and
+// every cell of every bucket and category is written as CSV.
+package density
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+)
+
+// Key identifies the positions counted together: those in the same cell of the grid, in the same time bucket, of
+// vessels in the same ship type category.
+type Key struct {
+	// Bucket is the start of the time bucket, or the zero time if the Aggregator has no buckets.
+	Bucket   time.Time
+	Category shiptype.Category
+	Cell     Cell
+}
+
+// Stats are the statistics of the positions in a cell.
+type Stats struct {
+	// Count is the number of positions.
+	Count int
+	// Vessels is the number of unique vessels.
+	Vessels int
+	// MeanSpeed is the mean speed over ground of the positions which report it, in knots, or zero if none do.
+	MeanSpeed float64
+}
+
+// Value selects the value of Stats which is drawn on a map.
+type Value func(Stats) float64
+
+// Values of Stats.
+var (
+	Count     Value = func(s Stats) float64 { return float64(s.Count) }
+	Vessels   Value = func(s Stats) float64 { return float64(s.Vessels) }
+	MeanSpeed Value = func(s Stats) float64 { return s.MeanSpeed }
+)
+
+// Layer is the statistics of every cell of a grid which has positions.
+type Layer map[Cell]Stats
+
+// accumulator accumulates the statistics of a cell.
+type accumulator struct {
+	count    int
+	speedSum float64
+	speeds   int
+	vessels  map[int]struct{}
+}
+
+func (a *accumulator) merge(b *accumulator) {
+	a.count += b.count
+	a.speedSum += b.speedSum
+	a.speeds += b.speeds
+	if a.vessels == nil {
+		a.vessels = make(map[int]struct{}, len(b.vessels))
+	}
+	maps.Copy(a.vessels, b.vessels)
+}
+
+func (a *accumulator) stats() Stats {
+	s := Stats{Count: a.count, Vessels: len(a.vessels)}
+	if a.speeds > 0 {
+		s.MeanSpeed = a.speedSum / float64(a.speeds)
+	}
+	return s
+}
+
+// Aggregator accumulates positions into the cells of a grid, by time bucket and ship type category.
+//
+// Positions carry no ship type, so the Aggregator remembers the ship types of vessels from the static data and
+// combined messages it is given. Positions of vessels whose ship type is not yet known are counted in
+// shiptype.CategoryUnknown.
+//
+// An Aggregator must be constructed with the NewAggregator factory function. It is not safe for concurrent use.
+type Aggregator struct {
+	grid      Grid
+	bucket    time.Duration
+	cells     map[Key]*accumulator
+	shipTypes map[int]shiptype.ShipType
+}
+
+// NewAggregator creates an Aggregator over the grid, with time buckets of the given size, starting at multiples of
+// the size since the zero time. A size of zero puts every position in a single bucket.
+func NewAggregator(grid Grid, bucket time.Duration) *Aggregator {
+	return &Aggregator{
+		grid:      grid,
+		bucket:    bucket,
+		cells:     make(map[Key]*accumulator),
+		shipTypes: make(map[int]shiptype.ShipType),
+	}
+}
+
+// Grid returns the grid of the Aggregator.
+func (a *Aggregator) Grid() Grid {
+	return a.grid
+}
+
+// Add counts the position of the vessel, if it is known and inside the grid, and remembers its ship type, if it is
+// known.
+func (a *Aggregator) Add(v ais.Vessel) {
+	if v.ShipType != nil {
+		a.shipTypes[v.Mmsi] = shiptype.ShipType(*v.ShipType)
+	}
+	if v.Latitude == nil || v.Longitude == nil {
+		return
+	}
+	cell, ok := a.grid.Cell(*v.Longitude, *v.Latitude)
+	if !ok {
+		return
+	}
+
+	key := Key{Category: shiptype.CategoryUnknown, Cell: cell}
+	if a.bucket > 0 {
+		key.Bucket = v.Msgtime.UTC().Truncate(a.bucket)
+	}
+	if shipType, ok := a.shipTypes[v.Mmsi]; ok {
+		key.Category = shipType.Category()
+	}
+
+	acc, ok := a.cells[key]
+	if !ok {
+		acc = &accumulator{vessels: make(map[int]struct{})}
+		a.cells[key] = acc
+	}
+	acc.count++
+	acc.vessels[v.Mmsi] = struct{}{}
+	if v.SpeedOverGround != nil {
+		acc.speedSum += *v.SpeedOverGround
+		acc.speeds++
+	}
+}
+
+// Buckets returns the start of every time bucket which has positions, in order.
+func (a *Aggregator) Buckets() []time.Time {
+	buckets := make(map[time.Time]bool)
+	for key := range a.cells {
+		buckets[key.Bucket] = true
+	}
+	return slices.SortedFunc(maps.Keys(buckets), time.Time.Compare)
+}
+
+// Layer merges the cells of the bucket starting at the given time and of the given categories. The zero time merges
+// every bucket, and no categories merges every category. Vessels in more than one of the merged cells are counted
+// once.
+func (a *Aggregator) Layer(bucket time.Time, categories ...shiptype.Category) Layer {
+	merged := make(map[Cell]*accumulator)
+	for key, acc := range a.cells {
+		if !bucket.IsZero() && !key.Bucket.Equal(bucket) {
+			continue
+		}
+		if len(categories) > 0 && !slices.Contains(categories, key.Category) {
+			continue
+		}
+		m, ok := merged[key.Cell]
+		if !ok {
+			m = &accumulator{}
+			merged[key.Cell] = m
+		}
+		m.merge(acc)
+	}
+
+	res := make(Layer, len(merged))
+	for cell, acc := range merged {
+		res[cell] = acc.stats()
+	}
+	return res
+}
+
+// CellStats is the statistics of a cell in a time bucket and ship type category.
+type CellStats struct {
+	Key
+	Stats
+}
+
+// Cells returns the statistics of every cell with positions, ordered by bucket, category and cell.
+func (a *Aggregator) Cells() []CellStats {
+	res := make([]CellStats, 0, len(a.cells))
+	for key, acc := range a.cells {
+		res = append(res, CellStats{Key: key, Stats: acc.stats()})
+	}
+	slices.SortFunc(res, func(a, b CellStats) int {
+		return cmp.Or(
+			a.Bucket.Compare(b.Bucket),
+			cmp.Compare(a.Category, b.Category),
+			cmp.Compare(a.Cell.Y, b.Cell.Y),
+			cmp.Compare(a.Cell.X, b.Cell.X),
+		)
+	})
+	return res
+}
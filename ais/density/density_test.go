@@ -0,0 +1,182 @@
+package density_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais/density"
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/aistest"
+	"github.com/ilder-as/go-barentswatch-ais/ais/storage"
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+var bounds = storage.Bounds{MinLon: 0, MinLat: 60, MaxLon: 10, MaxLat: 70}
+
+func TestGrid(t *testing.T) {
+	square, err := density.NewSquareGrid(bounds, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hex, err := density.NewHexGrid(bounds, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, grid := range []density.Grid{square, hex} {
+		// Points are in the polygons of their cells, and so are the centres of cells inside the bounds
+		for _, p := range [][2]float64{{0, 60}, {3.3, 61.7}, {9.99, 69.99}, {10, 70}, {5.5, 65.5}} {
+			cell, ok := grid.Cell(p[0], p[1])
+			if !ok {
+				t.Fatalf("%T: expected %v inside the grid", grid, p)
+			}
+			lon, lat := grid.Center(cell)
+			if got, ok := grid.Cell(lon, lat); ok && got != cell {
+				t.Errorf("%T: expected the centre of %v to be in it, got %v", grid, cell, got)
+			}
+			ring := grid.Polygon(cell)
+			if len(ring) < 4 || ring[0][0] != ring[len(ring)-1][0] || ring[0][1] != ring[len(ring)-1][1] {
+				t.Errorf("%T: expected a closed ring, got %v", grid, ring)
+			}
+			if !storage.Contains(geojson.NewPolygonGeometry([][][]float64{ring}), p[0], p[1]) &&
+				p != [2]float64{10, 70} {
+				t.Errorf("%T: expected %v inside the polygon of %v", grid, p, cell)
+			}
+		}
+		if _, ok := grid.Cell(11, 65); ok {
+			t.Errorf("%T: expected a point outside the bounds to have no cell", grid)
+		}
+	}
+
+	if got, _ := square.Cell(10, 70); got != (density.Cell{X: 9, Y: 9}) {
+		t.Errorf("expected the north-east corner in the last cell, got %v", got)
+	}
+	if _, err := density.NewSquareGrid(bounds, 0); err == nil {
+		t.Error("expected an error for a zero cell size")
+	}
+	if _, err := density.NewHexGrid(storage.Bounds{}, 1); err == nil {
+		t.Error("expected an error for empty bounds")
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	grid, err := density.NewSquareGrid(bounds, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := aistest.Start
+	fishing := int(shiptype.Fishing)
+	trawler := aistest.Staticdata(2, 3*time.Hour, "TRAWLER")
+	trawler.ShipType = &fishing
+
+	agg := density.NewAggregator(grid, 24*time.Hour)
+	agg.Add(aistest.Position(1, time.Hour, 0.5, 60.5, 10).Vessel())
+	agg.Add(aistest.Position(1, 2*time.Hour, 0.6, 60.6, 12).Vessel())
+	agg.Add(aistest.Position(2, 3*time.Hour, 0.7, 60.7, 2).Vessel())
+	agg.Add(trawler.Vessel())
+	agg.Add(aistest.Position(2, 25*time.Hour, 0.5, 60.5, 4).Vessel())
+	agg.Add(aistest.Position(3, 0, 20, 60.5, 4).Vessel())
+
+	if buckets := agg.Buckets(); len(buckets) != 2 || !buckets[0].Equal(day) || !buckets[1].Equal(day.AddDate(0, 0, 1)) {
+		t.Fatalf("expected two daily buckets, got %v", buckets)
+	}
+
+	cell := density.Cell{}
+	tests := []struct {
+		name       string
+		bucket     time.Time
+		categories []shiptype.Category
+		want       density.Stats
+	}{
+		{"all", time.Time{}, nil, density.Stats{Count: 4, Vessels: 2, MeanSpeed: 7}},
+		{"bucket", day, nil, density.Stats{Count: 3, Vessels: 2, MeanSpeed: 8}},
+		{"category", time.Time{}, []shiptype.Category{shiptype.CategoryFishing}, density.Stats{
+			Count: 1, Vessels: 1, MeanSpeed: 4,
+		}},
+		{"unknown", day, []shiptype.Category{shiptype.CategoryUnknown}, density.Stats{Count: 3, Vessels: 2, MeanSpeed: 8}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			layer := agg.Layer(test.bucket, test.categories...)
+			if len(layer) != 1 || layer[cell] != test.want {
+				t.Errorf("expected %+v, got %+v", test.want, layer)
+			}
+		})
+	}
+
+	cells := agg.Cells()
+	if len(cells) != 2 || cells[1].Category != shiptype.CategoryFishing || cells[1].Count != 1 {
+		t.Fatalf("unexpected cells %+v", cells)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	grid, err := density.NewHexGrid(bounds, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agg := density.NewAggregator(grid, 0)
+	for i := range 100 {
+		agg.Add(aistest.Position(i%7, 0, float64(i%10), 60+float64(i)/10, float64(i)).Vessel())
+	}
+	layer := agg.Layer(time.Time{})
+
+	var buf bytes.Buffer
+	if err := (density.Heatmap{Width: 200, Log: true}).WritePNG(&buf, grid, layer); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := img.Bounds().Size(); size.X != 200 || size.Y != 473 {
+		t.Errorf("expected a 200 pixel wide image 473 pixels high, got %v", size)
+	}
+	painted := 0
+	for y := range img.Bounds().Dy() {
+		for x := range img.Bounds().Dx() {
+			if _, _, _, a := img.At(x, y).RGBA(); a > 0 {
+				painted++
+			}
+		}
+	}
+	if painted == 0 {
+		t.Error("expected painted pixels")
+	}
+
+	buf.Reset()
+	if err := density.WriteGeoJSON(&buf, grid, layer); err != nil {
+		t.Fatal(err)
+	}
+	var fc geojson.FeatureCollection
+	if err := json.Unmarshal(buf.Bytes(), &fc); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, f := range fc.Features {
+		count, _ := f.PropertyInt("count")
+		total += count
+		if !f.Geometry.IsPolygon() || len(f.Geometry.Polygon[0]) != 7 {
+			t.Fatalf("expected hexagons, got %+v", f.Geometry)
+		}
+	}
+	if len(fc.Features) != len(layer) || total != 100 {
+		t.Errorf("expected %d features counting 100 positions, got %d counting %d", len(layer), len(fc.Features), total)
+	}
+
+	buf.Reset()
+	if err := density.WriteCSV(&buf, grid, agg.Cells()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(agg.Cells())+1 || records[1][0] != "" || records[1][1] != "Unknown" {
+		t.Errorf("unexpected records %v", records)
+	}
+}
@@ -0,0 +1,149 @@
+package density
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais/storage"
+)
+
+// Cell identifies a cell of a grid. Its meaning depends on the grid: columns and rows for a SquareGrid, and axial
+// coordinates for a HexGrid.
+type Cell struct {
+	X, Y int
+}
+
+// Grid divides an area into cells.
+type Grid interface {
+	// Bounds returns the area covered by the grid.
+	Bounds() storage.Bounds
+	// Cell returns the cell containing the coordinates, or false if they are outside the bounds of the grid.
+	Cell(lon, lat float64) (Cell, bool)
+	// Center returns the coordinates of the centre of the cell.
+	Center(c Cell) (lon, lat float64)
+	// Polygon returns the closed outer ring of the cell, as coordinates in longitude, latitude order.
+	Polygon(c Cell) [][]float64
+}
+
+// SquareGrid divides its bounds into cells of Size degrees of longitude and latitude, numbered from the south-west
+// corner of the bounds.
+type SquareGrid struct {
+	Area storage.Bounds
+	Size float64
+}
+
+// NewSquareGrid creates a SquareGrid over the bounds, with cells of size degrees.
+func NewSquareGrid(bounds storage.Bounds, size float64) (*SquareGrid, error) {
+	if err := validate(bounds, size); err != nil {
+		return nil, err
+	}
+	return &SquareGrid{Area: bounds, Size: size}, nil
+}
+
+// Bounds returns the area covered by the grid.
+func (g *SquareGrid) Bounds() storage.Bounds {
+	return g.Area
+}
+
+// Cell returns the cell containing the coordinates. Points on the northern and eastern edges of the bounds belong to
+// the cells along those edges.
+func (g *SquareGrid) Cell(lon, lat float64) (Cell, bool) {
+	if !g.Area.Contains(lon, lat) {
+		return Cell{}, false
+	}
+	x := int(math.Floor((lon - g.Area.MinLon) / g.Size))
+	y := int(math.Floor((lat - g.Area.MinLat) / g.Size))
+	columns := int(math.Ceil((g.Area.MaxLon - g.Area.MinLon) / g.Size))
+	rows := int(math.Ceil((g.Area.MaxLat - g.Area.MinLat) / g.Size))
+	return Cell{min(x, columns-1), min(y, rows-1)}, true
+}
+
+// Center returns the coordinates of the centre of the cell.
+func (g *SquareGrid) Center(c Cell) (lon, lat float64) {
+	return g.Area.MinLon + (float64(c.X)+0.5)*g.Size, g.Area.MinLat + (float64(c.Y)+0.5)*g.Size
+}
+
+// Polygon returns the closed outer ring of the cell.
+func (g *SquareGrid) Polygon(c Cell) [][]float64 {
+	minLon, minLat := g.Area.MinLon+float64(c.X)*g.Size, g.Area.MinLat+float64(c.Y)*g.Size
+	maxLon, maxLat := minLon+g.Size, minLat+g.Size
+	return [][]float64{{minLon, minLat}, {maxLon, minLat}, {maxLon, maxLat}, {minLon, maxLat}, {minLon, minLat}}
+}
+
+// HexGrid divides its bounds into pointy-top hexagons, in the manner of H3 but without its global index. Size is the
+// distance from the centre of a hexagon to its corners, in degrees of latitude.
+//
+// Longitudes are scaled by the cosine of the latitude at the middle of the bounds, so that hexagons are close to
+// regular there, and stretched north and south of it. Cells are axial coordinates, with the hexagon centred on the
+// south-west corner of the bounds as the origin.
+type HexGrid struct {
+	Area storage.Bounds
+	Size float64
+}
+
+// NewHexGrid creates a HexGrid over the bounds, with hexagons of size degrees from their centres to their corners.
+func NewHexGrid(bounds storage.Bounds, size float64) (*HexGrid, error) {
+	if err := validate(bounds, size); err != nil {
+		return nil, err
+	}
+	return &HexGrid{Area: bounds, Size: size}, nil
+}
+
+// Bounds returns the area covered by the grid.
+func (g *HexGrid) Bounds() storage.Bounds {
+	return g.Area
+}
+
+func (g *HexGrid) scale() float64 {
+	return math.Cos((g.Area.MinLat + g.Area.MaxLat) / 2 * math.Pi / 180)
+}
+
+// Cell returns the hexagon containing the coordinates.
+func (g *HexGrid) Cell(lon, lat float64) (Cell, bool) {
+	if !g.Area.Contains(lon, lat) {
+		return Cell{}, false
+	}
+	x, y := (lon-g.Area.MinLon)*g.scale(), lat-g.Area.MinLat
+	q := (math.Sqrt(3)/3*x - y/3) / g.Size
+	r := 2 * y / 3 / g.Size
+
+	// Round the cube coordinates q, r, -q-r, and fix the one which was rounded the most
+	rq, rr, rs := math.Round(q), math.Round(r), math.Round(-q-r)
+	dq, dr, ds := math.Abs(rq-q), math.Abs(rr-r), math.Abs(rs+q+r)
+	switch {
+	case dq > dr && dq > ds:
+		rq = -rr - rs
+	case dr > ds:
+		rr = -rq - rs
+	}
+	return Cell{int(rq), int(rr)}, true
+}
+
+// Center returns the coordinates of the centre of the hexagon.
+func (g *HexGrid) Center(c Cell) (lon, lat float64) {
+	x := g.Size * math.Sqrt(3) * (float64(c.X) + float64(c.Y)/2)
+	y := g.Size * 3 / 2 * float64(c.Y)
+	return g.Area.MinLon + x/g.scale(), g.Area.MinLat + y
+}
+
+// Polygon returns the closed outer ring of the hexagon.
+func (g *HexGrid) Polygon(c Cell) [][]float64 {
+	lon, lat := g.Center(c)
+	ring := make([][]float64, 7)
+	for i := range 6 {
+		angle := float64(60*i-30) * math.Pi / 180
+		ring[i] = []float64{lon + g.Size*math.Cos(angle)/g.scale(), lat + g.Size*math.Sin(angle)}
+	}
+	ring[6] = ring[0]
+	return ring
+}
+
+func validate(bounds storage.Bounds, size float64) error {
+	if !(size > 0) {
+		return fmt.Errorf("density: cell size must be positive, got %v", size)
+	}
+	if !(bounds.MinLon < bounds.MaxLon && bounds.MinLat < bounds.MaxLat) {
+		return fmt.Errorf("density: empty bounds %+v", bounds)
+	}
+	return nil
+}
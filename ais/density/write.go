@@ -0,0 +1,188 @@
+package density
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// DefaultGradient is the gradient of a Heatmap without one, from transparent blue for the lowest values through green
+// and yellow to opaque red for the highest.
+var DefaultGradient = []color.NRGBA{
+	{0, 0, 255, 96},
+	{0, 255, 255, 160},
+	{0, 255, 0, 192},
+	{255, 255, 0, 224},
+	{255, 0, 0, 255},
+}
+
+// Heatmap draws layers as images of the bounds of their grids, with cells without positions left transparent. The
+// image is an equirectangular projection, which can be placed on a web map as an image overlay of the bounds.
+type Heatmap struct {
+	// Width is the width of the image in pixels. Its height follows from the bounds of the grid, with longitudes
+	// scaled by the cosine of the latitude at the middle of the bounds, so that cells are not stretched. Zero means
+	// 1024.
+	Width int
+	// Value is the value which is drawn. Nil means Count.
+	Value Value
+	// Gradient is the colours of values from the lowest to the highest, which are interpolated between. Nil means
+	// DefaultGradient.
+	Gradient []color.NRGBA
+	// Max is the value drawn with the last colour of the gradient, and values above it are drawn the same. Zero means
+	// the highest value of the layer.
+	Max float64
+	// Log scales values logarithmically, so that cells with few positions are still visible next to busy shipping
+	// lanes.
+	Log bool
+}
+
+// Image draws the layer.
+func (h Heatmap) Image(grid Grid, layer Layer) *image.NRGBA {
+	width := cmp.Or(h.Width, 1024)
+	value := h.Value
+	if value == nil {
+		value = Count
+	}
+	gradient := h.Gradient
+	if len(gradient) == 0 {
+		gradient = DefaultGradient
+	}
+
+	bounds := grid.Bounds()
+	lonSpan, latSpan := bounds.MaxLon-bounds.MinLon, bounds.MaxLat-bounds.MinLat
+	scale := math.Cos((bounds.MinLat + bounds.MaxLat) / 2 * math.Pi / 180)
+	height := max(1, int(math.Round(float64(width)*latSpan/(lonSpan*scale))))
+
+	maxValue := h.Max
+	if maxValue == 0 {
+		for _, s := range layer {
+			maxValue = max(maxValue, value(s))
+		}
+	}
+	normalise := func(v float64) float64 {
+		if maxValue <= 0 {
+			return 1
+		}
+		if h.Log {
+			return min(1, math.Log1p(max(0, v))/math.Log1p(maxValue))
+		}
+		return min(1, max(0, v/maxValue))
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for py := range height {
+		lat := bounds.MaxLat - (float64(py)+0.5)/float64(height)*latSpan
+		for px := range width {
+			lon := bounds.MinLon + (float64(px)+0.5)/float64(width)*lonSpan
+			cell, ok := grid.Cell(lon, lat)
+			if !ok {
+				continue
+			}
+			if s, ok := layer[cell]; ok {
+				img.SetNRGBA(px, py, interpolate(gradient, normalise(value(s))))
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG draws the layer as a PNG image.
+func (h Heatmap) WritePNG(w io.Writer, grid Grid, layer Layer) error {
+	return png.Encode(w, h.Image(grid, layer))
+}
+
+// interpolate returns the colour of the gradient at t, between 0 and 1.
+func interpolate(gradient []color.NRGBA, t float64) color.NRGBA {
+	if len(gradient) == 1 {
+		return gradient[0]
+	}
+	pos := t * float64(len(gradient)-1)
+	i := min(int(pos), len(gradient)-2)
+	f := pos - float64(i)
+	a, b := gradient[i], gradient[i+1]
+	mix := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + f*(float64(b)-float64(a))))
+	}
+	return color.NRGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// cells returns the cells of the layer, ordered by row and column.
+func (l Layer) cells() []Cell {
+	res := make([]Cell, 0, len(l))
+	for cell := range l {
+		res = append(res, cell)
+	}
+	slices.SortFunc(res, func(a, b Cell) int {
+		return cmp.Or(cmp.Compare(a.Y, b.Y), cmp.Compare(a.X, b.X))
+	})
+	return res
+}
+
+// FeatureCollection returns a Polygon feature for every cell of the layer, with the properties "x" and "y", which
+// identify the cell, and "count", "vessels" and "meanSpeed", which are its statistics.
+func FeatureCollection(grid Grid, layer Layer) *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	for _, cell := range layer.cells() {
+		s := layer[cell]
+		f := geojson.NewPolygonFeature([][][]float64{grid.Polygon(cell)})
+		f.SetProperty("x", cell.X)
+		f.SetProperty("y", cell.Y)
+		f.SetProperty("count", s.Count)
+		f.SetProperty("vessels", s.Vessels)
+		f.SetProperty("meanSpeed", s.MeanSpeed)
+		fc.AddFeature(f)
+	}
+	return fc
+}
+
+// WriteGeoJSON writes the layer as a GeoJSON FeatureCollection, as returned by FeatureCollection.
+func WriteGeoJSON(w io.Writer, grid Grid, layer Layer) error {
+	return json.NewEncoder(w).Encode(FeatureCollection(grid, layer))
+}
+
+// CSVColumns are the names of the columns written by WriteCSV, in order.
+var CSVColumns = []string{"bucket", "category", "x", "y", "longitude", "latitude", "count", "vessels", "meanSpeed"}
+
+// WriteCSV writes the cells as CSV, with a header of CSVColumns. Buckets are written in RFC 3339 format, or empty if
+// the cells have no buckets, and the longitudes and latitudes are of the centres of the cells.
+func WriteCSV(w io.Writer, grid Grid, cells []CellStats) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVColumns); err != nil {
+		return err
+	}
+
+	record := make([]string, len(CSVColumns))
+	for _, c := range cells {
+		bucket := ""
+		if !c.Bucket.IsZero() {
+			bucket = c.Bucket.Format(time.RFC3339)
+		}
+		lon, lat := grid.Center(c.Cell)
+		record = append(record[:0],
+			bucket,
+			c.Category.String(),
+			strconv.Itoa(c.Cell.X),
+			strconv.Itoa(c.Cell.Y),
+			strconv.FormatFloat(lon, 'f', -1, 64),
+			strconv.FormatFloat(lat, 'f', -1, 64),
+			strconv.Itoa(c.Count),
+			strconv.Itoa(c.Vessels),
+			strconv.FormatFloat(c.MeanSpeed, 'f', -1, 64),
+		)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,345 @@
+// Package encounter detects ship-to-ship encounters, where two vessels stay close together at low speed for a long
+// time, as when transferring fish or cargo at sea.
+//
+// A Detector follows the positions of vessels in a spatial grid, so that only vessels near each other are compared,
+// and reports an encounter when two vessels part after having stayed within MaxDistance of each other, both at most
+// at MaxSpeed, for at least MinDuration:
+//
+//	d := encounter.NewDetector()
+//	d.ExcludeShipTypes = []shiptype.ShipType{shiptype.Tug, shiptype.PilotVessel}
+//	d.ExcludeAreas = ports
+//	for e := range encounter.Detect(ctx, d, msgs) {
+//		// ...
+//	}
+package encounter
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/geometry"
+	"github.com/ilder-as/go-barentswatch-ais/navmath"
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// Encounter is two vessels staying close together at low speed.
+type Encounter struct {
+	// Vessels are the two vessels, ordered by MMSI, with their static data and their latest positions as of End.
+	Vessels [2]ais.Vessel
+	// Start and End are the times of the first and last positions at which the vessels were together.
+	Start time.Time
+	End   time.Time
+	// Longitude and Latitude are the mean of the midpoints between the vessels during the encounter.
+	Longitude float64
+	Latitude  float64
+	// MinDistance is the shortest distance between the vessels during the encounter, in metres.
+	MinDistance float64
+
+	positions int
+}
+
+// Duration returns the time from the start to the end of the encounter.
+func (e Encounter) Duration() time.Duration {
+	return e.End.Sub(e.Start)
+}
+
+// Defaults of Detector.
+const (
+	DefaultMaxDistance = 500.0
+	DefaultMaxSpeed    = 2.0
+	DefaultMinDuration = 2 * time.Hour
+	DefaultMaxGap      = 15 * time.Minute
+)
+
+// metresPerDegree is the length of a degree of latitude in metres.
+const metresPerDegree = navmath.EarthRadius * math.Pi / 180
+
+type cell struct {
+	row, col int
+}
+
+// vessel is what a Detector knows about a vessel.
+type vessel struct {
+	ais.Vessel
+	// located is true if the vessel's position is in the grid, at cell
+	located bool
+	cell    cell
+	// encounters are the encounters in progress with other vessels, by their MMSI
+	encounters map[int]*Encounter
+}
+
+// Detector detects encounters between vessels.
+//
+// Vessels are together when their latest positions are within MaxDistance of each other and at most MaxGap apart in
+// time, and both report a speed over ground of at most MaxSpeed. Encounters are detected as vessels report their
+// positions, and end when one of the vessels reports a position where they are no longer together, or when the latest
+// position of one of them falls more than MaxGap behind the latest position of any vessel. Vessels are forgotten once
+// they have reported nothing for MaxGap, so that the Detector does not grow over a long stream.
+//
+// Vessels of the excluded ship types and categories, once their static data is known, and vessels inside the excluded
+// areas, are never together with other vessels.
+//
+// A position older than the latest position of its vessel is ignored. Vessels are compared by the times of their
+// latest positions, however recent their static data.
+//
+// A Detector must be constructed with the NewDetector factory function. It is not safe for concurrent use, and its
+// fields must be set before it is used.
+type Detector struct {
+	vessels map[int]*vessel
+	grid    map[cell]map[int]*vessel
+	// latest is the time of the latest position of any vessel, and pruned the time of the latest position when
+	// vessels were last pruned
+	latest time.Time
+	pruned time.Time
+
+	// MaxDistance is the longest distance between vessels which are together, in metres. Zero means
+	// DefaultMaxDistance.
+	MaxDistance float64
+	// MaxSpeed is the highest speed over ground of vessels which are together, in knots. Zero means DefaultMaxSpeed.
+	MaxSpeed float64
+	// MinDuration is the shortest encounter reported. Zero means DefaultMinDuration.
+	MinDuration time.Duration
+	// MaxGap is the longest time between the positions of vessels which are together. Zero means DefaultMaxGap.
+	MaxGap time.Duration
+
+	// ExcludeShipTypes and ExcludeCategories are the ship types and categories of vessels which are excluded, such
+	// as tugs and pilot vessels, which meet other vessels as part of their work.
+	ExcludeShipTypes  []shiptype.ShipType
+	ExcludeCategories []shiptype.Category
+	// ExcludeAreas are the areas, as Polygon or MultiPolygon geometries, where vessels are excluded, such as ports.
+	ExcludeAreas []*geojson.Geometry
+}
+
+// NewDetector creates a Detector.
+func NewDetector() *Detector {
+	return &Detector{
+		vessels: make(map[int]*vessel),
+		grid:    make(map[cell]map[int]*vessel),
+	}
+}
+
+// cellSize is the size of the cells of the grid in degrees of latitude, and at the equator, of longitude.
+func (d *Detector) cellSize() float64 {
+	return cmp.Or(d.MaxDistance, DefaultMaxDistance) / metresPerDegree
+}
+
+func (d *Detector) cellOf(lon, lat float64) cell {
+	size := d.cellSize()
+	return cell{int(math.Floor(lat / size)), int(math.Floor(lon / size))}
+}
+
+func (d *Detector) locate(vs *vessel) {
+	d.unlocate(vs)
+	if vs.Latitude == nil || vs.Longitude == nil {
+		return
+	}
+	vs.cell, vs.located = d.cellOf(*vs.Longitude, *vs.Latitude), true
+	if d.grid[vs.cell] == nil {
+		d.grid[vs.cell] = make(map[int]*vessel)
+	}
+	d.grid[vs.cell][vs.Mmsi] = vs
+}
+
+func (d *Detector) unlocate(vs *vessel) {
+	if !vs.located {
+		return
+	}
+	delete(d.grid[vs.cell], vs.Mmsi)
+	if len(d.grid[vs.cell]) == 0 {
+		delete(d.grid, vs.cell)
+	}
+	vs.located = false
+}
+
+// eligible is true if the vessel can be together with other vessels.
+func (d *Detector) eligible(vs *vessel) bool {
+	if !vs.located || vs.SpeedOverGround == nil || *vs.SpeedOverGround > cmp.Or(d.MaxSpeed, DefaultMaxSpeed) {
+		return false
+	}
+	if vs.ShipType != nil {
+		t := shiptype.ShipType(*vs.ShipType)
+		if slices.Contains(d.ExcludeShipTypes, t) || slices.Contains(d.ExcludeCategories, t.Category()) {
+			return false
+		}
+	}
+	for _, area := range d.ExcludeAreas {
+		if geometry.Contains(area, *vs.Longitude, *vs.Latitude) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasStatic is true if the vessel carries static data.
+func hasStatic(v ais.Vessel) bool {
+	return v.Name != "" || v.ShipType != nil || v.CallSign != "" || v.ImoNumber != nil
+}
+
+// Add updates what the Detector knows about the vessel, and returns the encounters which ended, if any. These are the
+// encounters of the vessel, and those of vessels which have stopped reporting.
+func (d *Detector) Add(v ais.Vessel) []Encounter {
+	vs, ok := d.vessels[v.Mmsi]
+	if !ok {
+		vs = &vessel{encounters: make(map[int]*Encounter)}
+		d.vessels[v.Mmsi] = vs
+	}
+	if hasStatic(v) {
+		vs.UpdateStaticdata(v.AsStaticdata())
+	}
+	if v.Latitude == nil || v.Longitude == nil || v.Msgtime.Before(vs.PositionTime) {
+		return nil
+	}
+	vs.UpdatePosition(v.AsPosition())
+	d.locate(vs)
+
+	together := make(map[int]*vessel)
+	if d.eligible(vs) {
+		maxDistance := cmp.Or(d.MaxDistance, DefaultMaxDistance)
+		maxGap := cmp.Or(d.MaxGap, DefaultMaxGap)
+		// Cells of longitude narrow towards the poles, so more of them are searched
+		cols := int(math.Ceil(1 / max(math.Cos(*vs.Latitude*math.Pi/180), 0.01)))
+		for row := vs.cell.row - 1; row <= vs.cell.row+1; row++ {
+			for col := vs.cell.col - cols; col <= vs.cell.col+cols; col++ {
+				for mmsi, other := range d.grid[cell{row, col}] {
+					if mmsi == vs.Mmsi || vs.PositionTime.Sub(other.PositionTime).Abs() > maxGap || !d.eligible(other) {
+						continue
+					}
+					dist, _ := vs.DistanceTo(other)
+					if dist <= maxDistance {
+						together[mmsi] = other
+						d.extend(vs, other, dist)
+					}
+				}
+			}
+		}
+	}
+
+	var res []Encounter
+	for mmsi := range vs.encounters {
+		if together[mmsi] == nil {
+			res = append(res, d.end(vs, d.vessels[mmsi])...)
+		}
+	}
+
+	// Vessels are pruned at most once every MaxGap, as it takes a pass over all of them
+	if vs.PositionTime.After(d.latest) {
+		d.latest = vs.PositionTime
+	}
+	if maxGap := cmp.Or(d.MaxGap, DefaultMaxGap); d.latest.Sub(d.pruned) >= maxGap {
+		res = append(res, d.prune(d.latest.Add(-maxGap))...)
+		d.pruned = d.latest
+	}
+	sortEncounters(res)
+	return res
+}
+
+// prune ends the encounters of the vessels whose latest position is before the time, as they can no longer be
+// together with other vessels, and forgets the vessels which have reported nothing since.
+func (d *Detector) prune(before time.Time) []Encounter {
+	var res []Encounter
+	for mmsi, vs := range d.vessels {
+		if !vs.PositionTime.Before(before) {
+			continue
+		}
+		for other := range vs.encounters {
+			res = append(res, d.end(vs, d.vessels[other])...)
+		}
+		if vs.Msgtime.Before(before) {
+			d.unlocate(vs)
+			delete(d.vessels, mmsi)
+		}
+	}
+	return res
+}
+
+// extend starts or extends the encounter of the vessels, at the time of the position of vs, and keeps the vessels as
+// they are then.
+func (d *Detector) extend(vs, other *vessel, dist float64) {
+	e, ok := vs.encounters[other.Mmsi]
+	if !ok {
+		e = &Encounter{Start: vs.PositionTime, MinDistance: dist}
+		vs.encounters[other.Mmsi] = e
+		other.encounters[vs.Mmsi] = e
+	}
+	e.End = vs.PositionTime
+	e.Vessels = [2]ais.Vessel{vs.Vessel, other.Vessel}
+	if other.Mmsi < vs.Mmsi {
+		e.Vessels[0], e.Vessels[1] = e.Vessels[1], e.Vessels[0]
+	}
+	e.MinDistance = min(e.MinDistance, dist)
+	lon := *vs.Longitude + math.Remainder(*other.Longitude-*vs.Longitude, 360)/2
+	lat := (*vs.Latitude + *other.Latitude) / 2
+	e.positions++
+	e.Longitude += (lon - e.Longitude) / float64(e.positions)
+	e.Latitude += (lat - e.Latitude) / float64(e.positions)
+}
+
+// end ends the encounter of the vessels, and returns it if it lasted long enough.
+func (d *Detector) end(a, b *vessel) []Encounter {
+	e := a.encounters[b.Mmsi]
+	delete(a.encounters, b.Mmsi)
+	delete(b.encounters, a.Mmsi)
+	if e.Duration() < cmp.Or(d.MinDuration, DefaultMinDuration) {
+		return nil
+	}
+	return []Encounter{*e}
+}
+
+// Flush ends every encounter in progress, and returns those which lasted long enough, ordered by start.
+func (d *Detector) Flush() []Encounter {
+	var res []Encounter
+	for _, vs := range d.vessels {
+		for mmsi := range vs.encounters {
+			res = append(res, d.end(vs, d.vessels[mmsi])...)
+		}
+	}
+	sortEncounters(res)
+	return res
+}
+
+func sortEncounters(encounters []Encounter) {
+	slices.SortFunc(encounters, func(a, b Encounter) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(a.Vessels[0].Mmsi, b.Vessels[0].Mmsi))
+	})
+}
+
+// Detect adds every message received from in to the Detector, and sends the encounters which end. When in is
+// closed, the encounters in progress are ended with Flush and sent, and the channel of encounters is closed. It is
+// also closed when the context is cancelled. It accepts the channel of StreamResponse.UnmarshalStream directly, as
+// well as channels of any of the combined types.
+func Detect[T interface{ Vessel() ais.Vessel }](ctx context.Context, d *Detector, in <-chan T) <-chan Encounter {
+	out := make(chan Encounter)
+	go func() {
+		defer close(out)
+		send := func(encounters []Encounter) bool {
+			for _, e := range encounters {
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return false
+				}
+			}
+			return true
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-in:
+				if !ok {
+					send(d.Flush())
+					return
+				}
+				if !send(d.Add(msg.Vessel())) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,126 @@
+package encounter_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/encounter"
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/aistest"
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func static(mmsi int, name string, t shiptype.ShipType) ais.Staticdata {
+	shipType := int(t)
+	s := aistest.Staticdata(mmsi, 0, name)
+	s.ShipType = &shipType
+	return s
+}
+
+func TestDetector(t *testing.T) {
+	port := geojson.NewPolygonGeometry([][][]float64{{{10, 60}, {11, 60}, {11, 61}, {10, 61}, {10, 60}}})
+	d := encounter.NewDetector()
+	d.ExcludeShipTypes = []shiptype.ShipType{shiptype.Tug}
+	d.ExcludeAreas = []*geojson.Geometry{port}
+
+	msgs := []aistest.Message{
+		static(1, "REEFER", shiptype.CargoAllShips),
+		static(2, "TRAWLER", shiptype.Fishing),
+		static(3, "TUG", shiptype.Tug),
+	}
+	for at := time.Duration(0); at <= 4*time.Hour; at += 10 * time.Minute {
+		drift := at.Minutes() * 1e-5
+		// Vessels 1 and 2 drift side by side, about 200 metres apart, for three hours, and 2 then leaves. The
+		// tug stays with vessel 1 throughout.
+		msgs = append(msgs, aistest.Position(1, at, 5, 70+drift, 0.5))
+		if at <= 3*time.Hour {
+			msgs = append(msgs, aistest.Position(2, at+time.Minute, 5.005, 70+drift, 1))
+		} else {
+			msgs = append(msgs, aistest.Position(2, at+time.Minute, 5.005+(at-3*time.Hour).Minutes()*1e-2, 70, 10))
+		}
+		msgs = append(msgs, aistest.Position(3, at+2*time.Minute, 4.995, 70, 1))
+
+		// Vessels 4 and 5 meet for an hour, which is too short
+		if at <= time.Hour {
+			msgs = append(msgs, aistest.Position(4, at, 20, 72, 0), aistest.Position(5, at, 20.001, 72, 0))
+		}
+		// Vessels 6 and 7 lie together in port
+		msgs = append(msgs, aistest.Position(6, at, 10.5, 60.5, 0), aistest.Position(7, at, 10.501, 60.5, 0))
+		// Vessels 8 and 9 are still together when the stream ends
+		msgs = append(msgs, aistest.Position(8, at, -5, 50, 0), aistest.Position(9, at, -5, 50.001, 0))
+	}
+
+	var encounters []encounter.Encounter
+	for e := range encounter.Detect(context.Background(), d, aistest.Stream(msgs)) {
+		encounters = append(encounters, e)
+	}
+
+	if len(encounters) != 2 {
+		t.Fatalf("expected 2 encounters, got %+v", encounters)
+	}
+	e := encounters[0]
+	if e.Vessels[0].Mmsi != 1 || e.Vessels[1].Mmsi != 2 || e.Vessels[1].Name != "TRAWLER" {
+		t.Errorf("expected an encounter of vessels 1 and 2 with their static data, got %+v", e.Vessels)
+	}
+	// The encounter ends when vessel 2 reports that it has left, and vessel 1 last saw it at 190 minutes
+	if !e.Start.Equal(aistest.Start.Add(time.Minute)) || !e.End.Equal(aistest.Start.Add(190*time.Minute)) {
+		t.Errorf("expected the encounter from 1 to 190 minutes, got %v to %v", e.Start, e.End)
+	}
+	if *e.Vessels[1].Longitude != 5.005 {
+		t.Errorf("expected vessel 2 at its last position in the encounter, got %f", *e.Vessels[1].Longitude)
+	}
+	if math.Abs(e.Longitude-5.0025) > 1e-6 || math.Abs(e.Latitude-70.0009) > 1e-3 || e.MinDistance > 200 ||
+		e.MinDistance < 150 {
+		t.Errorf("unexpected location or distance of %+v", e)
+	}
+	if e := encounters[1]; e.Vessels[0].Mmsi != 8 || e.Duration() != 4*time.Hour {
+		t.Errorf("expected the encounter in progress to be flushed, got %+v", e)
+	}
+}
+
+func TestDetector_Order(t *testing.T) {
+	msgs := []aistest.Message{aistest.Staticdata(1, 3*time.Hour, "REEFER")}
+	for at := time.Duration(0); at <= 150*time.Minute; at += 10 * time.Minute {
+		msgs = append(msgs, aistest.Position(1, at, 5, 70, 0), aistest.Position(2, at+time.Minute, 5.005, 70, 0))
+	}
+
+	var encounters []encounter.Encounter
+	for e := range encounter.Detect(context.Background(), encounter.NewDetector(), aistest.Stream(msgs)) {
+		encounters = append(encounters, e)
+	}
+	if len(encounters) != 1 || encounters[0].Vessels[0].Name != "REEFER" ||
+		!encounters[0].Start.Equal(aistest.Start.Add(time.Minute)) {
+		t.Fatalf("expected an encounter from the first positions, got %+v", encounters)
+	}
+}
+
+func TestDetector_Prune(t *testing.T) {
+	// Vessels 1 and 2 stop reporting after three hours together, while vessel 3 far away reports on
+	var msgs []aistest.Message
+	for at := time.Duration(0); at <= 6*time.Hour; at += 10 * time.Minute {
+		if at <= 3*time.Hour {
+			msgs = append(msgs, aistest.Position(1, at, 5, 70, 0), aistest.Position(2, at, 5.005, 70, 0))
+		}
+		msgs = append(msgs, aistest.Position(3, at, 20, 72, 12))
+	}
+
+	d := encounter.NewDetector()
+	var encounters []encounter.Encounter
+	for _, msg := range msgs {
+		for _, e := range d.Add(msg.Vessel()) {
+			if at := e.End.Add(2 * encounter.DefaultMaxGap); msg.Vessel().Msgtime.After(at) {
+				t.Errorf("expected the encounter to end by %v, ended at %v", at, msg.Vessel().Msgtime)
+			}
+			encounters = append(encounters, e)
+		}
+	}
+	if len(encounters) != 1 || encounters[0].Vessels[0].Mmsi != 1 || encounters[0].Duration() != 3*time.Hour {
+		t.Fatalf("expected the encounter of vessels 1 and 2 to end without Flush, got %+v", encounters)
+	}
+	if flushed := d.Flush(); len(flushed) != 0 {
+		t.Errorf("expected no encounters in progress, got %+v", flushed)
+	}
+}
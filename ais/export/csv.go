@@ -0,0 +1,108 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// CSVWriter writes rows as CSV, with a header of Columns. Null fields are written as empty cells, which pandas and
+// DuckDB read as nulls, and times are written in RFC 3339 format.
+//
+// A CSVWriter must be constructed with the NewCSVWriter factory function. It is not safe for concurrent use.
+type CSVWriter struct {
+	w      *csv.Writer
+	header bool
+	record []string
+}
+
+// NewCSVWriter creates a CSVWriter which writes to w.
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{
+		w:      csv.NewWriter(w),
+		record: make([]string, len(Columns)),
+	}
+}
+
+// Write writes the row, preceded by the header if it is the first.
+func (c *CSVWriter) Write(r Row) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	c.record = append(c.record[:0],
+		r.Type,
+		strconv.Itoa(r.Mmsi),
+		r.Msgtime.Format(timeFormat),
+		formatIntPtr(r.MessageType),
+		formatFloatPtr(r.Latitude),
+		formatFloatPtr(r.Longitude),
+		formatIntPtr(r.Altitude),
+		formatFloatPtr(r.SpeedOverGround),
+		formatFloatPtr(r.CourseOverGround),
+		formatFloatPtr(r.RateOfTurn),
+		formatIntPtr(r.TrueHeading),
+		formatIntPtr(r.NavigationalStatus),
+		formatStringPtr(r.Name),
+		formatIntPtr(r.ShipType),
+		formatIntPtr(r.ImoNumber),
+		formatStringPtr(r.CallSign),
+		formatStringPtr(r.Destination),
+		formatStringPtr(r.Eta),
+		formatIntPtr(r.Draught),
+		formatIntPtr(r.ShipLength),
+		formatIntPtr(r.ShipWidth),
+		formatIntPtr(r.DimensionA),
+		formatIntPtr(r.DimensionB),
+		formatIntPtr(r.DimensionC),
+		formatIntPtr(r.DimensionD),
+		formatIntPtr(r.PositionFixingDeviceType),
+		formatStringPtr(r.AisClass),
+		formatIntPtr(r.TypeOfAidsToNavigation),
+		formatIntPtr(r.TypeOfElectronicFixingDevice),
+	)
+	return c.w.Write(c.record)
+}
+
+// Flush writes any buffered rows to the underlying writer.
+func (c *CSVWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// Close writes the header if no rows were written, and flushes buffered rows. It does not close the underlying writer.
+func (c *CSVWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.Flush()
+}
+
+func (c *CSVWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(Columns)
+}
+
+func formatIntPtr(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
+
+func formatFloatPtr(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return formatFloat(*f)
+}
+
+func formatStringPtr(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
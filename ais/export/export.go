@@ -0,0 +1,143 @@
+// Package export converts AIS data to formats used by maps, GIS tools and analytics: GeoJSON, KML and GPX for maps,
+// and CSV and Parquet for analytics.
+//
+// Exporters work on snapshots of vessels, as ais.Vessel values, and on vessel tracks, which are collected with Tracks.
+// Both can be built from any of the response types which describe vessels:
+//
+//	res, err := client.GetLatestCombined()
+//	// ...
+//	latest, err := res.Unmarshal()
+//	// ...
+//	fc := export.FeatureCollection(export.Vessels(latest), nil, nil)
+//
+// Tabular exporters instead flatten every message into a Row, and can be used as a Sink at the end of a stream.
+package export
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+)
+
+// Vessels converts messages which describe vessels, such as the result of GetLatestCombined, to vessels.
+func Vessels[T interface{ Vessel() ais.Vessel }](msgs []T) []ais.Vessel {
+	res := make([]ais.Vessel, len(msgs))
+	for i, msg := range msgs {
+		res[i] = msg.Vessel()
+	}
+	return res
+}
+
+// TrackPoint is a single position of a vessel.
+type TrackPoint struct {
+	Msgtime          time.Time
+	Latitude         float64
+	Longitude        float64
+	SpeedOverGround  *float64
+	CourseOverGround *float64
+	TrueHeading      *int
+}
+
+// Track is the positions of a single vessel, ordered by time.
+type Track struct {
+	Mmsi int
+	// Name is the name of the vessel, if it is known.
+	Name string
+	// ShipType is the ship type of the vessel, if it is known.
+	ShipType *int
+	Points   []TrackPoint
+}
+
+// Tracks collects the positions of vessels into tracks, one per MMSI. Names and ship types are taken from static data
+// and from messages of the combined types which carry them.
+//
+// A Tracks must be constructed with the NewTracks factory function. It is not safe for concurrent use.
+type Tracks struct {
+	tracks map[int]*Track
+}
+
+// NewTracks creates a new, empty Tracks.
+func NewTracks() *Tracks {
+	return &Tracks{tracks: make(map[int]*Track)}
+}
+
+func (t *Tracks) track(mmsi int) *Track {
+	track, ok := t.tracks[mmsi]
+	if !ok {
+		track = &Track{Mmsi: mmsi}
+		t.tracks[mmsi] = track
+	}
+	return track
+}
+
+// Add adds the position of the vessel to its track, if the position is known, and updates the track's name and ship
+// type, if they are known.
+func (t *Tracks) Add(v ais.Vessel) {
+	track := t.track(v.Mmsi)
+	if v.Name != "" {
+		track.Name = v.Name
+	}
+	if v.ShipType != nil {
+		shipType := *v.ShipType
+		track.ShipType = &shipType
+	}
+	if v.Latitude == nil || v.Longitude == nil {
+		return
+	}
+	track.Points = append(track.Points, TrackPoint{
+		Msgtime:          v.Msgtime,
+		Latitude:         *v.Latitude,
+		Longitude:        *v.Longitude,
+		SpeedOverGround:  v.SpeedOverGround,
+		CourseOverGround: v.CourseOverGround,
+		TrueHeading:      v.TrueHeading,
+	})
+}
+
+// AddPosition adds the position report to the track of the vessel.
+func (t *Tracks) AddPosition(p ais.Position) {
+	t.Add(p.Vessel())
+}
+
+// AddStaticdata updates the name and ship type of the track of the vessel.
+func (t *Tracks) AddStaticdata(s ais.Staticdata) {
+	t.Add(s.Vessel())
+}
+
+// Update adds a message from an AIS stream. Aton messages are ignored.
+func (t *Tracks) Update(msg ais.AisMultiple) {
+	switch msg.Type {
+	case responsetype.Position:
+		t.AddPosition(msg.Position)
+	case responsetype.Staticdata:
+		t.AddStaticdata(msg.Staticdata)
+	}
+}
+
+// CollectTracks collects messages which describe vessels, such as position reports or the results of a combined
+// stream, into tracks, and returns them as List does.
+func CollectTracks[T interface{ Vessel() ais.Vessel }](msgs []T) []Track {
+	t := NewTracks()
+	for _, msg := range msgs {
+		t.Add(msg.Vessel())
+	}
+	return t.List()
+}
+
+// List returns the tracks ordered by MMSI, with their points ordered by time. Vessels without any known position are
+// included with no points.
+func (t *Tracks) List() []Track {
+	res := make([]Track, 0, len(t.tracks))
+	for _, track := range t.tracks {
+		sort.SliceStable(track.Points, func(i, j int) bool {
+			return track.Points[i].Msgtime.Before(track.Points[j].Msgtime)
+		})
+		res = append(res, *track)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Mmsi < res[j].Mmsi
+	})
+	return res
+}
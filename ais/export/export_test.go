@@ -0,0 +1,92 @@
+package export_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/export"
+)
+
+// decodeFixture decodes every line of a fixture into a T.
+func decodeFixture[T any](t *testing.T, filename string) []T {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var res []T
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var obj T
+		if err := json.Unmarshal(scanner.Bytes(), &obj); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, obj)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// track returns position reports of a vessel sailing north, one minute apart, in reverse order of time.
+func track(mmsi int, n int) []ais.Position {
+	start := time.Date(2023, 2, 20, 12, 0, 0, 0, time.UTC)
+	var res []ais.Position
+	for i := n - 1; i >= 0; i-- {
+		lat, lon, sog, cog, heading := 60+float64(i)/60, 5.0, 10.0, 0.0, 1
+		res = append(res, ais.Position{
+			Mmsi:             mmsi,
+			Msgtime:          start.Add(time.Duration(i) * time.Minute),
+			Latitude:         &lat,
+			Longitude:        &lon,
+			SpeedOverGround:  &sog,
+			CourseOverGround: &cog,
+			TrueHeading:      &heading,
+		})
+	}
+	return res
+}
+
+func TestTracks(t *testing.T) {
+	tracks := export.NewTracks()
+	for _, p := range track(257000000, 5) {
+		tracks.AddPosition(p)
+	}
+	shipType := 60
+	tracks.AddStaticdata(ais.Staticdata{Mmsi: 257000000, Name: "FERRY", ShipType: &shipType})
+	tracks.AddStaticdata(ais.Staticdata{Mmsi: 100000000, Name: "NO POSITION"})
+
+	msgs := decodeFixture[ais.AisMultiple](t, "../testdata/get_ais.txt")
+	for _, msg := range msgs {
+		tracks.Update(msg)
+	}
+
+	list := tracks.List()
+	if len(list) < 2 || list[0].Mmsi != 100000000 || len(list[0].Points) != 0 {
+		t.Fatalf("expected tracks ordered by MMSI, including vessels without position, got %d tracks", len(list))
+	}
+
+	var ferry export.Track
+	for i, track := range list {
+		if i > 0 && list[i-1].Mmsi >= track.Mmsi {
+			t.Fatalf("expected tracks ordered by MMSI, got %d before %d", list[i-1].Mmsi, track.Mmsi)
+		}
+		for j := 1; j < len(track.Points); j++ {
+			if track.Points[j].Msgtime.Before(track.Points[j-1].Msgtime) {
+				t.Fatalf("expected points of %d ordered by time", track.Mmsi)
+			}
+		}
+		if track.Mmsi == 257000000 {
+			ferry = track
+		}
+	}
+	if ferry.Name != "FERRY" || ferry.ShipType == nil || *ferry.ShipType != 60 || len(ferry.Points) != 5 {
+		t.Errorf("unexpected track %+v", ferry)
+	}
+}
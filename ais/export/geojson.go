@@ -0,0 +1,278 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/countrycode"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// The kinds of feature, held by the "kind" property of every exported feature.
+const (
+	KindVessel = "vessel"
+	KindAton   = "aton"
+	KindTrack  = "track"
+)
+
+// CategoryColors are the colours of the ship type categories. They are held by the "marker-color" property of vessel
+// features and the "stroke" property of track features, as defined by the simplestyle specification, which is
+// understood by many web maps.
+var CategoryColors = map[shiptype.Category]string{
+	shiptype.CategoryUnknown:        "#9e9e9e",
+	shiptype.CategoryWingInGround:   "#795548",
+	shiptype.CategoryFishing:        "#ff9800",
+	shiptype.CategoryTugTow:         "#00bcd4",
+	shiptype.CategorySpecialCraft:   "#607d8b",
+	shiptype.CategoryHighSpeedCraft: "#ffeb3b",
+	shiptype.CategoryPassenger:      "#2196f3",
+	shiptype.CategoryCargo:          "#4caf50",
+	shiptype.CategoryTanker:         "#f44336",
+	shiptype.CategoryOther:          "#9c27b0",
+}
+
+// AtonColor is the colour of aid to navigation features.
+var AtonColor = "#000000"
+
+// VesselFeature returns a Point feature for the vessel, or nil if its position is unknown. The feature's id is the
+// vessel's MMSI, and its properties are the known fields of the vessel, along with properties for styling:
+//
+//   - "kind" is KindVessel
+//   - "category" is the name of the vessel's ship type category
+//   - "heading" is the true heading of the vessel, or its course over ground if the heading is not available
+//   - "flag" and "country" are the ISO 3166-1 alpha-2 code and name of the vessel's flag state, derived from its MMSI
+//   - "marker-color" is the colour of the vessel's ship type category
+func VesselFeature(v ais.Vessel) *geojson.Feature {
+	if v.Latitude == nil || v.Longitude == nil {
+		return nil
+	}
+
+	f := geojson.NewPointFeature([]float64{*v.Longitude, *v.Latitude})
+	f.ID = v.Mmsi
+	f.SetProperty("kind", KindVessel)
+	f.SetProperty("mmsi", v.Mmsi)
+	f.SetProperty("msgtime", v.Msgtime)
+	setString(f, "name", v.Name)
+	setPtr(f, "speedOverGround", v.SpeedOverGround)
+	setPtr(f, "courseOverGround", v.CourseOverGround)
+	setPtr(f, "rateOfTurn", v.RateOfTurn)
+	setPtr(f, "trueHeading", v.TrueHeading)
+	setPtr(f, "navigationalStatus", v.NavigationalStatus)
+	setPtr(f, "shipType", v.ShipType)
+	setPtr(f, "imoNumber", v.ImoNumber)
+	setString(f, "callSign", v.CallSign)
+	setString(f, "destination", v.Destination)
+	setString(f, "eta", v.Eta)
+	setPtr(f, "shipLength", v.ShipLength)
+	setPtr(f, "shipWidth", v.ShipWidth)
+
+	if h, ok := heading(v.TrueHeading, v.CourseOverGround); ok {
+		f.SetProperty("heading", h)
+	}
+	category := shipCategory(v.ShipType)
+	f.SetProperty("category", category.String())
+	f.SetProperty("marker-color", CategoryColors[category])
+	setFlag(f, v.Mmsi)
+	return f
+}
+
+// AtonFeature returns a Point feature for the aid to navigation, or nil if its position is unknown. The feature's id
+// is the MMSI of the aid to navigation, and its properties are its known fields, along with "kind", which is KindAton,
+// and "flag", "country" and "marker-color" as for vessels.
+func AtonFeature(a ais.Aton) *geojson.Feature {
+	if a.Latitude == nil || a.Longitude == nil {
+		return nil
+	}
+
+	f := geojson.NewPointFeature([]float64{*a.Longitude, *a.Latitude})
+	f.ID = a.Mmsi
+	f.SetProperty("kind", KindAton)
+	f.SetProperty("mmsi", a.Mmsi)
+	f.SetProperty("msgtime", a.Msgtime)
+	setString(f, "name", a.Name)
+	f.SetProperty("typeOfAidsToNavigation", a.TypeOfAidsToNavigation)
+	f.SetProperty("marker-color", AtonColor)
+	setFlag(f, a.Mmsi)
+	return f
+}
+
+// TrackFeature returns a LineString feature for the track, or nil if it has fewer than two points. The properties of
+// the feature are "kind", which is KindTrack, "mmsi", "name" and "shipType" if known, "start" and "end", which are the
+// times of the first and last points, "coordTimes", which are the times of every point, and "category", "flag",
+// "country" and "stroke" for styling as for vessels.
+func TrackFeature(t Track) *geojson.Feature {
+	if len(t.Points) < 2 {
+		return nil
+	}
+
+	coordinates := make([][]float64, len(t.Points))
+	times := make([]string, len(t.Points))
+	for i, p := range t.Points {
+		coordinates[i] = []float64{p.Longitude, p.Latitude}
+		times[i] = p.Msgtime.Format(timeFormat)
+	}
+
+	f := geojson.NewLineStringFeature(coordinates)
+	f.SetProperty("kind", KindTrack)
+	f.SetProperty("mmsi", t.Mmsi)
+	setString(f, "name", t.Name)
+	setPtr(f, "shipType", t.ShipType)
+	f.SetProperty("start", t.Points[0].Msgtime)
+	f.SetProperty("end", t.Points[len(t.Points)-1].Msgtime)
+	f.SetProperty("coordTimes", times)
+
+	category := shipCategory(t.ShipType)
+	f.SetProperty("category", category.String())
+	f.SetProperty("stroke", CategoryColors[category])
+	setFlag(f, t.Mmsi)
+	return f
+}
+
+// FeatureCollection returns a FeatureCollection of the vessels, aids to navigation and tracks, in that order. Those
+// whose position is unknown, and tracks with fewer than two points, are left out.
+func FeatureCollection(vessels []ais.Vessel, atons []ais.Aton, tracks []Track) *geojson.FeatureCollection {
+	fc := geojson.NewFeatureCollection()
+	for _, v := range vessels {
+		if f := VesselFeature(v); f != nil {
+			fc.AddFeature(f)
+		}
+	}
+	for _, a := range atons {
+		if f := AtonFeature(a); f != nil {
+			fc.AddFeature(f)
+		}
+	}
+	for _, t := range tracks {
+		if f := TrackFeature(t); f != nil {
+			fc.AddFeature(f)
+		}
+	}
+	return fc
+}
+
+// SeqWriter writes GeoJSON text sequences as defined by RFC 8142, in which every feature is a separate record. Unlike
+// a FeatureCollection, a sequence can be consumed while it is being written, which suits live maps.
+//
+// If the underlying writer is an http.ResponseWriter, or otherwise implements http.Flusher, it is flushed after every
+// record.
+type SeqWriter struct {
+	w io.Writer
+}
+
+// NewSeqWriter creates a SeqWriter which writes to w.
+func NewSeqWriter(w io.Writer) *SeqWriter {
+	return &SeqWriter{w: w}
+}
+
+// Write writes a feature as a single record.
+func (s *SeqWriter) Write(f *geojson.Feature) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	// A record is the record separator, the JSON text and a line feed
+	record := make([]byte, 0, len(data)+2)
+	record = append(record, 0x1e)
+	record = append(record, data...)
+	record = append(record, '\n')
+	if _, err := s.w.Write(record); err != nil {
+		return err
+	}
+
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// WriteVessel writes the vessel as a Point feature, or nothing if its position is unknown.
+func (s *SeqWriter) WriteVessel(v ais.Vessel) error {
+	if f := VesselFeature(v); f != nil {
+		return s.Write(f)
+	}
+	return nil
+}
+
+// WriteAton writes the aid to navigation as a Point feature, or nothing if its position is unknown.
+func (s *SeqWriter) WriteAton(a ais.Aton) error {
+	if f := AtonFeature(a); f != nil {
+		return s.Write(f)
+	}
+	return nil
+}
+
+// WriteSeq writes every message received from in as a GeoJSON text sequence, until in is closed or the context is
+// cancelled. Aton messages of an AIS stream are written as aids to navigation, and every other message as a vessel.
+// Messages without a position, such as static data, are left out.
+//
+// It returns the context's error if the context was cancelled, the first error writing to w, or nil.
+func WriteSeq[T interface{ Vessel() ais.Vessel }](ctx context.Context, w io.Writer, in <-chan T) error {
+	s := NewSeqWriter(w)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-in:
+			if !ok {
+				return nil
+			}
+			var err error
+			if a, isAis := any(msg).(ais.AisMultiple); isAis && a.Type == responsetype.Aton {
+				err = s.WriteAton(a.Aton)
+			} else {
+				err = s.WriteVessel(msg.Vessel())
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// timeFormat is the format of times in exported data.
+const timeFormat = "2006-01-02T15:04:05.999999999Z07:00"
+
+// heading returns the true heading if available, or else the course over ground if available. In AIS, a heading of
+// 511 and a course of 360 mean not available.
+func heading(trueHeading *int, courseOverGround *float64) (float64, bool) {
+	if trueHeading != nil && *trueHeading >= 0 && *trueHeading < 360 {
+		return float64(*trueHeading), true
+	}
+	if courseOverGround != nil && *courseOverGround >= 0 && *courseOverGround < 360 {
+		return *courseOverGround, true
+	}
+	return 0, false
+}
+
+func shipCategory(shipType *int) shiptype.Category {
+	if shipType == nil {
+		return shiptype.CategoryUnknown
+	}
+	return shiptype.ShipType(*shipType).Category()
+}
+
+// setFlag sets the "flag" and "country" properties from the MMSI, if it identifies a country.
+func setFlag(f *geojson.Feature, mmsi int) {
+	if c, ok := countrycode.FromMMSI(mmsi); ok {
+		f.SetProperty("flag", string(c))
+		f.SetProperty("country", c.ToCountryName())
+	}
+}
+
+func setString(f *geojson.Feature, key, value string) {
+	if value != "" {
+		f.SetProperty(key, value)
+	}
+}
+
+func setPtr[T any](f *geojson.Feature, key string, value *T) {
+	if value != nil {
+		f.SetProperty(key, *value)
+	}
+}
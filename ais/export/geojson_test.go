@@ -0,0 +1,148 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/export"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestFeatureCollection(t *testing.T) {
+	latest := decodeFixture[ais.CombinedFullJson](t, "../testdata/combined_full_json.txt")
+	vessels := export.Vessels(latest)
+
+	var atons []ais.Aton
+	for _, msg := range decodeFixture[ais.AisMultiple](t, "../testdata/get_ais.txt") {
+		if msg.Type == responsetype.Aton {
+			atons = append(atons, msg.Aton)
+		}
+	}
+
+	tracks := export.NewTracks()
+	for _, p := range track(257000000, 3) {
+		tracks.AddPosition(p)
+	}
+
+	fc := export.FeatureCollection(vessels, atons, tracks.List())
+	data, err := json.Marshal(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fc, err = geojson.UnmarshalFeatureCollection(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+	for _, f := range fc.Features {
+		kind, _ := f.PropertyString("kind")
+		counts[kind]++
+
+		switch kind {
+		case export.KindVessel:
+			if !f.Geometry.IsPoint() {
+				t.Fatalf("expected vessel to be a point, got %s", f.Geometry.Type)
+			}
+			if _, err := f.PropertyString("category"); err != nil {
+				t.Fatalf("expected category of vessel %v", f.ID)
+			}
+			if _, err := f.PropertyString("marker-color"); err != nil {
+				t.Fatalf("expected colour of vessel %v", f.ID)
+			}
+		case export.KindAton:
+			if !f.Geometry.IsPoint() {
+				t.Fatalf("expected aton to be a point, got %s", f.Geometry.Type)
+			}
+		case export.KindTrack:
+			if !f.Geometry.IsLineString() || len(f.Geometry.LineString) != 3 {
+				t.Fatalf("expected track to be a line string of 3 points, got %+v", f.Geometry)
+			}
+			if flag, _ := f.PropertyString("flag"); flag != "NO" {
+				t.Errorf("expected Norwegian flag, got %q", flag)
+			}
+		default:
+			t.Fatalf("unexpected kind %q", kind)
+		}
+	}
+
+	if counts[export.KindVessel] == 0 || counts[export.KindVessel] > len(vessels) {
+		t.Errorf("expected up to %d vessels, got %d", len(vessels), counts[export.KindVessel])
+	}
+	if counts[export.KindAton] == 0 || counts[export.KindAton] > len(atons) {
+		t.Errorf("expected up to %d atons, got %d", len(atons), counts[export.KindAton])
+	}
+	if counts[export.KindTrack] != 1 {
+		t.Errorf("expected 1 track, got %d", counts[export.KindTrack])
+	}
+}
+
+func TestVesselFeature(t *testing.T) {
+	p := track(257000000, 1)[0]
+	heading := 511
+	p.TrueHeading = &heading
+	shipType := 80
+
+	v := ais.NewVessel(p, ais.Staticdata{Mmsi: p.Mmsi, Name: "TANKER", ShipType: &shipType})
+	f := export.VesselFeature(v)
+	if f.ID != 257000000 || f.Geometry.Point[0] != *p.Longitude || f.Geometry.Point[1] != *p.Latitude {
+		t.Errorf("unexpected feature %+v", f)
+	}
+	if category, _ := f.PropertyString("category"); category != "Tanker" {
+		t.Errorf("expected category Tanker, got %q", category)
+	}
+	// The course over ground is used when the heading is not available
+	if h, _ := f.PropertyFloat64("heading"); h != *p.CourseOverGround {
+		t.Errorf("expected heading %f, got %f", *p.CourseOverGround, h)
+	}
+
+	v.Latitude = nil
+	if f := export.VesselFeature(v); f != nil {
+		t.Errorf("expected no feature for vessel without position, got %+v", f)
+	}
+}
+
+func TestWriteSeq(t *testing.T) {
+	msgs := decodeFixture[ais.AisMultiple](t, "../testdata/get_ais.txt")
+	in := make(chan ais.AisMultiple)
+	go func() {
+		defer close(in)
+		for _, msg := range msgs {
+			in <- msg
+		}
+	}()
+
+	var buf bytes.Buffer
+	if err := export.WriteSeq(context.Background(), &buf, in); err != nil {
+		t.Fatal(err)
+	}
+
+	positions := 0
+	for _, msg := range msgs {
+		if msg.Type == responsetype.Position && msg.Position.Latitude != nil && msg.Position.Longitude != nil ||
+			msg.Type == responsetype.Aton && msg.Aton.Latitude != nil && msg.Aton.Longitude != nil {
+			positions++
+		}
+	}
+
+	records := bytes.Split(buf.Bytes(), []byte{0x1e})
+	if len(records[0]) != 0 {
+		t.Fatalf("expected sequence to start with a record separator, got %q", records[0])
+	}
+	records = records[1:]
+	if len(records) != positions {
+		t.Fatalf("expected %d records, got %d", positions, len(records))
+	}
+	for _, record := range records {
+		if !bytes.HasSuffix(record, []byte("\n")) {
+			t.Fatalf("expected record to end with a line feed, got %q", record)
+		}
+		if _, err := geojson.UnmarshalFeature(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
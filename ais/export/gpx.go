@@ -0,0 +1,125 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/navmath"
+)
+
+// The elements of GPX documents. Speed and course are held by Garmin's TrackPointExtension, as GPX 1.1 has no
+// elements for them.
+type gpxDocument struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	Xmlns     string        `xml:"xmlns,attr"`
+	XmlnsTpx  string        `xml:"xmlns:gpxtpx,attr"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Name      string        `xml:"metadata>name,omitempty"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+	Tracks    []gpxTrack    `xml:"trk"`
+}
+
+type gpxWaypoint struct {
+	Lat  string `xml:"lat,attr"`
+	Lon  string `xml:"lon,attr"`
+	Time string `xml:"time,omitempty"`
+	Name string `xml:"name,omitempty"`
+	Desc string `xml:"desc,omitempty"`
+	Type string `xml:"type,omitempty"`
+}
+
+type gpxTrack struct {
+	Name   string     `xml:"name,omitempty"`
+	Desc   string     `xml:"desc,omitempty"`
+	Type   string     `xml:"type,omitempty"`
+	Points []gpxPoint `xml:"trkseg>trkpt"`
+}
+
+type gpxPoint struct {
+	Lat        string         `xml:"lat,attr"`
+	Lon        string         `xml:"lon,attr"`
+	Time       string         `xml:"time"`
+	Extensions *gpxExtensions `xml:"extensions>gpxtpx:TrackPointExtension"`
+}
+
+type gpxExtensions struct {
+	Speed  string `xml:"gpxtpx:speed,omitempty"`
+	Course string `xml:"gpxtpx:course,omitempty"`
+}
+
+// WriteGPX writes a GPX 1.1 document with the given name, holding a snapshot of the vessels as waypoints and the
+// history of the tracks as tracks of a single segment.
+//
+// Vessels and tracks are named by the name of the vessel if known, or else by its MMSI, and their type is the name of
+// their ship type category. Track points hold the speed over ground, in metres per second, and the course over ground,
+// in degrees, in Garmin's TrackPointExtension, which most GPX tools understand. Vessels without a known position and
+// tracks without points are left out.
+func WriteGPX(w io.Writer, name string, vessels []ais.Vessel, tracks []Track) error {
+	doc := gpxDocument{
+		Xmlns:    "http://www.topografix.com/GPX/1/1",
+		XmlnsTpx: "http://www.garmin.com/xmlschemas/TrackPointExtension/v2",
+		Version:  "1.1",
+		Creator:  "go-barentswatch-ais",
+		Name:     name,
+	}
+
+	for _, v := range vessels {
+		if v.Latitude == nil || v.Longitude == nil {
+			continue
+		}
+		doc.Waypoints = append(doc.Waypoints, gpxWaypoint{
+			Lat:  formatFloat(*v.Latitude),
+			Lon:  formatFloat(*v.Longitude),
+			Time: v.Msgtime.Format(timeFormat),
+			Name: vesselName(v.Name, v.Mmsi),
+			Desc: fmt.Sprintf("MMSI %d", v.Mmsi),
+			Type: shipCategory(v.ShipType).String(),
+		})
+	}
+
+	for _, t := range tracks {
+		if len(t.Points) == 0 {
+			continue
+		}
+		track := gpxTrack{
+			Name:   vesselName(t.Name, t.Mmsi),
+			Desc:   fmt.Sprintf("MMSI %d", t.Mmsi),
+			Type:   shipCategory(t.ShipType).String(),
+			Points: make([]gpxPoint, len(t.Points)),
+		}
+		for i, p := range t.Points {
+			point := gpxPoint{
+				Lat:  formatFloat(p.Latitude),
+				Lon:  formatFloat(p.Longitude),
+				Time: p.Msgtime.Format(timeFormat),
+			}
+			var ext gpxExtensions
+			if p.SpeedOverGround != nil {
+				ext.Speed = formatFloat(navmath.KnotsToMetresPerSecond(*p.SpeedOverGround))
+			}
+			// In AIS, a course of 360 means not available
+			if p.CourseOverGround != nil && *p.CourseOverGround >= 0 && *p.CourseOverGround < 360 {
+				ext.Course = formatFloat(*p.CourseOverGround)
+			}
+			if ext != (gpxExtensions{}) {
+				point.Extensions = &ext
+			}
+			track.Points[i] = point
+		}
+		doc.Tracks = append(doc.Tracks, track)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,70 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/export"
+)
+
+func TestWriteGPX(t *testing.T) {
+	positions := track(257000000, 3)
+	positions[0].SpeedOverGround = nil
+	tracks := export.CollectTracks(positions)
+
+	shipType := 60
+	v := ais.NewVessel(positions[0], ais.Staticdata{Mmsi: 257000000, Name: "FERRY", ShipType: &shipType})
+
+	var buf bytes.Buffer
+	if err := export.WriteGPX(&buf, "Ferry", []ais.Vessel{v}, tracks); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Version   string `xml:"version,attr"`
+		Waypoints []struct {
+			Name string `xml:"name"`
+			Type string `xml:"type"`
+		} `xml:"wpt"`
+		Tracks []struct {
+			Name   string `xml:"name"`
+			Points []struct {
+				Lat        float64 `xml:"lat,attr"`
+				Time       string  `xml:"time"`
+				Extensions *struct {
+					Speed  string `xml:"TrackPointExtension>speed"`
+					Course string `xml:"TrackPointExtension>course"`
+				} `xml:"extensions"`
+			} `xml:"trkseg>trkpt"`
+		} `xml:"trk"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Version != "1.1" || len(doc.Waypoints) != 1 || len(doc.Tracks) != 1 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	if doc.Waypoints[0].Name != "FERRY" || doc.Waypoints[0].Type != "Passenger" {
+		t.Errorf("unexpected waypoint %+v", doc.Waypoints[0])
+	}
+
+	tr := doc.Tracks[0]
+	if tr.Name != "257000000" || len(tr.Points) != 3 {
+		t.Fatalf("expected track named by MMSI with 3 points, got %+v", tr)
+	}
+	if tr.Points[0].Lat != 60 || tr.Points[0].Time != "2023-02-20T12:00:00Z" {
+		t.Errorf("unexpected first point %+v", tr.Points[0])
+	}
+	// 10 knots is 5.144 metres per second
+	ext := tr.Points[0].Extensions
+	if ext == nil || ext.Speed != "5.144444444444445" || ext.Course != "0" {
+		t.Errorf("unexpected extensions of first point %+v", ext)
+	}
+	// The last point, which was the first position, has no speed
+	if ext := tr.Points[2].Extensions; ext == nil || ext.Speed != "" || ext.Course != "0" {
+		t.Errorf("unexpected extensions of last point %+v", ext)
+	}
+}
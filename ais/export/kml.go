@@ -0,0 +1,174 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+)
+
+// KMLIcon is the icon of vessels in KML documents. It should point north, as it is rotated to the heading of the
+// vessel.
+var KMLIcon = "https://earth.google.com/images/kml-icons/track-directional/track-0.png"
+
+// The elements of KML documents. Elements of the gx extension are named with their prefix, which encoding/xml writes
+// as is.
+type kmlDocument struct {
+	XMLName xml.Name    `xml:"kml"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	XmlnsGx string      `xml:"xmlns:gx,attr"`
+	Name    string      `xml:"Document>name,omitempty"`
+	Folders []kmlFolder `xml:"Document>Folder"`
+}
+
+type kmlFolder struct {
+	Name       string         `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name         string           `xml:"name,omitempty"`
+	Description  string           `xml:"description,omitempty"`
+	TimeStamp    *kmlTimeStamp    `xml:"TimeStamp"`
+	Style        *kmlStyle        `xml:"Style"`
+	Point        *kmlPoint        `xml:"Point"`
+	Track        *kmlTrack        `xml:"gx:Track"`
+	ExtendedData *kmlExtendedData `xml:"ExtendedData"`
+}
+
+type kmlTimeStamp struct {
+	When string `xml:"when"`
+}
+
+type kmlStyle struct {
+	Heading string `xml:"IconStyle>heading,omitempty"`
+	Icon    string `xml:"IconStyle>Icon>href"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlTrack struct {
+	When   []string `xml:"when"`
+	Coord  []string `xml:"gx:coord"`
+	Angles []string `xml:"gx:angles"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// WriteKML writes a KML document with the given name, holding a snapshot of the vessels and the history of the tracks.
+//
+// Every vessel with a known position is a placemark, time-stamped with the time of its latest message and with an
+// icon rotated to its heading. Every track with at least one point is a placemark with a gx:Track, which Google Earth
+// plays back with its time slider. Tracks are named by the name of the vessel if known, or else by its MMSI.
+func WriteKML(w io.Writer, name string, vessels []ais.Vessel, tracks []Track) error {
+	doc := kmlDocument{
+		Xmlns:   "http://www.opengis.net/kml/2.2",
+		XmlnsGx: "http://www.google.com/kml/ext/2.2",
+		Name:    name,
+	}
+
+	if len(vessels) > 0 {
+		folder := kmlFolder{Name: "Vessels"}
+		for _, v := range vessels {
+			if v.Latitude == nil || v.Longitude == nil {
+				continue
+			}
+			p := kmlPlacemark{
+				Name:         vesselName(v.Name, v.Mmsi),
+				TimeStamp:    &kmlTimeStamp{When: v.Msgtime.Format(timeFormat)},
+				Style:        &kmlStyle{Icon: KMLIcon},
+				Point:        &kmlPoint{Coordinates: formatFloat(*v.Longitude) + "," + formatFloat(*v.Latitude)},
+				ExtendedData: vesselData(v),
+			}
+			if h, ok := heading(v.TrueHeading, v.CourseOverGround); ok {
+				p.Style.Heading = formatFloat(h)
+			}
+			folder.Placemarks = append(folder.Placemarks, p)
+		}
+		doc.Folders = append(doc.Folders, folder)
+	}
+
+	if len(tracks) > 0 {
+		folder := kmlFolder{Name: "Tracks"}
+		for _, t := range tracks {
+			if len(t.Points) == 0 {
+				continue
+			}
+			track := &kmlTrack{}
+			var last float64
+			for _, p := range t.Points {
+				// Points without heading keep the heading of the point before
+				if h, ok := heading(p.TrueHeading, p.CourseOverGround); ok {
+					last = h
+				}
+				track.When = append(track.When, p.Msgtime.Format(timeFormat))
+				track.Coord = append(track.Coord, formatFloat(p.Longitude)+" "+formatFloat(p.Latitude)+" 0")
+				track.Angles = append(track.Angles, formatFloat(last)+" 0 0")
+			}
+			folder.Placemarks = append(folder.Placemarks, kmlPlacemark{
+				Name:        vesselName(t.Name, t.Mmsi),
+				Description: fmt.Sprintf("MMSI %d", t.Mmsi),
+				Style:       &kmlStyle{Icon: KMLIcon},
+				Track:       track,
+			})
+		}
+		doc.Folders = append(doc.Folders, folder)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// vesselData returns the known fields of the vessel as KML extended data.
+func vesselData(v ais.Vessel) *kmlExtendedData {
+	data := []kmlData{{Name: "mmsi", Value: strconv.Itoa(v.Mmsi)}}
+	add := func(name, value string) {
+		if value != "" {
+			data = append(data, kmlData{Name: name, Value: value})
+		}
+	}
+	add("callSign", v.CallSign)
+	add("destination", v.Destination)
+	if v.SpeedOverGround != nil {
+		add("speedOverGround", formatFloat(*v.SpeedOverGround))
+	}
+	if v.CourseOverGround != nil {
+		add("courseOverGround", formatFloat(*v.CourseOverGround))
+	}
+	if v.ShipType != nil {
+		add("shipType", strconv.Itoa(*v.ShipType))
+		add("category", shipCategory(v.ShipType).String())
+	}
+	return &kmlExtendedData{Data: data}
+}
+
+// vesselName returns the name of the vessel, or its MMSI if the name is unknown.
+func vesselName(name string, mmsi int) string {
+	if name != "" {
+		return name
+	}
+	return strconv.Itoa(mmsi)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,72 @@
+package export_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/export"
+)
+
+func TestWriteKML(t *testing.T) {
+	latest := decodeFixture[ais.CombinedFullJson](t, "../testdata/combined_full_json.txt")
+	vessels := export.Vessels(latest)
+	tracks := export.CollectTracks(track(257000000, 3))
+	tracks[0].Name = "FERRY"
+
+	var buf bytes.Buffer
+	if err := export.WriteKML(&buf, "Vessels", vessels, tracks); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Name    string `xml:"Document>name"`
+		Folders []struct {
+			Name       string `xml:"name"`
+			Placemarks []struct {
+				Name    string `xml:"name"`
+				When    string `xml:"TimeStamp>when"`
+				Heading string `xml:"Style>IconStyle>heading"`
+				Point   string `xml:"Point>coordinates"`
+				Track   struct {
+					When   []string `xml:"when"`
+					Coord  []string `xml:"coord"`
+					Angles []string `xml:"angles"`
+				} `xml:"Track"`
+			} `xml:"Placemark"`
+		} `xml:"Document>Folder"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Name != "Vessels" || len(doc.Folders) != 2 {
+		t.Fatalf("expected document with 2 folders, got %+v", doc)
+	}
+	if !strings.Contains(buf.String(), `xmlns:gx="http://www.google.com/kml/ext/2.2"`) {
+		t.Error("expected gx namespace to be declared")
+	}
+
+	vesselFolder := doc.Folders[0]
+	if len(vesselFolder.Placemarks) == 0 || len(vesselFolder.Placemarks) > len(vessels) {
+		t.Fatalf("expected up to %d vessels, got %d", len(vessels), len(vesselFolder.Placemarks))
+	}
+	for _, p := range vesselFolder.Placemarks {
+		if p.When == "" || p.Point == "" {
+			t.Fatalf("expected time-stamped point, got %+v", p)
+		}
+	}
+
+	trackFolder := doc.Folders[1]
+	if len(trackFolder.Placemarks) != 1 {
+		t.Fatalf("expected 1 track, got %d", len(trackFolder.Placemarks))
+	}
+	tr := trackFolder.Placemarks[0]
+	if tr.Name != "FERRY" || len(tr.Track.When) != 3 || len(tr.Track.Coord) != 3 || len(tr.Track.Angles) != 3 {
+		t.Fatalf("unexpected track %+v", tr)
+	}
+	if tr.Track.When[0] != "2023-02-20T12:00:00Z" || tr.Track.Coord[0] != "5 60 0" || tr.Track.Angles[0] != "1 0 0" {
+		t.Errorf("unexpected first point of track %+v", tr.Track)
+	}
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+// Compression is the compression codec of Parquet files.
+type Compression int
+
+// The supported compression codecs. Zstd compresses best, while Snappy is fastest.
+const (
+	CompressionNone Compression = iota
+	CompressionSnappy
+	CompressionGzip
+	CompressionZstd
+)
+
+func (c Compression) codec() compress.Codec {
+	switch c {
+	case CompressionSnappy:
+		return &parquet.Snappy
+	case CompressionGzip:
+		return &parquet.Gzip
+	case CompressionZstd:
+		return &parquet.Zstd
+	}
+	return &parquet.Uncompressed
+}
+
+// DefaultRowGroupSize is the number of rows of a row group of a Parquet file, unless otherwise specified.
+const DefaultRowGroupSize = 128 * 1024
+
+// batchSize is the number of rows buffered by a ParquetWriter before they are handed to the Parquet encoder.
+const batchSize = 1024
+
+// ParquetWriter writes rows as a Parquet file with the schema of Row. Rows are batched into row groups of a fixed
+// number of rows, so that the file can be read without holding it all in memory. Nothing can be read until the
+// ParquetWriter is closed, which writes the file's footer.
+//
+// A ParquetWriter must be constructed with the NewParquetWriter factory function. It is not safe for concurrent use.
+type ParquetWriter struct {
+	w     *parquet.GenericWriter[Row]
+	batch []Row
+}
+
+// NewParquetWriter creates a ParquetWriter which writes to w, with row groups of rowGroupSize rows, or
+// DefaultRowGroupSize if rowGroupSize is not positive, and pages compressed with the given codec.
+func NewParquetWriter(w io.Writer, rowGroupSize int, compression Compression) *ParquetWriter {
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+	return &ParquetWriter{
+		w: parquet.NewGenericWriter[Row](w,
+			parquet.MaxRowsPerRowGroup(int64(rowGroupSize)),
+			parquet.Compression(compression.codec()),
+			parquet.CreatedBy("go-barentswatch-ais", "", ""),
+		),
+		batch: make([]Row, 0, min(rowGroupSize, batchSize)),
+	}
+}
+
+// Write writes the row.
+func (p *ParquetWriter) Write(r Row) error {
+	p.batch = append(p.batch, r)
+	if len(p.batch) == cap(p.batch) {
+		return p.writeBatch()
+	}
+	return nil
+}
+
+// Flush ends the current row group, and writes it to the underlying writer.
+func (p *ParquetWriter) Flush() error {
+	if err := p.writeBatch(); err != nil {
+		return err
+	}
+	return p.w.Flush()
+}
+
+// Close writes the buffered rows and the footer of the file. It does not close the underlying writer.
+func (p *ParquetWriter) Close() error {
+	if err := p.writeBatch(); err != nil {
+		return err
+	}
+	return p.w.Close()
+}
+
+func (p *ParquetWriter) writeBatch() error {
+	if len(p.batch) == 0 {
+		return nil
+	}
+	_, err := p.w.Write(p.batch)
+	clear(p.batch)
+	p.batch = p.batch[:0]
+	return err
+}
@@ -0,0 +1,286 @@
+package export
+
+import (
+	"context"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+)
+
+// Row is a message flattened into a stable schema for tabular formats, such as CSV and Parquet. Every message type
+// is flattened into the same columns, so that messages of different types can be written to the same table.
+//
+// Fields are nil when the message type has no such field, or when the field is null in the message, so that they are
+// written as nulls rather than zeros. Msgtime is in UTC, and is written to Parquet with microsecond precision.
+type Row struct {
+	// Type is the type of the message, such as "Position" for AIS messages or "FullJson" for combined messages.
+	Type                     string    `parquet:"type,dict"`
+	Mmsi                     int       `parquet:"mmsi"`
+	Msgtime                  time.Time `parquet:"msgtime,timestamp(microsecond)"`
+	MessageType              *int      `parquet:"messageType"`
+	Latitude                 *float64  `parquet:"latitude"`
+	Longitude                *float64  `parquet:"longitude"`
+	Altitude                 *int      `parquet:"altitude"`
+	SpeedOverGround          *float64  `parquet:"speedOverGround"`
+	CourseOverGround         *float64  `parquet:"courseOverGround"`
+	RateOfTurn               *float64  `parquet:"rateOfTurn"`
+	TrueHeading              *int      `parquet:"trueHeading"`
+	NavigationalStatus       *int      `parquet:"navigationalStatus"`
+	Name                     *string   `parquet:"name"`
+	ShipType                 *int      `parquet:"shipType"`
+	ImoNumber                *int      `parquet:"imoNumber"`
+	CallSign                 *string   `parquet:"callSign"`
+	Destination              *string   `parquet:"destination"`
+	Eta                      *string   `parquet:"eta"`
+	Draught                  *int      `parquet:"draught"`
+	ShipLength               *int      `parquet:"shipLength"`
+	ShipWidth                *int      `parquet:"shipWidth"`
+	DimensionA               *int      `parquet:"dimensionA"`
+	DimensionB               *int      `parquet:"dimensionB"`
+	DimensionC               *int      `parquet:"dimensionC"`
+	DimensionD               *int      `parquet:"dimensionD"`
+	PositionFixingDeviceType *int      `parquet:"positionFixingDeviceType"`
+	// AisClass is the class of the transponder, known as AisClass in Position and as ReportClass in the other types.
+	AisClass                     *string `parquet:"aisClass"`
+	TypeOfAidsToNavigation       *int    `parquet:"typeOfAidsToNavigation"`
+	TypeOfElectronicFixingDevice *int    `parquet:"typeOfElectronicFixingDevice"`
+}
+
+// Columns are the names of the columns of Row, in order. They are the names of the fields in the API's JSON.
+var Columns = []string{
+	"type", "mmsi", "msgtime", "messageType", "latitude", "longitude", "altitude", "speedOverGround",
+	"courseOverGround", "rateOfTurn", "trueHeading", "navigationalStatus", "name", "shipType", "imoNumber", "callSign",
+	"destination", "eta", "draught", "shipLength", "shipWidth", "dimensionA", "dimensionB", "dimensionC", "dimensionD",
+	"positionFixingDeviceType", "aisClass", "typeOfAidsToNavigation", "typeOfElectronicFixingDevice",
+}
+
+// Message is the union of the message types which can be flattened into rows.
+type Message interface {
+	ais.AisMultiple | ais.Position | ais.Aton | ais.Staticdata | ais.CombinedMultiple | ais.CombinedSimpleJson |
+		ais.CombinedFullJson | ais.CombinedSimpleGeojson | ais.CombinedFullGeojson
+}
+
+// NewRow flattens a message into a row. An AisMultiple or CombinedMultiple of an unknown type is flattened into the
+// zero Row, whose Type is empty.
+func NewRow[T Message](msg T) Row {
+	switch m := any(msg).(type) {
+	case ais.AisMultiple:
+		switch m.Type {
+		case responsetype.Position:
+			return NewRow(m.Position)
+		case responsetype.Aton:
+			return NewRow(m.Aton)
+		case responsetype.Staticdata:
+			return NewRow(m.Staticdata)
+		}
+		return Row{}
+	case ais.Position:
+		return Row{
+			Type:               string(responsetype.Position),
+			Mmsi:               m.Mmsi,
+			Msgtime:            m.Msgtime.UTC(),
+			MessageType:        &m.MessageType,
+			Latitude:           m.Latitude,
+			Longitude:          m.Longitude,
+			Altitude:           m.Altitude,
+			SpeedOverGround:    m.SpeedOverGround,
+			CourseOverGround:   m.CourseOverGround,
+			RateOfTurn:         m.RateOfTurn,
+			TrueHeading:        m.TrueHeading,
+			NavigationalStatus: &m.NavigationalStatus,
+			AisClass:           &m.AisClass,
+		}
+	case ais.Aton:
+		return Row{
+			Type:                         string(responsetype.Aton),
+			Mmsi:                         m.Mmsi,
+			Msgtime:                      m.Msgtime.UTC(),
+			MessageType:                  &m.MessageType,
+			Latitude:                     m.Latitude,
+			Longitude:                    m.Longitude,
+			Name:                         &m.Name,
+			DimensionA:                   m.DimensionA,
+			DimensionB:                   m.DimensionB,
+			DimensionC:                   m.DimensionC,
+			DimensionD:                   m.DimensionD,
+			TypeOfAidsToNavigation:       &m.TypeOfAidsToNavigation,
+			TypeOfElectronicFixingDevice: &m.TypeOfElectronicFixingDevice,
+		}
+	case ais.Staticdata:
+		return Row{
+			Type:                     string(responsetype.Staticdata),
+			Mmsi:                     m.Mmsi,
+			Msgtime:                  m.Msgtime.UTC(),
+			MessageType:              &m.MessageType,
+			Name:                     &m.Name,
+			ShipType:                 m.ShipType,
+			ImoNumber:                m.ImoNumber,
+			CallSign:                 &m.CallSign,
+			Destination:              &m.Destination,
+			Eta:                      &m.Eta,
+			Draught:                  m.Draught,
+			ShipLength:               m.ShipLength,
+			ShipWidth:                m.ShipWidth,
+			DimensionA:               m.DimensionA,
+			DimensionB:               m.DimensionB,
+			DimensionC:               m.DimensionC,
+			DimensionD:               m.DimensionD,
+			PositionFixingDeviceType: &m.PositionFixingDeviceType,
+			AisClass:                 &m.ReportClass,
+		}
+	case ais.CombinedMultiple:
+		switch m.Type {
+		case responsetype.SimpleJson:
+			return NewRow(m.CombinedSimpleJson)
+		case responsetype.FullJson:
+			return NewRow(m.CombinedFullJson)
+		case responsetype.SimpleGeojson:
+			return NewRow(m.CombinedSimpleGeojson)
+		case responsetype.FullGeojson:
+			return NewRow(m.CombinedFullGeojson)
+		}
+		return Row{}
+	case ais.CombinedSimpleJson:
+		return Row{
+			Type:             string(responsetype.SimpleJson),
+			Mmsi:             m.Mmsi,
+			Msgtime:          m.Msgtime.UTC(),
+			Latitude:         m.Latitude,
+			Longitude:        m.Longitude,
+			SpeedOverGround:  m.SpeedOverGround,
+			CourseOverGround: m.CourseOverGround,
+			RateOfTurn:       m.RateOfTurn,
+			TrueHeading:      m.TrueHeading,
+			Name:             &m.Name,
+			ShipType:         m.ShipType,
+		}
+	case ais.CombinedFullJson:
+		return Row{
+			Type:                     string(responsetype.FullJson),
+			Mmsi:                     m.Mmsi,
+			Msgtime:                  m.Msgtime.UTC(),
+			Latitude:                 m.Latitude,
+			Longitude:                m.Longitude,
+			Altitude:                 m.Altitude,
+			SpeedOverGround:          m.SpeedOverGround,
+			CourseOverGround:         m.CourseOverGround,
+			RateOfTurn:               m.RateOfTurn,
+			TrueHeading:              m.TrueHeading,
+			NavigationalStatus:       &m.NavigationalStatus,
+			Name:                     &m.Name,
+			ShipType:                 m.ShipType,
+			ImoNumber:                m.ImoNumber,
+			CallSign:                 &m.CallSign,
+			Destination:              &m.Destination,
+			Eta:                      &m.Eta,
+			Draught:                  m.Draught,
+			ShipLength:               m.ShipLength,
+			ShipWidth:                m.ShipWidth,
+			DimensionA:               m.DimensionA,
+			DimensionB:               m.DimensionB,
+			DimensionC:               m.DimensionC,
+			DimensionD:               m.DimensionD,
+			PositionFixingDeviceType: &m.PositionFixingDeviceType,
+			AisClass:                 &m.ReportClass,
+		}
+	case ais.CombinedSimpleGeojson:
+		p := m.Properties
+		r := Row{
+			Type:             string(responsetype.SimpleGeojson),
+			Mmsi:             p.Mmsi,
+			Msgtime:          p.Msgtime.UTC(),
+			SpeedOverGround:  p.SpeedOverGround,
+			CourseOverGround: p.CourseOverGround,
+			RateOfTurn:       p.RateOfTurn,
+			TrueHeading:      p.TrueHeading,
+			Name:             &p.Name,
+			ShipType:         p.ShipType,
+		}
+		r.Longitude, r.Latitude = coordinates(m.Geometry.Coordinates)
+		return r
+	case ais.CombinedFullGeojson:
+		p := m.Properties
+		r := Row{
+			Type:                     string(responsetype.FullGeojson),
+			Mmsi:                     p.Mmsi,
+			Msgtime:                  p.Msgtime.UTC(),
+			SpeedOverGround:          p.SpeedOverGround,
+			CourseOverGround:         p.CourseOverGround,
+			RateOfTurn:               p.RateOfTurn,
+			TrueHeading:              p.TrueHeading,
+			NavigationalStatus:       &p.NavigationalStatus,
+			Name:                     &p.Name,
+			ShipType:                 p.ShipType,
+			ImoNumber:                p.ImoNumber,
+			CallSign:                 &p.CallSign,
+			Destination:              &p.Destination,
+			Eta:                      &p.Eta,
+			Draught:                  p.Draught,
+			ShipLength:               p.ShipLength,
+			ShipWidth:                p.ShipWidth,
+			DimensionA:               p.DimensionA,
+			DimensionB:               p.DimensionB,
+			DimensionC:               p.DimensionC,
+			DimensionD:               p.DimensionD,
+			PositionFixingDeviceType: &p.PositionFixingDeviceType,
+			AisClass:                 &p.ReportClass,
+		}
+		r.Longitude, r.Latitude = coordinates(m.Geometry.Coordinates)
+		return r
+	}
+	panic("unreachable")
+}
+
+// coordinates returns copies of the longitude and latitude of GeoJSON coordinates, or nil if they are missing.
+func coordinates(c []float64) (lon, lat *float64) {
+	if len(c) < 2 {
+		return nil, nil
+	}
+	lon, lat = new(float64), new(float64)
+	*lon, *lat = c[0], c[1]
+	return lon, lat
+}
+
+// RowWriter writes rows to a tabular format. Close must be called to flush buffered rows; it does not close the
+// underlying writer.
+type RowWriter interface {
+	Write(r Row) error
+	Close() error
+}
+
+// Sink writes every message received from in to w as a row, until in is closed or the context is cancelled, and then
+// closes w. It is meant to be used at the end of a stream:
+//
+//	f, err := os.Create("positions.parquet")
+//	// ...
+//	ch, err := res.UnmarshalStream()
+//	// ...
+//	err = export.Sink(ctx, export.NewParquetWriter(f, 0, export.CompressionZstd), ch)
+//
+// Messages of unknown types, which NewRow flattens into the zero Row, are skipped. It returns the context's error if
+// the context was cancelled, the first error writing or closing w, or nil. Rows written before the context was
+// cancelled are flushed.
+func Sink[T Message](ctx context.Context, w RowWriter, in <-chan T) error {
+	for {
+		select {
+		case <-ctx.Done():
+			if err := w.Close(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case msg, ok := <-in:
+			if !ok {
+				return w.Close()
+			}
+			row := NewRow(msg)
+			if row.Type == "" {
+				continue
+			}
+			if err := w.Write(row); err != nil {
+				w.Close()
+				return err
+			}
+		}
+	}
+}
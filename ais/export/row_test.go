@@ -0,0 +1,155 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/export"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+	"github.com/parquet-go/parquet-go"
+)
+
+// send sends the messages on a channel, which is closed when all are sent.
+func send[T any](msgs []T) <-chan T {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for _, msg := range msgs {
+			ch <- msg
+		}
+	}()
+	return ch
+}
+
+func TestNewRow(t *testing.T) {
+	p := track(257000000, 1)[0]
+	p.Altitude = nil
+	row := export.NewRow(p)
+	if row.Type != "Position" || row.Mmsi != 257000000 || row.Latitude == nil || *row.Latitude != *p.Latitude {
+		t.Errorf("unexpected row %+v", row)
+	}
+	if row.Altitude != nil || row.Name != nil || row.ShipType != nil {
+		t.Errorf("expected null fields to be nil, got %+v", row)
+	}
+	if row.NavigationalStatus == nil || *row.NavigationalStatus != 0 {
+		t.Errorf("expected navigational status 0 of position, got %v", row.NavigationalStatus)
+	}
+
+	row = export.NewRow(ais.AisMultiple{Type: responsetype.Staticdata, Staticdata: ais.Staticdata{Mmsi: 1, ReportClass: "A"}})
+	if row.Type != "Staticdata" || row.Latitude != nil || row.Name == nil || *row.AisClass != "A" {
+		t.Errorf("unexpected row %+v", row)
+	}
+
+	if row := export.NewRow(ais.AisMultiple{Position: p}); row != (export.Row{}) {
+		t.Errorf("expected the zero row for a message of unknown type, got %+v", row)
+	}
+	if row := export.NewRow(ais.CombinedMultiple{}); row != (export.Row{}) {
+		t.Errorf("expected the zero row for a combined message of unknown type, got %+v", row)
+	}
+
+	for _, msg := range decodeFixture[ais.CombinedMultiple](t, "../testdata/combined_full_geojson.txt") {
+		row := export.NewRow(msg)
+		if row.Type != "FullGeojson" || row.Mmsi == 0 || row.Destination == nil {
+			t.Fatalf("unexpected row %+v", row)
+		}
+		coordinates := msg.CombinedFullGeojson.Geometry.Coordinates
+		if len(coordinates) == 2 && (row.Longitude == nil || *row.Longitude != coordinates[0]) {
+			t.Fatalf("expected longitude %f, got %v", coordinates[0], row.Longitude)
+		}
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	msgs := decodeFixture[ais.AisMultiple](t, "../testdata/get_ais.txt")
+
+	// A message of unknown type at the end is skipped
+	var buf bytes.Buffer
+	in := send(append(slices.Clone(msgs), ais.AisMultiple{}))
+	if err := export.Sink(context.Background(), export.NewCSVWriter(&buf), in); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(msgs)+1 {
+		t.Fatalf("expected header and %d rows, got %d records", len(msgs), len(records))
+	}
+	for i, column := range export.Columns {
+		if records[0][i] != column {
+			t.Fatalf("expected column %d to be %q, got %q", i, column, records[0][i])
+		}
+	}
+
+	for i, msg := range msgs {
+		record := records[i+1]
+		if record[0] != string(msg.Type) {
+			t.Fatalf("expected type %s, got %s", msg.Type, record[0])
+		}
+		// Static data has no position, which must be written as null rather than zero
+		if msg.Type == responsetype.Staticdata && (record[4] != "" || record[5] != "") {
+			t.Fatalf("expected empty position of static data, got %q, %q", record[4], record[5])
+		}
+	}
+}
+
+func TestCSVWriter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := export.NewCSVWriter(&buf).Close(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 1 || len(records[0]) != len(export.Columns) {
+		t.Errorf("expected only the header, got %v, %v", records, err)
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	msgs := decodeFixture[ais.CombinedFullJson](t, "../testdata/combined_full_json.txt")
+
+	var buf bytes.Buffer
+	w := export.NewParquetWriter(&buf, 10, export.CompressionZstd)
+	if err := export.Sink(context.Background(), w, send(msgs)); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups := len(f.RowGroups()); groups != (len(msgs)+9)/10 {
+		t.Errorf("expected %d row groups of 10 rows, got %d", (len(msgs)+9)/10, groups)
+	}
+	for i, column := range f.Schema().Columns() {
+		if column[0] != export.Columns[i] {
+			t.Fatalf("expected column %d to be %q, got %q", i, export.Columns[i], column[0])
+		}
+	}
+
+	rows, err := parquet.Read[export.Row](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(msgs) {
+		t.Fatalf("expected %d rows, got %d", len(msgs), len(rows))
+	}
+	for i, row := range rows {
+		msg := msgs[i]
+		if row.Type != "FullJson" || row.Mmsi != msg.Mmsi || !row.Msgtime.Equal(msg.Msgtime.Truncate(time.Microsecond)) {
+			t.Fatalf("unexpected row %+v for message %+v", row, msg)
+		}
+		if (row.ImoNumber == nil) != (msg.ImoNumber == nil) || (row.Latitude == nil) != (msg.Latitude == nil) {
+			t.Fatalf("expected nulls to be preserved, got %+v for message %+v", row, msg)
+		}
+		// Messages of the combined types have no message type
+		if row.MessageType != nil {
+			t.Fatalf("expected null message type, got %d", *row.MessageType)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+// Package expr implements a small expression language for filtering AIS messages on the client side, complementing
+// the server side filters of the API.
+//
+// An expression compares message fields with literals, and combines comparisons with boolean logic:
+//
+//	speedOverGround > 15 and navigationalStatus == 1
+//	name ~ 'HAVILA*' or (shipType >= 60 and shipType < 70)
+//	trueHeading is not null and not within([[4, 58], [12, 58], [12, 62], [4, 62]])
+//
+// Fields are named by their JSON names, case-insensitively, and the short names sog, cog, rot, heading, lat, lon and
+// imo are accepted as aliases. The field "type" holds the response type of the message, e.g. "Position".
+//
+// The operators ==, !=, <, <=, > and >= compare numbers, strings and times, where times are written as RFC 3339
+// strings. The operator ~ matches a string field against a case-insensitive glob pattern, where * matches any
+// sequence of characters and ? matches a single character, and =~ matches a string field against a regular
+// expression. A field which is absent from a message, or is null, only matches "is null" and "== null"; all other
+// comparisons with it are false.
+//
+// within matches messages whose position is inside a polygon given as [longitude, latitude] pairs.
+//
+// Boolean logic is written with and, or and not, or equivalently &&, || and !.
+package expr
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/geometry"
+)
+
+// SyntaxError is returned by Compile when an expression is malformed or refers to unknown fields.
+type SyntaxError struct {
+	// Pos is the byte offset in the expression where the error was found.
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("expr: position %d: %s", e.Pos, e.Msg)
+}
+
+// Expression is a compiled expression. It is safe for concurrent use.
+type Expression struct {
+	src  string
+	root node
+}
+
+// Compile parses an expression and checks it against the known message fields.
+func Compile(src string) (*Expression, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{src: src, root: root}, nil
+}
+
+// MustCompile is like Compile, but panics if the expression cannot be compiled.
+func MustCompile(src string) *Expression {
+	e, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// String returns the source of the expression.
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Match is true iff the message matches the expression.
+//
+// The message can be an ais.AisMultiple, ais.CombinedMultiple, or any of the concrete types they hold, such as
+// ais.Position or ais.CombinedFullGeojson. Messages of other types never match.
+func (e *Expression) Match(msg any) bool {
+	r, ok := newRecord(msg)
+	if !ok {
+		return false
+	}
+	return e.root.eval(r)
+}
+
+// Filter passes on the messages from in which match the expression. The returned channel is closed when in is closed,
+// or when the context is cancelled.
+func Filter[T any](ctx context.Context, e *Expression, in <-chan T) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-in:
+				if !ok {
+					return
+				}
+				if !e.Match(msg) {
+					continue
+				}
+				select {
+				case out <- msg:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+type node interface {
+	eval(r record) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(r record) bool { return n.left.eval(r) && n.right.eval(r) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(r record) bool { return n.left.eval(r) || n.right.eval(r) }
+
+type notNode struct{ n node }
+
+func (n notNode) eval(r record) bool { return !n.n.eval(r) }
+
+type nullNode struct {
+	field  string
+	negate bool
+}
+
+func (n nullNode) eval(r record) bool {
+	return (r.get(n.field).kind == kindNull) != n.negate
+}
+
+type compareNode struct {
+	field string
+	op    string
+	lit   value
+}
+
+func (n compareNode) eval(r record) bool {
+	v := r.get(n.field)
+	if v.kind != n.lit.kind {
+		return false
+	}
+
+	var c int
+	switch v.kind {
+	case kindNumber:
+		c = compare(v.num, n.lit.num)
+	case kindString:
+		c = compare(v.str, n.lit.str)
+	case kindTime:
+		switch {
+		case v.t.Before(n.lit.t):
+			c = -1
+		case v.t.After(n.lit.t):
+			c = 1
+		}
+	}
+
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func compare[T float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type matchNode struct {
+	field string
+	re    *regexp.Regexp
+}
+
+func (n matchNode) eval(r record) bool {
+	v := r.get(n.field)
+	return v.kind == kindString && n.re.MatchString(v.str)
+}
+
+type withinNode struct {
+	polygon [][][]float64
+}
+
+func (n withinNode) eval(r record) bool {
+	x, y, ok := r.position()
+	return ok && geometry.PolygonContains(n.polygon, x, y)
+}
@@ -0,0 +1,115 @@
+package expr_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/expr"
+)
+
+const (
+	position = `{"type":"Position","messageType":1,"courseOverGround":296.1,"aisClass":"A","altitude":null,"latitude":58.186747,"longitude":2.023988,"navigationalStatus":1,"rateOfTurn":-10,"speedOverGround":16.8,"trueHeading":null,"mmsi":248024000,"msgtime":"2023-02-20T13:13:55.620904+00:00"}`
+	aton     = `{"type":"Aton","messageType":21,"mmsi":992581025,"msgtime":"2023-02-20T13:13:55.6210607+00:00","dimensionA":0,"dimensionB":0,"dimensionC":0,"dimensionD":0,"typeOfAidsToNavigation":3,"latitude":61.31775,"longitude":2.253103,"name":"HYWIND TAMPEN HY08","typeOfElectronicFixingDevice":1}`
+	geojson  = `{"type":"Feature","geometry":{"type":"Point","coordinates":[6.12674,62.52776]},"properties":{"mmsi":257399000,"name":"HAVILA CASTOR","msgtime":"2023-02-17T11:35:51+00:00","speedOverGround":0,"courseOverGround":223,"navigationalStatus":3,"rateOfTurn":0,"shipType":52,"trueHeading":221,"callSign":"LFSK","destination":null,"eta":"03211234","imoNumber":null,"dimensionA":7,"dimensionB":10,"dimensionC":2,"dimensionD":4,"draught":24,"shipLength":17,"shipWidth":6,"positionFixingDeviceType":1,"reportClass":"A"}}`
+)
+
+func unmarshal[T any](t *testing.T, data string) T {
+	var v T
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestExpression_Match(t *testing.T) {
+	pos := unmarshal[ais.AisMultiple](t, position)
+	at := unmarshal[ais.AisMultiple](t, aton)
+	geo := unmarshal[ais.CombinedMultiple](t, geojson)
+
+	cases := []struct {
+		expr    string
+		msg     any
+		matches bool
+	}{
+		{"SOG > 15 and navigationalStatus == 1", pos, true},
+		{"sog > 15 && navigationalStatus != 1", pos, false},
+		{"speedOverGround <= 16.8", pos, true},
+		{"trueHeading is null", pos, true},
+		{"trueHeading == null", pos, true},
+		{"trueHeading is not null", geo, true},
+		{"trueHeading > 0", pos, false},
+		{"not (trueHeading > 0)", pos, true},
+		{"name is null", pos, true},
+		{"type == 'Aton'", at, true},
+		{"type == 'Aton'", pos, false},
+		{"name ~ 'HYWIND*'", at, true},
+		{"name ~ 'hywind tampen hy0?'", at, true},
+		{"name ~ 'HAVILA*'", geo, true},
+		{"name ~ 'HAVILA*'", at, false},
+		{`name =~ "^HAVILA (CASTOR|POLLUX)$"`, geo, true},
+		{"shipType >= 50 and shipType < 60 or mmsi == 0", geo, true},
+		{"msgtime > '2023-02-20T00:00:00Z'", pos, true},
+		{"msgtime > '2023-02-20T00:00:00Z'", geo, false},
+		{"within([[2, 58], [3, 58], [3, 59], [2, 59], [2, 58]])", pos, true},
+		{"within([[2, 58], [3, 58], [3, 59], [2, 59]])", at, false},
+		{"lat > 62 and lon < 7", geo, true},
+		{"mmsi == 248024000", pos.AsPosition(), true},
+		{"mmsi == 248024000", "not a message", false},
+	}
+
+	for _, c := range cases {
+		e, err := expr.Compile(c.expr)
+		if err != nil {
+			t.Errorf("unable to compile %q: %s", c.expr, err)
+			continue
+		}
+		if m := e.Match(c.msg); m != c.matches {
+			t.Errorf("expected %q to match %v to be %t", c.expr, c.msg, c.matches)
+		}
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"speed > 15",
+		"sog > 'fast'",
+		"name > 5",
+		"sog ~ '1*'",
+		"name =~ '('",
+		"msgtime > 'yesterday'",
+		"sog > 15 and",
+		"(sog > 15",
+		"sog 15",
+		"within([[2, 58], [3, 58]])",
+		"name == 'unterminated",
+		"sog > 15 $",
+	} {
+		_, err := expr.Compile(src)
+		var syntaxErr *expr.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("expected syntax error for %q, got %v", src, err)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	in := make(chan ais.AisMultiple)
+	go func() {
+		defer close(in)
+		in <- unmarshal[ais.AisMultiple](t, position)
+		in <- unmarshal[ais.AisMultiple](t, aton)
+	}()
+
+	var res []ais.AisMultiple
+	for msg := range expr.Filter(context.Background(), expr.MustCompile("type == 'Aton'"), in) {
+		res = append(res, msg)
+	}
+
+	if len(res) != 1 || res[0].Aton.Mmsi != 992581025 {
+		t.Errorf("unexpected filter result %v", res)
+	}
+}
@@ -0,0 +1,231 @@
+package expr
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+)
+
+type kind int
+
+const (
+	kindNull kind = iota
+	kindNumber
+	kindString
+	kindTime
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	case kindTime:
+		return "time"
+	default:
+		return "null"
+	}
+}
+
+// value is the value of a field in a message, or a literal in an expression.
+type value struct {
+	kind kind
+	num  float64
+	str  string
+	t    time.Time
+}
+
+// aliases are short names for commonly used fields.
+var aliases = map[string]string{
+	"sog":     "speedOverGround",
+	"cog":     "courseOverGround",
+	"rot":     "rateOfTurn",
+	"heading": "trueHeading",
+	"lat":     "latitude",
+	"lon":     "longitude",
+	"imo":     "imoNumber",
+}
+
+// fieldKinds maps the lower case name of every known field to its canonical name and kind. The known fields are the
+// union of the JSON fields of all message types, as well as "type".
+var fieldKinds = map[string]struct {
+	name string
+	kind kind
+}{
+	"type": {"type", kindString},
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func init() {
+	for _, v := range []any{
+		ais.Position{},
+		ais.Aton{},
+		ais.Staticdata{},
+		ais.CombinedFullJson{},
+		ais.CombinedSimpleJson{},
+		ais.CombinedFullGeojson{}.Properties,
+		ais.CombinedSimpleGeojson{}.Properties,
+	} {
+		for name, f := range structFields(reflect.TypeOf(v)) {
+			if _, ok := fieldKinds[name]; ok {
+				continue
+			}
+			ft := f.typ
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			k := kindNumber
+			switch {
+			case ft == timeType:
+				k = kindTime
+			case ft.Kind() == reflect.String:
+				k = kindString
+			}
+			fieldKinds[name] = struct {
+				name string
+				kind kind
+			}{f.name, k}
+		}
+	}
+}
+
+type structField struct {
+	name  string
+	index int
+	typ   reflect.Type
+}
+
+var fieldCache sync.Map // reflect.Type -> map[string]structField
+
+// structFields returns the fields of a struct type by lower case JSON name.
+func structFields(t reflect.Type) map[string]structField {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string]structField)
+	}
+	fields := make(map[string]structField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[strings.ToLower(name)] = structField{name, i, f.Type}
+	}
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// record is a message prepared for field lookups.
+type record struct {
+	typ string
+	// v is the struct holding the fields of the message
+	v reflect.Value
+	// coordinates holds the coordinates of Geojson messages, which are not part of the properties struct
+	coordinates []float64
+}
+
+// newRecord prepares a message for field lookups. The returned bool is false if the message type is not supported.
+func newRecord(msg any) (record, bool) {
+	switch m := msg.(type) {
+	case ais.AisMultiple:
+		switch m.Type {
+		case responsetype.Position:
+			return record{typ: string(m.Type), v: reflect.ValueOf(m.Position)}, true
+		case responsetype.Aton:
+			return record{typ: string(m.Type), v: reflect.ValueOf(m.Aton)}, true
+		case responsetype.Staticdata:
+			return record{typ: string(m.Type), v: reflect.ValueOf(m.Staticdata)}, true
+		}
+	case *ais.AisMultiple:
+		return newRecord(*m)
+	case ais.CombinedMultiple:
+		switch m.Type {
+		case responsetype.FullJson:
+			return record{typ: string(m.Type), v: reflect.ValueOf(m.CombinedFullJson)}, true
+		case responsetype.SimpleJson:
+			return record{typ: string(m.Type), v: reflect.ValueOf(m.CombinedSimpleJson)}, true
+		case responsetype.FullGeojson:
+			g := m.CombinedFullGeojson
+			return record{typ: string(m.Type), v: reflect.ValueOf(g.Properties), coordinates: g.Geometry.Coordinates}, true
+		case responsetype.SimpleGeojson:
+			g := m.CombinedSimpleGeojson
+			return record{typ: string(m.Type), v: reflect.ValueOf(g.Properties), coordinates: g.Geometry.Coordinates}, true
+		}
+	case *ais.CombinedMultiple:
+		return newRecord(*m)
+	case ais.Position:
+		return record{typ: string(responsetype.Position), v: reflect.ValueOf(m)}, true
+	case ais.Aton:
+		return record{typ: string(responsetype.Aton), v: reflect.ValueOf(m)}, true
+	case ais.Staticdata:
+		return record{typ: string(responsetype.Staticdata), v: reflect.ValueOf(m)}, true
+	case ais.CombinedFullJson:
+		return record{typ: string(responsetype.FullJson), v: reflect.ValueOf(m)}, true
+	case ais.CombinedSimpleJson:
+		return record{typ: string(responsetype.SimpleJson), v: reflect.ValueOf(m)}, true
+	case ais.CombinedFullGeojson:
+		return record{typ: string(responsetype.FullGeojson), v: reflect.ValueOf(m.Properties), coordinates: m.Geometry.Coordinates}, true
+	case ais.CombinedSimpleGeojson:
+		return record{typ: string(responsetype.SimpleGeojson), v: reflect.ValueOf(m.Properties), coordinates: m.Geometry.Coordinates}, true
+	}
+	return record{}, false
+}
+
+// get returns the value of the field with the given lower case name. Fields which are not present in the message, or
+// are nil, have kind null.
+func (r record) get(field string) value {
+	switch field {
+	case "type":
+		return value{kind: kindString, str: r.typ}
+	case "longitude", "latitude":
+		if r.coordinates != nil {
+			i := 0
+			if field == "latitude" {
+				i = 1
+			}
+			if len(r.coordinates) <= i {
+				return value{}
+			}
+			return value{kind: kindNumber, num: r.coordinates[i]}
+		}
+	}
+
+	f, ok := structFields(r.v.Type())[field]
+	if !ok {
+		return value{}
+	}
+	v := r.v.Field(f.index)
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return value{}
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == timeType:
+		return value{kind: kindTime, t: v.Interface().(time.Time)}
+	case v.Kind() == reflect.String:
+		return value{kind: kindString, str: v.String()}
+	case v.CanInt():
+		return value{kind: kindNumber, num: float64(v.Int())}
+	case v.CanFloat():
+		return value{kind: kindNumber, num: v.Float()}
+	}
+	return value{}
+}
+
+// position returns the longitude and latitude of the message, if present.
+func (r record) position() (lon, lat float64, ok bool) {
+	lonV, latV := r.get("longitude"), r.get("latitude")
+	if lonV.kind != kindNumber || latV.kind != kindNumber {
+		return 0, 0, false
+	}
+	return lonV.num, latV.num, true
+}
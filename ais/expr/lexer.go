@@ -0,0 +1,109 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+type token struct {
+	typ tokenType
+	val string
+	pos int
+}
+
+func (t token) String() string {
+	if t.typ == tokEOF {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+// operators are matched longest first.
+var operators = []string{"==", "!=", "<=", ">=", "=~", "&&", "||", "<", ">", "~", "!"}
+
+// lex splits the source into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{tokLBracket, "[", i})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{tokRBracket, "]", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '\'' || c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(src) && rune(src[i]) != c; i++ {
+				if src[i] == '\\' && i+1 < len(src) {
+					i++
+				}
+				sb.WriteByte(src[i])
+			}
+			if i >= len(src) {
+				return nil, &SyntaxError{Pos: start, Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, token{tokString, sb.String(), start})
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			start := i
+			i++
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || strings.ContainsRune(".eE", rune(src[i])) ||
+				(strings.ContainsRune("+-", rune(src[i])) && strings.ContainsRune("eE", rune(src[i-1])))) {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i], start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, src[start:i], start})
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{tokOp, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
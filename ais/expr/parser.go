@@ -0,0 +1,305 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parser is a recursive descent parser for the grammar
+//
+//	expr       = and { ("or" | "||") and }
+//	and        = unary { ("and" | "&&") unary }
+//	unary      = ("not" | "!") unary | primary
+//	primary    = "(" expr ")" | within | comparison
+//	within     = "within" "(" "[" position { "," position } "]" ")"
+//	position   = "[" number "," number "]"
+//	comparison = field ( op literal | "is" [ "not" ] "null" )
+//	op         = "==" | "!=" | "<" | "<=" | ">" | ">=" | "~" | "=~"
+//	literal    = number | string | "null"
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// keyword is true iff the token is the given keyword or operator
+func keyword(t token, words ...string) bool {
+	if t.typ != tokIdent && t.typ != tokOp {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.val, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(typ tokenType, what string) (token, error) {
+	t := p.next()
+	if t.typ != typ {
+		return t, p.errorf(t, "expected %s, found %s", what, t)
+	}
+	return t, nil
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.typ != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return n, nil
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for keyword(p.peek(), "or", "||") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for keyword(p.peek(), "and", "&&") {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if keyword(p.peek(), "not", "!") {
+		p.next()
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	switch {
+	case t.typ == tokLParen:
+		p.next()
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case keyword(t, "within") && p.tokens[p.pos+1].typ == tokLParen:
+		return p.within()
+	case t.typ == tokIdent:
+		return p.comparison()
+	default:
+		return nil, p.errorf(t, "expected field, found %s", t)
+	}
+}
+
+func (p *parser) within() (node, error) {
+	p.next()
+	p.next()
+	if _, err := p.expect(tokLBracket, `"["`); err != nil {
+		return nil, err
+	}
+	var ring [][]float64
+	for {
+		start, err := p.expect(tokLBracket, `"["`)
+		if err != nil {
+			return nil, err
+		}
+		var pos [2]float64
+		for i := range pos {
+			if i > 0 {
+				if _, err := p.expect(tokComma, `","`); err != nil {
+					return nil, err
+				}
+			}
+			t, err := p.expect(tokNumber, "number")
+			if err != nil {
+				return nil, err
+			}
+			if pos[i], err = strconv.ParseFloat(t.val, 64); err != nil {
+				return nil, p.errorf(t, "invalid number %s", t)
+			}
+		}
+		if pos[0] < -180 || pos[0] > 180 || pos[1] < -90 || pos[1] > 90 {
+			return nil, p.errorf(start, "position out of bounds: [%g, %g]", pos[0], pos[1])
+		}
+		if _, err := p.expect(tokRBracket, `"]"`); err != nil {
+			return nil, err
+		}
+		ring = append(ring, []float64{pos[0], pos[1]})
+		if p.peek().typ != tokComma {
+			break
+		}
+		p.next()
+	}
+	end, err := p.expect(tokRBracket, `"]"`)
+	if err != nil {
+		return nil, err
+	}
+	if len(ring) > 1 && slices.Equal(ring[0], ring[len(ring)-1]) {
+		ring = ring[:len(ring)-1]
+	}
+	if len(ring) < 3 {
+		return nil, p.errorf(end, "polygon must have at least 3 distinct positions")
+	}
+	if _, err := p.expect(tokRParen, `")"`); err != nil {
+		return nil, err
+	}
+	return withinNode{[][][]float64{ring}}, nil
+}
+
+func (p *parser) comparison() (node, error) {
+	t := p.next()
+	field, ok := fieldKinds[strings.ToLower(t.val)]
+	if alias, isAlias := aliases[strings.ToLower(t.val)]; !ok && isAlias {
+		field, ok = fieldKinds[strings.ToLower(alias)]
+	}
+	if !ok {
+		return nil, p.errorf(t, "unknown field %q", t.val)
+	}
+	name := strings.ToLower(field.name)
+
+	if keyword(p.peek(), "is") {
+		p.next()
+		negate := false
+		if keyword(p.peek(), "not") {
+			p.next()
+			negate = true
+		}
+		if n := p.next(); !keyword(n, "null") {
+			return nil, p.errorf(n, "expected null, found %s", n)
+		}
+		return nullNode{name, negate}, nil
+	}
+
+	opTok := p.next()
+	if opTok.typ != tokOp || !keyword(opTok, "==", "!=", "<", "<=", ">", ">=", "~", "=~") {
+		return nil, p.errorf(opTok, "expected comparison operator after %q, found %s", field.name, opTok)
+	}
+	op := opTok.val
+
+	lit := p.next()
+	if keyword(lit, "null") {
+		switch op {
+		case "==":
+			return nullNode{name, false}, nil
+		case "!=":
+			return nullNode{name, true}, nil
+		default:
+			return nil, p.errorf(opTok, "operator %s cannot be used with null", op)
+		}
+	}
+
+	switch op {
+	case "~", "=~":
+		if field.kind != kindString {
+			return nil, p.errorf(opTok, "operator %s requires a string field, %q is a %s", op, field.name, field.kind)
+		}
+		if lit.typ != tokString {
+			return nil, p.errorf(lit, "operator %s requires a string, found %s", op, lit)
+		}
+		pattern := lit.val
+		if op == "~" {
+			pattern = globToRegexp(pattern)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, p.errorf(lit, "invalid pattern: %s", err)
+		}
+		return matchNode{name, re}, nil
+	}
+
+	var v value
+	switch field.kind {
+	case kindNumber:
+		if lit.typ != tokNumber {
+			return nil, p.errorf(lit, "field %q is a number, found %s", field.name, lit)
+		}
+		n, err := strconv.ParseFloat(lit.val, 64)
+		if err != nil {
+			return nil, p.errorf(lit, "invalid number %s", lit)
+		}
+		v = value{kind: kindNumber, num: n}
+	case kindString:
+		if lit.typ != tokString {
+			return nil, p.errorf(lit, "field %q is a string, found %s", field.name, lit)
+		}
+		v = value{kind: kindString, str: lit.val}
+	case kindTime:
+		if lit.typ != tokString {
+			return nil, p.errorf(lit, "field %q is a time, expected an RFC 3339 string, found %s", field.name, lit)
+		}
+		t, err := time.Parse(time.RFC3339, lit.val)
+		if err != nil {
+			return nil, p.errorf(lit, "field %q is a time, expected an RFC 3339 string: %s", field.name, err)
+		}
+		v = value{kind: kindTime, t: t}
+	}
+
+	return compareNode{name, op, v}, nil
+}
+
+// globToRegexp converts a glob pattern, where * matches any sequence of characters and ? matches a single character,
+// into an anchored, case-insensitive regular expression.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("(?i)^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
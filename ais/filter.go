@@ -0,0 +1,512 @@
+package ais
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/countrycode"
+	"github.com/ilder-as/go-barentswatch-ais/modelformat"
+	"github.com/ilder-as/go-barentswatch-ais/modeltype"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// DefaultMaxSinceAge is the maximum age of the Since field of a filter accepted by Validate, and by the builders unless
+// set with their MaxSinceAge methods. The API only serves a limited history, and requests for data further back than
+// it return nothing, so the age should be set to the history of the API if it differs.
+const DefaultMaxSinceAge = 24 * time.Hour
+
+// ValidationError is returned by the Validate methods of the filter types, and holds every problem found.
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Error()
+	}
+	return "invalid filter: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the problems found, so that they can be inspected with errors.Is and errors.As, which follow
+// multiple wrapped errors from Go 1.20.
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// IsValidationError returns true iff the supplied error is, or wraps, a *ValidationError.
+func IsValidationError(err error) bool {
+	var v *ValidationError
+	return errors.As(err, &v)
+}
+
+// validation collects problems found while validating a filter.
+type validation []error
+
+func (v *validation) addf(format string, args ...any) {
+	*v = append(*v, fmt.Errorf(format, args...))
+}
+
+func (v validation) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: v}
+}
+
+// Validate checks the filter against the constraints of the API, and returns a *ValidationError listing every problem
+// found, or nil if the filter is valid. Since may be at most DefaultMaxSinceAge in the past.
+func (f FilterInput) Validate() error {
+	return f.validate(0)
+}
+
+// validate validates the filter, with Since at most maxSinceAge in the past. Zero means DefaultMaxSinceAge.
+func (f FilterInput) validate(maxSinceAge time.Duration) error {
+	var v validation
+	v.geometry(f.Geometry)
+	v.since(f.Since, maxSinceAge)
+	v.mmsi(f.MMSI...)
+	v.shipTypes(f.ShipTypes)
+	v.countryCodes(f.CountryCodes)
+	if !(f.IncludePosition || f.IncludeStatic || f.IncludeAton || f.IncludeSafetyRelated || f.IncludeBinaryBroadcastMetHyd) {
+		v.addf("no message types included")
+	}
+	return v.err()
+}
+
+// Validate checks the filter against the constraints of the API, and returns a *ValidationError listing every problem
+// found, or nil if the filter is valid. Since may be at most DefaultMaxSinceAge in the past.
+func (f LatestAisFilterInput) Validate() error {
+	return f.validate(0)
+}
+
+// validate validates the filter, with Since at most maxSinceAge in the past. Zero means DefaultMaxSinceAge.
+func (f LatestAisFilterInput) validate(maxSinceAge time.Duration) error {
+	var v validation
+	v.geometry(f.Geometry)
+	v.since(f.Since, maxSinceAge)
+	v.mmsi(f.MMSI...)
+	v.shipTypes(f.ShipTypes)
+	v.countryCodes(f.CountryCodes)
+	if !(f.IncludePosition || f.IncludeStatic || f.IncludeAton || f.IncludeSafetyRelated || f.IncludeBinaryBroadcastMetHyd) {
+		v.addf("no message types included")
+	}
+	return v.err()
+}
+
+// Validate checks the filter against the constraints of the API, and returns a *ValidationError listing every problem
+// found, or nil if the filter is valid. Since may be at most DefaultMaxSinceAge in the past.
+func (f CombinedFilterInput) Validate() error {
+	return f.validate(0)
+}
+
+// validate validates the filter, with Since at most maxSinceAge in the past. Zero means DefaultMaxSinceAge.
+func (f CombinedFilterInput) validate(maxSinceAge time.Duration) error {
+	var v validation
+	v.geometry(f.Geometry)
+	v.since(f.Since, maxSinceAge)
+	if f.MMSI != nil {
+		v.mmsi(*f.MMSI)
+	}
+	v.shipTypes(f.ShipTypes)
+	v.countryCodes(f.CountryCodes)
+	switch f.ModelType {
+	case modeltype.ModelTypeS, modeltype.ModelTypeFull:
+	case "":
+		v.addf("model type is empty")
+	default:
+		v.addf("unknown model type: %s", f.ModelType)
+	}
+	switch f.ModelFormat {
+	case modelformat.Json, modelformat.Geojson:
+	case "":
+		v.addf("model format is empty")
+	default:
+		v.addf("unknown model format: %s", f.ModelFormat)
+	}
+	return v.err()
+}
+
+func (v *validation) since(since *time.Time, maxAge time.Duration) {
+	if since == nil {
+		return
+	}
+	now := time.Now()
+	maxAge = cmp.Or(maxAge, DefaultMaxSinceAge)
+	if since.After(now) {
+		v.addf("since is in the future: %s", since.Format(time.RFC3339))
+	} else if now.Sub(*since) > maxAge {
+		v.addf("since is more than %s ago: %s", maxAge, since.Format(time.RFC3339))
+	}
+}
+
+func (v *validation) mmsi(mmsis ...int) {
+	for _, mmsi := range mmsis {
+		if mmsi <= 0 || mmsi > 999999999 {
+			v.addf("invalid mmsi: %d", mmsi)
+		}
+	}
+}
+
+func (v *validation) shipTypes(shipTypes []shiptype.ShipType) {
+	for _, s := range shipTypes {
+		if !s.Valid() {
+			v.addf("invalid ship type: %d", int(s))
+		}
+	}
+}
+
+func (v *validation) countryCodes(countryCodes []countrycode.CountryCode) {
+	for _, c := range countryCodes {
+		if !c.Valid() {
+			v.addf("unknown country code: %q", string(c))
+		}
+	}
+}
+
+func (v *validation) geometry(g *geojson.Geometry) {
+	if g == nil {
+		return
+	}
+	switch g.Type {
+	case geojson.GeometryPolygon:
+		v.polygon(g.Polygon)
+	case geojson.GeometryMultiPolygon:
+		if len(g.MultiPolygon) == 0 {
+			v.addf("geometry has no polygons")
+		}
+		for _, p := range g.MultiPolygon {
+			v.polygon(p)
+		}
+	default:
+		v.addf("unsupported geometry type: %q", g.Type)
+	}
+}
+
+func (v *validation) polygon(rings [][][]float64) {
+	if len(rings) == 0 {
+		v.addf("polygon has no rings")
+	}
+	for _, ring := range rings {
+		if len(ring) < 4 {
+			v.addf("polygon ring has %d positions, at least 4 are required", len(ring))
+			continue
+		}
+		for _, pos := range ring {
+			if len(pos) < 2 {
+				v.addf("polygon position has %d coordinates, at least 2 are required", len(pos))
+				return
+			}
+			if pos[0] < -180 || pos[0] > 180 || pos[1] < -90 || pos[1] > 90 {
+				v.addf("polygon position out of bounds: [%g, %g]", pos[0], pos[1])
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			v.addf("polygon ring is not closed")
+		}
+	}
+}
+
+// BoundingBox returns a polygon geometry covering the area between the supplied corners, for use as the Geometry of a
+// filter.
+func BoundingBox(minLon, minLat, maxLon, maxLat float64) *geojson.Geometry {
+	return geojson.NewPolygonGeometry([][][]float64{{
+		{minLon, minLat},
+		{maxLon, minLat},
+		{maxLon, maxLat},
+		{minLon, maxLat},
+		{minLon, minLat},
+	}})
+}
+
+// FilterInputBuilder builds a FilterInput.
+//
+// A FilterInputBuilder must be constructed with the NewFilterInput factory function.
+type FilterInputBuilder struct {
+	f           FilterInput
+	maxSinceAge time.Duration
+}
+
+// NewFilterInput creates a new FilterInputBuilder. By default, positions and static data are included.
+func NewFilterInput() *FilterInputBuilder {
+	return &FilterInputBuilder{f: FilterInput{IncludePosition: true, IncludeStatic: true}}
+}
+
+// Geometry limits the filter to the supplied polygon or multipolygon.
+func (b *FilterInputBuilder) Geometry(g *geojson.Geometry) *FilterInputBuilder {
+	b.f.Geometry = g
+	return b
+}
+
+// Since limits the filter to messages received after t.
+func (b *FilterInputBuilder) Since(t time.Time) *FilterInputBuilder {
+	b.f.Since = &t
+	return b
+}
+
+// MaxSinceAge sets the maximum age of Since accepted by Build. Zero means DefaultMaxSinceAge.
+func (b *FilterInputBuilder) MaxSinceAge(maxAge time.Duration) *FilterInputBuilder {
+	b.maxSinceAge = maxAge
+	return b
+}
+
+// MMSI adds vessels to the filter by MMSI.
+func (b *FilterInputBuilder) MMSI(mmsi ...int) *FilterInputBuilder {
+	b.f.MMSI = append(b.f.MMSI, mmsi...)
+	return b
+}
+
+// ShipTypes adds ship types to the filter.
+func (b *FilterInputBuilder) ShipTypes(shipTypes ...shiptype.ShipType) *FilterInputBuilder {
+	b.f.ShipTypes = append(b.f.ShipTypes, shipTypes...)
+	return b
+}
+
+// CountryCodes adds flag states to the filter.
+func (b *FilterInputBuilder) CountryCodes(countryCodes ...countrycode.CountryCode) *FilterInputBuilder {
+	b.f.CountryCodes = append(b.f.CountryCodes, countryCodes...)
+	return b
+}
+
+// IncludePosition sets whether position messages are included.
+func (b *FilterInputBuilder) IncludePosition(include bool) *FilterInputBuilder {
+	b.f.IncludePosition = include
+	return b
+}
+
+// IncludeStatic sets whether static data messages are included.
+func (b *FilterInputBuilder) IncludeStatic(include bool) *FilterInputBuilder {
+	b.f.IncludeStatic = include
+	return b
+}
+
+// IncludeAton sets whether aids to navigation messages are included.
+func (b *FilterInputBuilder) IncludeAton(include bool) *FilterInputBuilder {
+	b.f.IncludeAton = include
+	return b
+}
+
+// IncludeSafetyRelated sets whether safety related messages are included.
+func (b *FilterInputBuilder) IncludeSafetyRelated(include bool) *FilterInputBuilder {
+	b.f.IncludeSafetyRelated = include
+	return b
+}
+
+// IncludeBinaryBroadcastMetHyd sets whether binary broadcast meteorological and hydrological messages are included.
+func (b *FilterInputBuilder) IncludeBinaryBroadcastMetHyd(include bool) *FilterInputBuilder {
+	b.f.IncludeBinaryBroadcastMetHyd = include
+	return b
+}
+
+// Downsample sets whether the data is downsampled by the API.
+func (b *FilterInputBuilder) Downsample(downsample bool) *FilterInputBuilder {
+	b.f.Downsample = downsample
+	return b
+}
+
+// Build validates and returns the FilterInput. If the filter is invalid, the error is a *ValidationError. The filter
+// does not share its slices with the builder, which can go on to build other filters.
+func (b *FilterInputBuilder) Build() (FilterInput, error) {
+	f := b.f
+	f.MMSI, f.ShipTypes, f.CountryCodes = slices.Clone(f.MMSI), slices.Clone(f.ShipTypes), slices.Clone(f.CountryCodes)
+	return f, f.validate(b.maxSinceAge)
+}
+
+// LatestAisFilterInputBuilder builds a LatestAisFilterInput.
+//
+// A LatestAisFilterInputBuilder must be constructed with the NewLatestAisFilterInput factory function.
+type LatestAisFilterInputBuilder struct {
+	f           LatestAisFilterInput
+	maxSinceAge time.Duration
+}
+
+// NewLatestAisFilterInput creates a new LatestAisFilterInputBuilder. By default, positions and static data are
+// included.
+func NewLatestAisFilterInput() *LatestAisFilterInputBuilder {
+	return &LatestAisFilterInputBuilder{f: LatestAisFilterInput{IncludePosition: true, IncludeStatic: true}}
+}
+
+// Geometry limits the filter to the supplied polygon or multipolygon.
+func (b *LatestAisFilterInputBuilder) Geometry(g *geojson.Geometry) *LatestAisFilterInputBuilder {
+	b.f.Geometry = g
+	return b
+}
+
+// Since limits the filter to messages received after t.
+func (b *LatestAisFilterInputBuilder) Since(t time.Time) *LatestAisFilterInputBuilder {
+	b.f.Since = &t
+	return b
+}
+
+// MaxSinceAge sets the maximum age of Since accepted by Build. Zero means DefaultMaxSinceAge.
+func (b *LatestAisFilterInputBuilder) MaxSinceAge(maxAge time.Duration) *LatestAisFilterInputBuilder {
+	b.maxSinceAge = maxAge
+	return b
+}
+
+// MMSI adds vessels to the filter by MMSI.
+func (b *LatestAisFilterInputBuilder) MMSI(mmsi ...int) *LatestAisFilterInputBuilder {
+	b.f.MMSI = append(b.f.MMSI, mmsi...)
+	return b
+}
+
+// ShipTypes adds ship types to the filter.
+func (b *LatestAisFilterInputBuilder) ShipTypes(shipTypes ...shiptype.ShipType) *LatestAisFilterInputBuilder {
+	b.f.ShipTypes = append(b.f.ShipTypes, shipTypes...)
+	return b
+}
+
+// CountryCodes adds flag states to the filter.
+func (b *LatestAisFilterInputBuilder) CountryCodes(countryCodes ...countrycode.CountryCode) *LatestAisFilterInputBuilder {
+	b.f.CountryCodes = append(b.f.CountryCodes, countryCodes...)
+	return b
+}
+
+// IncludePosition sets whether position messages are included.
+func (b *LatestAisFilterInputBuilder) IncludePosition(include bool) *LatestAisFilterInputBuilder {
+	b.f.IncludePosition = include
+	return b
+}
+
+// IncludeStatic sets whether static data messages are included.
+func (b *LatestAisFilterInputBuilder) IncludeStatic(include bool) *LatestAisFilterInputBuilder {
+	b.f.IncludeStatic = include
+	return b
+}
+
+// IncludeAton sets whether aids to navigation messages are included.
+func (b *LatestAisFilterInputBuilder) IncludeAton(include bool) *LatestAisFilterInputBuilder {
+	b.f.IncludeAton = include
+	return b
+}
+
+// IncludeSafetyRelated sets whether safety related messages are included.
+func (b *LatestAisFilterInputBuilder) IncludeSafetyRelated(include bool) *LatestAisFilterInputBuilder {
+	b.f.IncludeSafetyRelated = include
+	return b
+}
+
+// IncludeBinaryBroadcastMetHyd sets whether binary broadcast meteorological and hydrological messages are included.
+func (b *LatestAisFilterInputBuilder) IncludeBinaryBroadcastMetHyd(include bool) *LatestAisFilterInputBuilder {
+	b.f.IncludeBinaryBroadcastMetHyd = include
+	return b
+}
+
+// Build validates and returns the LatestAisFilterInput. If the filter is invalid, the error is a *ValidationError. The
+// filter does not share its slices with the builder, which can go on to build other filters.
+func (b *LatestAisFilterInputBuilder) Build() (LatestAisFilterInput, error) {
+	f := b.f
+	f.MMSI, f.ShipTypes, f.CountryCodes = slices.Clone(f.MMSI), slices.Clone(f.ShipTypes), slices.Clone(f.CountryCodes)
+	return f, f.validate(b.maxSinceAge)
+}
+
+// ResponseType returns the type of the messages the API responds with to the filter, as decided by its model type and
+// model format. It returns false if either is empty or unknown.
+func (f CombinedFilterInput) ResponseType() (responsetype.Combined, bool) {
+	switch {
+	case f.ModelType == modeltype.ModelTypeS && f.ModelFormat == modelformat.Json:
+		return responsetype.SimpleJson, true
+	case f.ModelType == modeltype.ModelTypeFull && f.ModelFormat == modelformat.Json:
+		return responsetype.FullJson, true
+	case f.ModelType == modeltype.ModelTypeS && f.ModelFormat == modelformat.Geojson:
+		return responsetype.SimpleGeojson, true
+	case f.ModelType == modeltype.ModelTypeFull && f.ModelFormat == modelformat.Geojson:
+		return responsetype.FullGeojson, true
+	default:
+		return "", false
+	}
+}
+
+// combinedType returns the type the messages of a combined stream requested with the filter are decoded into. A filter
+// without model type and model format leaves the type to be deduced from every message, while a filter with only one
+// of them, or an unknown one, is an error.
+func (f CombinedFilterInput) combinedType() (responsetype.Combined, error) {
+	typ, ok := f.ResponseType()
+	if !ok && (f.ModelType != "" || f.ModelFormat != "") {
+		return "", fmt.Errorf("no response type for model type %q and model format %q", f.ModelType, f.ModelFormat)
+	}
+	return typ, nil
+}
+
+// CombinedFilterInputBuilder builds a CombinedFilterInput.
+//
+// A CombinedFilterInputBuilder must be constructed with the NewCombinedFilterInput factory function.
+type CombinedFilterInputBuilder struct {
+	f           CombinedFilterInput
+	maxSinceAge time.Duration
+}
+
+// NewCombinedFilterInput creates a new CombinedFilterInputBuilder. By default, the simple model type in JSON format is
+// requested.
+func NewCombinedFilterInput() *CombinedFilterInputBuilder {
+	return &CombinedFilterInputBuilder{f: CombinedFilterInput{ModelType: modeltype.ModelTypeS, ModelFormat: modelformat.Json}}
+}
+
+// Geometry limits the filter to the supplied polygon or multipolygon.
+func (b *CombinedFilterInputBuilder) Geometry(g *geojson.Geometry) *CombinedFilterInputBuilder {
+	b.f.Geometry = g
+	return b
+}
+
+// Since limits the filter to messages received after t.
+func (b *CombinedFilterInputBuilder) Since(t time.Time) *CombinedFilterInputBuilder {
+	b.f.Since = &t
+	return b
+}
+
+// MaxSinceAge sets the maximum age of Since accepted by Build. Zero means DefaultMaxSinceAge.
+func (b *CombinedFilterInputBuilder) MaxSinceAge(maxAge time.Duration) *CombinedFilterInputBuilder {
+	b.maxSinceAge = maxAge
+	return b
+}
+
+// MMSI limits the filter to a single vessel.
+func (b *CombinedFilterInputBuilder) MMSI(mmsi int) *CombinedFilterInputBuilder {
+	b.f.MMSI = &mmsi
+	return b
+}
+
+// ShipTypes adds ship types to the filter.
+func (b *CombinedFilterInputBuilder) ShipTypes(shipTypes ...shiptype.ShipType) *CombinedFilterInputBuilder {
+	b.f.ShipTypes = append(b.f.ShipTypes, shipTypes...)
+	return b
+}
+
+// CountryCodes adds flag states to the filter.
+func (b *CombinedFilterInputBuilder) CountryCodes(countryCodes ...countrycode.CountryCode) *CombinedFilterInputBuilder {
+	b.f.CountryCodes = append(b.f.CountryCodes, countryCodes...)
+	return b
+}
+
+// ModelType sets the model type of the response.
+func (b *CombinedFilterInputBuilder) ModelType(t modeltype.ModelType) *CombinedFilterInputBuilder {
+	b.f.ModelType = t
+	return b
+}
+
+// ModelFormat sets the model format of the response.
+func (b *CombinedFilterInputBuilder) ModelFormat(f modelformat.ModelFormat) *CombinedFilterInputBuilder {
+	b.f.ModelFormat = f
+	return b
+}
+
+// Downsample sets whether the data is downsampled by the API.
+func (b *CombinedFilterInputBuilder) Downsample(downsample bool) *CombinedFilterInputBuilder {
+	b.f.Downsample = downsample
+	return b
+}
+
+// Build validates and returns the CombinedFilterInput. If the filter is invalid, the error is a *ValidationError. The
+// filter does not share its slices with the builder, which can go on to build other filters.
+func (b *CombinedFilterInputBuilder) Build() (CombinedFilterInput, error) {
+	f := b.f
+	f.ShipTypes, f.CountryCodes = slices.Clone(f.ShipTypes), slices.Clone(f.CountryCodes)
+	return f, f.validate(b.maxSinceAge)
+}
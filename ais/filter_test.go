@@ -0,0 +1,118 @@
+package ais_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/countrycode"
+	"github.com/ilder-as/go-barentswatch-ais/modelformat"
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestFilterInputBuilder(t *testing.T) {
+	filter, err := ais.NewFilterInput().
+		Geometry(ais.BoundingBox(4, 58, 12, 62)).
+		Since(time.Now().Add(-time.Hour)).
+		MMSI(257123456).
+		ShipTypes(shiptype.ShipTypesIn(shiptype.CategoryTanker)...).
+		CountryCodes(countrycode.Norway).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !filter.IncludePosition || !filter.IncludeStatic || filter.IncludeAton {
+		t.Error("expected positions and static data to be included by default")
+	}
+	if len(filter.ShipTypes) != 10 {
+		t.Errorf("expected 10 ship types, got %d", len(filter.ShipTypes))
+	}
+
+	// Filters built from the same builder do not share their slices
+	b := ais.NewFilterInput().MMSI(257123456, 257123457).ShipTypes(shiptype.Fishing).CountryCodes(countrycode.Norway)
+	first, _ := b.Build()
+	first.MMSI[0], first.ShipTypes[0], first.CountryCodes[0] = 0, shiptype.Tug, countrycode.Sweden
+	second, _ := b.MMSI(257123458).Build()
+	if second.MMSI[0] != 257123456 || second.ShipTypes[0] != shiptype.Fishing ||
+		second.CountryCodes[0] != countrycode.Norway {
+		t.Errorf("expected the second filter unchanged by the first, got %+v", second)
+	}
+}
+
+func TestFilterInput_Validate(t *testing.T) {
+	since := time.Now().Add(time.Hour)
+	filter := ais.FilterInput{
+		Geometry: geojson.NewPolygonGeometry([][][]float64{{{4, 58}, {12, 58}, {12, 62}, {4, 62}}}),
+		Since:    &since,
+		MMSI:     []int{-1},
+	}
+
+	err := filter.Validate()
+	if !ais.IsValidationError(err) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	var v *ais.ValidationError
+	errors.As(err, &v)
+	// Unclosed polygon, future since, invalid MMSI and no message types included
+	if len(v.Problems) != 4 {
+		t.Errorf("expected 4 problems, got %d: %s", len(v.Problems), err)
+	}
+	for _, p := range v.Problems {
+		if !errors.Is(err, p) {
+			t.Errorf("expected the validation error to unwrap to %v", p)
+		}
+	}
+}
+
+func TestFilterInputBuilder_MaxSinceAge(t *testing.T) {
+	since := time.Now().Add(-3 * 24 * time.Hour)
+	if _, err := ais.NewFilterInput().Since(since).Build(); !ais.IsValidationError(err) {
+		t.Errorf("expected a validation error for since older than DefaultMaxSinceAge, got %v", err)
+	}
+	if _, err := ais.NewLatestAisFilterInput().Since(since).MaxSinceAge(7 * 24 * time.Hour).Build(); err != nil {
+		t.Errorf("expected since within MaxSinceAge to be accepted, got %v", err)
+	}
+}
+
+func TestCombinedFilterInput_Validate(t *testing.T) {
+	if err := (ais.CombinedFilterInput{}).Validate(); err == nil {
+		t.Error("expected error for empty model type and format")
+	}
+
+	filter, err := ais.NewCombinedFilterInput().ModelFormat(modelformat.Geojson).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.ModelFormat != modelformat.Geojson {
+		t.Errorf("expected model format Geojson, got %s", filter.ModelFormat)
+	}
+}
+
+func TestFilterInput_MarshalJSON(t *testing.T) {
+	filters := []any{
+		ais.FilterInput{ShipTypes: []shiptype.ShipType{shiptype.Fishing, shiptype.Tug}},
+		ais.LatestAisFilterInput{ShipTypes: []shiptype.ShipType{shiptype.Fishing, shiptype.Tug}},
+		ais.CombinedFilterInput{ShipTypes: []shiptype.ShipType{shiptype.Fishing, shiptype.Tug}},
+	}
+	for _, f := range filters {
+		data, err := json.Marshal(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if string(fields["shipTypes"]) != "[30,52]" {
+			t.Errorf("expected ship types as numeric codes in %T, got %s", f, fields["shipTypes"])
+		}
+		if _, ok := fields["geometry"]; !ok {
+			t.Errorf("expected the other fields of %T, got %s", f, data)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package aistest builds the synthetic messages the tests of the analysis packages are run on. Times are given as
+// durations after Start, so that tests read as timelines:
+//
+//	msgs := []aistest.Message{
+//		aistest.Staticdata(1, 0, "NORDSTJERNEN"),
+//		aistest.Position(1, 0, 14.4, 67.28, 0),
+//		aistest.Position(1, 10*time.Minute, 14.4, 67.30, 10),
+//	}
+//	for ev := range portcall.Detect(ctx, d, aistest.Stream(msgs)) {
+//		// ...
+//	}
+package aistest
+
+import (
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+)
+
+// Start is the time the synthetic messages are relative to.
+var Start = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+// Message is a synthetic message, such as an ais.Position or an ais.Staticdata. It is accepted by the functions which
+// take streams of any of the message types, such as portcall.Detect.
+type Message = interface{ Vessel() ais.Vessel }
+
+// Position returns a position report of the vessel, at after Start, at the coordinates with the speed over ground in
+// knots.
+func Position(mmsi int, after time.Duration, lon, lat, sog float64) ais.Position {
+	return ais.Position{
+		Mmsi:            mmsi,
+		Msgtime:         Start.Add(after),
+		Longitude:       &lon,
+		Latitude:        &lat,
+		SpeedOverGround: &sog,
+	}
+}
+
+// Staticdata returns a static data report of the named vessel, at after Start.
+func Staticdata(mmsi int, after time.Duration, name string) ais.Staticdata {
+	return ais.Staticdata{
+		Mmsi:    mmsi,
+		Msgtime: Start.Add(after),
+		Name:    name,
+	}
+}
+
+// Stream sends the messages on the returned channel in order, and closes it after the last, as a stream does.
+func Stream[T any](msgs []T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, msg := range msgs {
+			out <- msg
+		}
+	}()
+	return out
+}
@@ -0,0 +1,62 @@
+// Package geometry implements the planar geometry of GeoJSON polygons shared by the packages of ais, such as
+// point-in-polygon tests. Coordinates are in degrees of longitude and latitude, as in GeoJSON, and treated as planar.
+package geometry
+
+import (
+	"slices"
+
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// Polygons returns the polygons of a Polygon or MultiPolygon geometry, leaving out those without rings. Other
+// geometries, and nil, have no polygons.
+func Polygons(g *geojson.Geometry) [][][][]float64 {
+	if g == nil {
+		return nil
+	}
+	var res [][][][]float64
+	switch g.Type {
+	case geojson.GeometryPolygon:
+		res = [][][][]float64{g.Polygon}
+	case geojson.GeometryMultiPolygon:
+		res = g.MultiPolygon
+	}
+	return slices.DeleteFunc(slices.Clone(res), func(p [][][]float64) bool { return len(p) == 0 })
+}
+
+// Contains is true iff the point is inside the Polygon or MultiPolygon geometry. Points inside holes are outside the
+// polygon. Other geometries, and nil, contain no points.
+func Contains(g *geojson.Geometry, lon, lat float64) bool {
+	for _, p := range Polygons(g) {
+		if PolygonContains(p, lon, lat) {
+			return true
+		}
+	}
+	return false
+}
+
+// PolygonContains is true iff the point is inside the polygon, given as its outer ring followed by its holes.
+func PolygonContains(rings [][][]float64, lon, lat float64) bool {
+	if len(rings) == 0 || !ringContains(rings[0], lon, lat) {
+		return false
+	}
+	for _, hole := range rings[1:] {
+		if ringContains(hole, lon, lat) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains casts a ray from the point towards increasing longitude, and counts the edges of the ring it crosses.
+func ringContains(ring [][]float64, lon, lat float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,51 @@
+package geometry_test
+
+import (
+	"testing"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/geometry"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestContains(t *testing.T) {
+	// A square with a square hole in the middle, and a triangle far from it
+	square := [][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{4, 4}, {6, 4}, {6, 6}, {4, 6}, {4, 4}},
+	}
+	triangle := [][][]float64{{{20, 20}, {21, 20}, {21, 21}, {20, 20}}}
+	polygon := geojson.NewPolygonGeometry(square)
+	multi := geojson.NewMultiPolygonGeometry(square, triangle)
+
+	tests := []struct {
+		geometry *geojson.Geometry
+		lon, lat float64
+		want     bool
+	}{
+		{polygon, 1, 1, true},
+		{polygon, 5, 5, false},
+		{polygon, 11, 5, false},
+		{polygon, -1, 5, false},
+		{polygon, 20.9, 20.1, false},
+		{multi, 1, 1, true},
+		{multi, 5, 5, false},
+		{multi, 20.9, 20.1, true},
+		{geojson.NewPointGeometry([]float64{1, 1}), 1, 1, false},
+		{nil, 1, 1, false},
+	}
+	for _, test := range tests {
+		if got := geometry.Contains(test.geometry, test.lon, test.lat); got != test.want {
+			t.Errorf("%v contains %f, %f: expected %t, got %t", test.geometry, test.lon, test.lat, test.want, got)
+		}
+	}
+}
+
+func TestPolygons(t *testing.T) {
+	square := [][][]float64{{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}
+	if p := geometry.Polygons(geojson.NewMultiPolygonGeometry(square, nil, square)); len(p) != 2 {
+		t.Errorf("expected polygons without rings to be left out, got %v", p)
+	}
+	if p := geometry.Polygons(nil); p != nil {
+		t.Errorf("expected no polygons of nil, got %v", p)
+	}
+}
@@ -0,0 +1,96 @@
+package ais
+
+import "github.com/ilder-as/go-barentswatch-ais/navmath"
+
+// Located is a value which may have coordinates, such as a Position, an Aton or a navmath.Point.
+type Located interface {
+	// Point returns the coordinates of the value, and false if they are not known.
+	Point() (navmath.Point, bool)
+}
+
+func point(lat, lon *float64) (navmath.Point, bool) {
+	if lat == nil || lon == nil {
+		return navmath.Point{}, false
+	}
+	return navmath.Point{Latitude: *lat, Longitude: *lon}, true
+}
+
+// distanceTo returns the great-circle distance in metres from a to b, and false if either has no coordinates.
+func distanceTo(a, b Located) (float64, bool) {
+	p, ok := a.Point()
+	q, ok2 := b.Point()
+	if !ok || !ok2 {
+		return 0, false
+	}
+	return navmath.Distance(p, q), true
+}
+
+// bearingTo returns the initial great-circle bearing in degrees from a to b, and false if either has no coordinates.
+func bearingTo(a, b Located) (float64, bool) {
+	p, ok := a.Point()
+	q, ok2 := b.Point()
+	if !ok || !ok2 {
+		return 0, false
+	}
+	return navmath.InitialBearing(p, q), true
+}
+
+// Point returns the coordinates of the position, and false if they are not known.
+func (a Position) Point() (navmath.Point, bool) {
+	return point(a.Latitude, a.Longitude)
+}
+
+// DistanceTo returns the great-circle distance in metres to other, and false if either has no coordinates.
+func (a Position) DistanceTo(other Located) (float64, bool) {
+	return distanceTo(a, other)
+}
+
+// BearingTo returns the initial great-circle bearing in degrees to other, and false if either has no coordinates.
+func (a Position) BearingTo(other Located) (float64, bool) {
+	return bearingTo(a, other)
+}
+
+// Point returns the coordinates of the aid to navigation, and false if they are not known.
+func (a Aton) Point() (navmath.Point, bool) {
+	return point(a.Latitude, a.Longitude)
+}
+
+// DistanceTo returns the great-circle distance in metres to other, and false if either has no coordinates.
+func (a Aton) DistanceTo(other Located) (float64, bool) {
+	return distanceTo(a, other)
+}
+
+// BearingTo returns the initial great-circle bearing in degrees to other, and false if either has no coordinates.
+func (a Aton) BearingTo(other Located) (float64, bool) {
+	return bearingTo(a, other)
+}
+
+// Point returns the coordinates of the vessel, and false if they are not known.
+func (a CombinedSimpleJson) Point() (navmath.Point, bool) {
+	return point(a.Latitude, a.Longitude)
+}
+
+// DistanceTo returns the great-circle distance in metres to other, and false if either has no coordinates.
+func (a CombinedSimpleJson) DistanceTo(other Located) (float64, bool) {
+	return distanceTo(a, other)
+}
+
+// BearingTo returns the initial great-circle bearing in degrees to other, and false if either has no coordinates.
+func (a CombinedSimpleJson) BearingTo(other Located) (float64, bool) {
+	return bearingTo(a, other)
+}
+
+// Point returns the coordinates of the vessel, and false if they are not known.
+func (v Vessel) Point() (navmath.Point, bool) {
+	return point(v.Latitude, v.Longitude)
+}
+
+// DistanceTo returns the great-circle distance in metres to other, and false if either has no coordinates.
+func (v Vessel) DistanceTo(other Located) (float64, bool) {
+	return distanceTo(v, other)
+}
+
+// BearingTo returns the initial great-circle bearing in degrees to other, and false if either has no coordinates.
+func (v Vessel) BearingTo(other Located) (float64, bool) {
+	return bearingTo(v, other)
+}
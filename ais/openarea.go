@@ -0,0 +1,312 @@
+package ais
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/geometry"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// ErrOutsideOpenArea is returned by OpenArea.Check and OpenArea.Clip when a geometry lies entirely outside the Open
+// AIS Area. The API serves no data outside the area, so requests limited to such a geometry return nothing.
+var ErrOutsideOpenArea = errors.New("geometry lies entirely outside the open AIS area")
+
+// OpenArea is the Open AIS Area, the area in which the API serves AIS data, as returned by GetOpenAisArea.
+//
+// It is used to check and clip the geometries of filters before requests:
+//
+//	area, err := client.OpenArea()
+//	if err != nil {
+//		return err
+//	}
+//	if filter.Geometry, err = area.Clip(filter.Geometry); errors.Is(err, ais.ErrOutsideOpenArea) {
+//		// No data will be returned for the filter
+//	}
+type OpenArea struct {
+	// Geometry is the area, as a Polygon or MultiPolygon geometry.
+	Geometry *geojson.Geometry
+	polygons [][][][]float64
+}
+
+// NewOpenArea creates an OpenArea of the geometry, which must be a Polygon or MultiPolygon geometry. It returns an
+// error for other geometries, and for nil.
+func NewOpenArea(g *geojson.Geometry) (*OpenArea, error) {
+	polygons := geometry.Polygons(g)
+	if len(polygons) == 0 {
+		return nil, fmt.Errorf("open AIS area is not a polygon or multipolygon")
+	}
+	return &OpenArea{Geometry: g, polygons: polygons}, nil
+}
+
+// OpenArea returns the Open AIS Area. It is fetched with GetOpenAisArea the first time it is requested, and cached for
+// the life of the Client. Errors are not cached. The area is fetched without holding up other requests for it, so
+// concurrent first requests may each fetch it.
+func (c *Client) OpenArea() (*OpenArea, error) {
+	return c.OpenAreaContext(context.Background())
+}
+
+// OpenAreaContext returns the Open AIS Area with a context for cancellation of the request, if one is made. See
+// OpenArea.
+func (c *Client) OpenAreaContext(ctx context.Context) (*OpenArea, error) {
+	c.openAreaMu.Lock()
+	area := c.openArea
+	c.openAreaMu.Unlock()
+	if area != nil {
+		return area, nil
+	}
+
+	area, err := c.fetchOpenArea(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.openAreaMu.Lock()
+	defer c.openAreaMu.Unlock()
+	if c.openArea == nil {
+		c.openArea = area
+	}
+	return c.openArea, nil
+}
+
+func (c *Client) fetchOpenArea(ctx context.Context) (*OpenArea, error) {
+	res, err := c.GetOpenAisArea(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("unexpected status fetching the open AIS area: %s", res.Status)
+	}
+	g, err := res.Unmarshal()
+	if err != nil {
+		return nil, err
+	}
+	return NewOpenArea(&g)
+}
+
+// Contains is true iff the point is inside the area.
+func (a *OpenArea) Contains(lat, lon float64) bool {
+	for _, p := range a.polygons {
+		if geometry.PolygonContains(p, lon, lat) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersects is true iff the Polygon or MultiPolygon geometry overlaps the area. A nil geometry, which leaves a filter
+// unlimited, intersects every area.
+func (a *OpenArea) Intersects(g *geojson.Geometry) bool {
+	if g == nil {
+		return true
+	}
+	for _, p := range geometry.Polygons(g) {
+		for _, q := range a.polygons {
+			if polygonsIntersect(p, q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check returns ErrOutsideOpenArea if the geometry, such as the Geometry of a FilterInput, lies entirely outside the
+// area, and nil otherwise.
+func (a *OpenArea) Check(g *geojson.Geometry) error {
+	if !a.Intersects(g) {
+		return ErrOutsideOpenArea
+	}
+	return nil
+}
+
+// Clip returns the part of the Polygon or MultiPolygon geometry inside the area, for use as the Geometry of a filter.
+// A nil geometry is returned as is, and ErrOutsideOpenArea is returned if the geometry lies entirely outside the area.
+//
+// The result is exact when either the geometry or the polygons of the area are convex, as bounding boxes are. Otherwise
+// the geometry is clipped to the convex hulls of the polygons of the area, which covers every part of the geometry
+// inside the area, and more. Holes in the area are not clipped away.
+func (a *OpenArea) Clip(g *geojson.Geometry) (*geojson.Geometry, error) {
+	if g == nil {
+		return nil, nil
+	}
+	if !a.Intersects(g) {
+		return nil, ErrOutsideOpenArea
+	}
+
+	var res [][][][]float64
+	for _, p := range geometry.Polygons(g) {
+		for _, q := range a.polygons {
+			if len(p) == 1 && convex(p[0]) {
+				if ring := clipRing(q[0], p[0]); ring != nil {
+					res = append(res, [][][]float64{ring})
+				}
+				continue
+			}
+			hull := convexHull(q[0])
+			outer := clipRing(p[0], hull)
+			if outer == nil {
+				continue
+			}
+			clipped := [][][]float64{outer}
+			for _, hole := range p[1:] {
+				if ring := clipRing(hole, hull); ring != nil {
+					clipped = append(clipped, ring)
+				}
+			}
+			res = append(res, clipped)
+		}
+	}
+
+	switch len(res) {
+	case 0:
+		return nil, ErrOutsideOpenArea
+	case 1:
+		return geojson.NewPolygonGeometry(res[0]), nil
+	default:
+		return geojson.NewMultiPolygonGeometry(res...), nil
+	}
+}
+
+// polygonsIntersect is true iff the polygons overlap: an edge of one crosses an edge of the other, or a position of
+// one is inside the other.
+func polygonsIntersect(p, q [][][]float64) bool {
+	for _, a := range p {
+		for _, b := range q {
+			for i := 1; i < len(a); i++ {
+				for j := 1; j < len(b); j++ {
+					if segmentsCross(a[i-1], a[i], b[j-1], b[j]) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	for _, pos := range p[0] {
+		if geometry.PolygonContains(q, pos[0], pos[1]) {
+			return true
+		}
+	}
+	for _, pos := range q[0] {
+		if geometry.PolygonContains(p, pos[0], pos[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// cross returns the cross product of the vectors from o to a and from o to b, which is positive if o, a and b turn
+// counter-clockwise.
+func cross(o, a, b []float64) float64 {
+	return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+}
+
+// segmentsCross is true iff the segments from a1 to a2 and from b1 to b2 cross, not counting segments which only touch.
+func segmentsCross(a1, a2, b1, b2 []float64) bool {
+	d1, d2 := cross(b1, b2, a1), cross(b1, b2, a2)
+	d3, d4 := cross(a1, a2, b1), cross(a1, a2, b2)
+	return (d1 > 0) != (d2 > 0) && d1 != 0 && d2 != 0 && (d3 > 0) != (d4 > 0) && d3 != 0 && d4 != 0
+}
+
+// signedArea returns the signed area of the closed ring, which is positive if it is counter-clockwise.
+func signedArea(ring [][]float64) float64 {
+	sum := 0.0
+	for i := 1; i < len(ring); i++ {
+		sum += ring[i-1][0]*ring[i][1] - ring[i][0]*ring[i-1][1]
+	}
+	return sum / 2
+}
+
+// convex is true iff the closed ring is convex.
+func convex(ring [][]float64) bool {
+	n := len(ring) - 1
+	if n < 3 {
+		return false
+	}
+	sign := 0.0
+	for i := range n {
+		c := cross(ring[i], ring[(i+1)%n], ring[(i+2)%n])
+		if c == 0 {
+			continue
+		}
+		if sign != 0 && (c > 0) != (sign > 0) {
+			return false
+		}
+		sign = c
+	}
+	return sign != 0
+}
+
+// convexHull returns the convex hull of the closed ring as a closed, counter-clockwise ring, by the monotone chain
+// algorithm.
+func convexHull(ring [][]float64) [][]float64 {
+	points := slices.Clone(ring)
+	slices.SortFunc(points, func(a, b []float64) int {
+		return cmp.Or(cmp.Compare(a[0], b[0]), cmp.Compare(a[1], b[1]))
+	})
+	reversed := slices.Clone(points)
+	slices.Reverse(reversed)
+	var hull [][]float64
+	for _, pass := range [2][][]float64{points, reversed} {
+		start := len(hull)
+		for _, p := range pass {
+			for len(hull) >= start+2 && cross(hull[len(hull)-2], hull[len(hull)-1], p) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		hull = hull[:len(hull)-1]
+	}
+	if len(hull) == 0 {
+		return ring
+	}
+	return append(hull, hull[0])
+}
+
+// clipRing clips the closed subject ring to the closed, convex clip ring by the Sutherland–Hodgman algorithm, and
+// returns the clipped ring, closed, or nil if nothing of it remains.
+func clipRing(subject, clip [][]float64) [][]float64 {
+	if signedArea(clip) < 0 {
+		clip = slices.Clone(clip)
+		slices.Reverse(clip)
+	}
+	out := subject[:len(subject)-1]
+	for i := 1; i < len(clip) && len(out) > 0; i++ {
+		a, b := clip[i-1], clip[i]
+		in := out
+		out = nil
+		for j, cur := range in {
+			prev := in[(j+len(in)-1)%len(in)]
+			curIn, prevIn := cross(a, b, cur) >= 0, cross(a, b, prev) >= 0
+			if curIn != prevIn {
+				out = append(out, intersection(prev, cur, a, b))
+			}
+			if curIn {
+				out = append(out, cur)
+			}
+		}
+	}
+
+	out = slices.CompactFunc(out, func(p, q []float64) bool { return p[0] == q[0] && p[1] == q[1] })
+	if len(out) > 1 && out[0][0] == out[len(out)-1][0] && out[0][1] == out[len(out)-1][1] {
+		out = out[:len(out)-1]
+	}
+	if len(out) < 3 {
+		return nil
+	}
+	res := append(slices.Clone(out), out[0])
+	if signedArea(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// intersection returns the intersection of the line through p and q with the line through a and b.
+func intersection(p, q, a, b []float64) []float64 {
+	d1, d2 := cross(a, b, p), cross(a, b, q)
+	t := d1 / (d1 - d2)
+	return []float64{p[0] + t*(q[0]-p[0]), p[1] + t*(q[1]-p[1])}
+}
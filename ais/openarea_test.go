@@ -0,0 +1,205 @@
+package ais_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// openArea is an L-shaped area, with a notch in its north-west corner.
+var openArea = geojson.NewPolygonGeometry([][][]float64{{{0, 60}, {20, 60}, {20, 70}, {10, 70}, {10, 65}, {0, 65}, {0, 60}}})
+
+func TestOpenArea_Contains(t *testing.T) {
+	area, err := ais.NewOpenArea(openArea)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		lat, lon float64
+		want     bool
+	}{
+		{62, 5, true},
+		{68, 15, true},
+		{68, 5, false},
+		{75, 15, false},
+	}
+	for _, test := range tests {
+		if got := area.Contains(test.lat, test.lon); got != test.want {
+			t.Errorf("expected Contains(%g, %g) to be %v", test.lat, test.lon, test.want)
+		}
+	}
+
+	if _, err := ais.NewOpenArea(geojson.NewPointGeometry([]float64{0, 60})); err == nil {
+		t.Error("expected an error for a point")
+	}
+	if _, err := ais.NewOpenArea(nil); err == nil {
+		t.Error("expected an error for no geometry")
+	}
+}
+
+func TestOpenArea_Check(t *testing.T) {
+	area, _ := ais.NewOpenArea(openArea)
+	tests := []struct {
+		name string
+		g    *geojson.Geometry
+		want error
+	}{
+		{"unlimited", nil, nil},
+		{"inside", ais.BoundingBox(12, 62, 14, 64), nil},
+		{"overlapping", ais.BoundingBox(15, 55, 25, 62), nil},
+		{"around", ais.BoundingBox(-10, 50, 30, 80), nil},
+		{"foreign waters", ais.BoundingBox(30, 60, 40, 70), ais.ErrOutsideOpenArea},
+		{"notch", ais.BoundingBox(1, 66, 9, 69), ais.ErrOutsideOpenArea},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := area.Check(test.g); !errors.Is(err, test.want) {
+				t.Errorf("expected %v, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestOpenArea_Clip(t *testing.T) {
+	area, _ := ais.NewOpenArea(openArea)
+
+	tests := []struct {
+		name    string
+		g       *geojson.Geometry
+		inside  [][2]float64
+		outside [][2]float64
+	}{
+		{
+			// The bounding box is convex, so the clipped geometry is exactly the overlap
+			name:    "convex",
+			g:       ais.BoundingBox(5, 62, 15, 68),
+			inside:  [][2]float64{{63, 5.5}, {67, 12}},
+			outside: [][2]float64{{67, 5.5}, {61, 16}},
+		},
+		{
+			// Neither is convex, so the geometry is clipped to the convex hull of the area
+			name: "concave",
+			g: geojson.NewPolygonGeometry([][][]float64{{
+				{18, 55}, {25, 55}, {25, 58}, {19, 58}, {19, 62}, {18, 62}, {18, 55},
+			}}),
+			inside:  [][2]float64{{61, 18.5}},
+			outside: [][2]float64{{57, 20}, {59, 18.5}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clipped, err := area.Clip(test.g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := (ais.FilterInput{Geometry: clipped, IncludePosition: true}).Validate(); err != nil {
+				t.Errorf("expected the clipped geometry to be valid in a filter, got %v", err)
+			}
+			c, _ := ais.NewOpenArea(clipped)
+			for _, p := range test.inside {
+				if !c.Contains(p[0], p[1]) {
+					t.Errorf("expected %v to be inside the clipped geometry %v", p, clipped.Polygon)
+				}
+			}
+			for _, p := range test.outside {
+				if c.Contains(p[0], p[1]) {
+					t.Errorf("expected %v to be outside the clipped geometry %v", p, clipped.Polygon)
+				}
+			}
+		})
+	}
+
+	if g, err := area.Clip(nil); g != nil || err != nil {
+		t.Errorf("expected no geometry to be left as is, got %v, %v", g, err)
+	}
+	if _, err := area.Clip(ais.BoundingBox(30, 60, 40, 70)); !errors.Is(err, ais.ErrOutsideOpenArea) {
+		t.Errorf("expected ErrOutsideOpenArea, got %v", err)
+	}
+}
+
+func TestClient_OpenArea(t *testing.T) {
+	requests := 0
+	sv := server(t, oauthSpoofMW(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/openaisarea") {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		w.Header().Add("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openArea)
+	}))
+	defer sv.Close()
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+	client := ais.NewClient("", "", urls)
+
+	for range 2 {
+		area, err := client.OpenArea()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !area.Contains(62, 5) {
+			t.Error("expected the area to be decoded")
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected the area to be fetched once, got %d requests", requests)
+	}
+
+	urls.OpenAISAreaEndpoint = "/v1/missing"
+	if _, err := ais.NewClient("", "", urls).OpenArea(); err == nil {
+		t.Error("expected an error for a missing area")
+	}
+}
+
+func TestClient_OpenAreaConcurrent(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var requests atomic.Int32
+	sv := server(t, oauthSpoofMW(func(w http.ResponseWriter, r *http.Request) {
+		// The first request is held until the test ends
+		if requests.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		w.Header().Add("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openArea)
+	}))
+	defer sv.Close()
+	defer close(release)
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+	client := ais.NewClient("", "", urls)
+
+	go client.OpenArea()
+	<-started
+
+	// A slow request does not hold up others
+	type result struct {
+		area *ais.OpenArea
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		area, err := client.OpenArea()
+		done <- result{area, err}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil || !res.area.Contains(62, 5) {
+			t.Errorf("expected the area to be decoded, got %v", res.err)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the request not to wait for the slow request")
+	}
+}
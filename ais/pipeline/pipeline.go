@@ -0,0 +1,389 @@
+// Package pipeline implements composable, context-aware stages for processing the channels returned by
+// StreamResponse.UnmarshalStream.
+//
+// Every stage takes a context and one or more input channels, and returns one or more output channels. A stage's
+// output channels are closed when its input channels are closed, or when the context is cancelled, at which point the
+// stage's goroutines exit. Stages apply backpressure: a stage only reads from its input when the previous value has
+// been consumed from its output.
+//
+// A typical pipeline looks like
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	defer cancel()
+//
+//	stream, err := client.GetAisContext(ctx)
+//	// ...
+//	ch, wait, err := pipeline.FromStream(ctx, &stream)
+//	// ...
+//	positions := pipeline.Filter(ctx, ch, func(a ais.AisMultiple) bool { return a.Type == responsetype.Position })
+//	batches := pipeline.Batch(ctx, positions, 100, time.Second)
+//	for batch := range batches {
+//		// ...
+//	}
+//	if err := wait(); !ais.IsEOF(err) {
+//		// ...
+//	}
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+)
+
+// FromStream unmarshals the stream and returns its data channel, as well as a function which waits for the stream to
+// end and returns the reason, as reported by StreamResponse.Error.
+//
+// The context should be the context of the request which returned the stream, or a child of it, so that cancelling
+// it stops both the stream and the pipeline. If it is not, cancelling it closes the stream's body.
+func FromStream[T any](ctx context.Context, stream *ais.StreamResponse[T]) (<-chan T, func() error, error) {
+	in, err := stream.UnmarshalStream()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make(chan T)
+	done := make(chan struct{})
+	var streamErr error
+
+	go func() {
+		defer close(done)
+		defer close(out)
+
+		// cancel closes the body, which ends the stream even if its request has another context, and drains in until
+		// UnmarshalStream has returned
+		cancel := func() {
+			streamErr = ctx.Err()
+			stream.Body.Close()
+			for range in {
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				cancel()
+				return
+			case v, ok := <-in:
+				if !ok {
+					streamErr = stream.Error()
+					return
+				}
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	wait := func() error {
+		<-done
+		return streamErr
+	}
+
+	return out, wait, nil
+}
+
+// ForEach calls fn for every value received from in, until in is closed, the context is cancelled or fn returns an
+// error. It returns the context's error if the context was cancelled, the error returned by fn, or nil.
+func ForEach[T any](ctx context.Context, in <-chan T, fn func(T) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case v, ok := <-in:
+			if !ok {
+				return nil
+			}
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// send sends v on out, and returns false if the context was cancelled first.
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Filter passes on the values from in for which keep returns true.
+func Filter[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if keep(v) && !send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// Map passes on the result of fn applied to every value from in.
+func Map[T, U any](ctx context.Context, in <-chan T, fn func(T) U) <-chan U {
+	out := make(chan U)
+
+	go func() {
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				if !send(ctx, out, fn(v)) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// Batch groups the values from in into slices of at most size values. A batch is passed on when it is full, or when
+// maxWait has passed since its first value was received, whichever comes first. A maxWait of zero or less means
+// batches are only passed on when full. A partial batch is passed on when in is closed.
+func Batch[T any](ctx context.Context, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	if size < 1 {
+		size = 1
+	}
+	out := make(chan []T)
+
+	go func() {
+		defer close(out)
+
+		var batch []T
+		// timeout is nil, and thus blocks forever, whenever no batch is pending
+		var timeout <-chan time.Time
+		var timer *time.Timer
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			b := batch
+			batch = nil
+			return send(ctx, out, b)
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timeout:
+				if !flush() {
+					return
+				}
+			case v, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				if len(batch) == 0 && maxWait > 0 {
+					timer = time.NewTimer(maxWait)
+					timeout = timer.C
+				}
+				batch = append(batch, v)
+				if len(batch) >= size && !flush() {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// Dedupe drops values whose key, as returned by key, has already been seen within the given window. The window is
+// measured from the first time a key is seen, so a value repeated continuously is passed on once per window.
+func Dedupe[T any, K comparable](ctx context.Context, in <-chan T, key func(T) K, window time.Duration) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		seen := make(map[K]time.Time)
+		lastPrune := time.Now()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+
+				now := time.Now()
+				if now.Sub(lastPrune) >= window {
+					for k, t := range seen {
+						if now.Sub(t) >= window {
+							delete(seen, k)
+						}
+					}
+					lastPrune = now
+				}
+
+				k := key(v)
+				if t, ok := seen[k]; ok && now.Sub(t) < window {
+					continue
+				}
+				seen[k] = now
+
+				if !send(ctx, out, v) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// Tee passes on every value from in to n output channels. A value is sent to every output before the next value is
+// read from in, so the pipeline runs at the pace of the slowest consumer.
+func Tee[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	outs := make([]chan T, n)
+	res := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T)
+		res[i] = outs[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				for _, out := range outs {
+					if !send(ctx, out, v) {
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return res
+}
+
+// Merge passes on the values from all the input channels, in the order they are received. The output channel is
+// closed when all the input channels are closed.
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func(in <-chan T) {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+					if !send(ctx, out, v) {
+						return
+					}
+				}
+			}
+		}(in)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// Throttle passes on the values from in at most once per interval. Values are delayed rather than dropped, so a
+// throttled pipeline applies backpressure upstream.
+func Throttle[T any](ctx context.Context, in <-chan T, interval time.Duration) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		var next time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+
+				if wait := time.Until(next); wait > 0 {
+					timer := time.NewTimer(wait)
+					select {
+					case <-ctx.Done():
+						timer.Stop()
+						return
+					case <-timer.C:
+					}
+				}
+
+				if !send(ctx, out, v) {
+					return
+				}
+				next = time.Now().Add(interval)
+			}
+		}
+	}()
+
+	return out
+}
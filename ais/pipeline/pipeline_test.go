@@ -0,0 +1,223 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/pipeline"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+	"golang.org/x/oauth2"
+)
+
+// source returns a channel which yields the supplied values and then closes.
+func source[T any](values ...T) <-chan T {
+	ch := make(chan T)
+	go func() {
+		defer close(ch)
+		for _, v := range values {
+			ch <- v
+		}
+	}()
+	return ch
+}
+
+func collect[T any](ch <-chan T) []T {
+	var res []T
+	for v := range ch {
+		res = append(res, v)
+	}
+	return res
+}
+
+func TestFilterMap(t *testing.T) {
+	ctx := context.Background()
+	evens := pipeline.Filter(ctx, source(1, 2, 3, 4, 5, 6), func(i int) bool { return i%2 == 0 })
+	squares := collect(pipeline.Map(ctx, evens, func(i int) int { return i * i }))
+
+	if len(squares) != 3 || squares[0] != 4 || squares[1] != 16 || squares[2] != 36 {
+		t.Errorf("unexpected result %v", squares)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	batches := collect(pipeline.Batch(ctx, source(1, 2, 3, 4, 5), 2, time.Hour))
+	if len(batches) != 3 || len(batches[2]) != 1 {
+		t.Errorf("unexpected batches %v", batches)
+	}
+
+	in := make(chan int)
+	out := pipeline.Batch(ctx, in, 10, 10*time.Millisecond)
+	in <- 1
+	select {
+	case batch := <-out:
+		if len(batch) != 1 {
+			t.Errorf("unexpected batch %v", batch)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected partial batch to be flushed after max wait")
+	}
+	close(in)
+}
+
+func TestDedupe(t *testing.T) {
+	ctx := context.Background()
+	res := collect(pipeline.Dedupe(ctx, source(1, 2, 1, 3, 2), func(i int) int { return i }, time.Hour))
+	if len(res) != 3 {
+		t.Errorf("unexpected result %v", res)
+	}
+
+	res = collect(pipeline.Dedupe(ctx, source(1, 1, 1), func(i int) int { return i }, 0))
+	if len(res) != 3 {
+		t.Errorf("expected no deduplication with empty window, got %v", res)
+	}
+}
+
+func TestTeeMerge(t *testing.T) {
+	ctx := context.Background()
+	outs := pipeline.Tee(ctx, source(1, 2, 3), 2)
+	res := collect(pipeline.Merge(ctx, outs...))
+	if len(res) != 6 {
+		t.Errorf("expected every value twice, got %v", res)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	ctx := context.Background()
+	start := time.Now()
+	res := collect(pipeline.Throttle(ctx, source(1, 2, 3), 20*time.Millisecond))
+	if len(res) != 3 {
+		t.Errorf("unexpected result %v", res)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected throttling to take at least 40ms, took %s", elapsed)
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	outs := pipeline.Tee(ctx, pipeline.Throttle(ctx, pipeline.Batch(ctx, in, 5, time.Second), time.Millisecond), 2)
+	merged := pipeline.Merge(ctx, pipeline.Filter(ctx, outs[0], func([]int) bool { return true }), outs[1])
+	in <- 1
+	cancel()
+
+	for range merged {
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("expected goroutines to exit on cancellation, %d remain of %d", n, before)
+	}
+}
+
+// newClient returns a client of a test server, which serves streams with the handler.
+func newClient(t *testing.T, handler http.HandlerFunc) *ais.Client {
+	sv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "token") {
+			w.Header().Add("Content-Type", "application/json")
+			token, _ := json.Marshal(oauth2.Token{AccessToken: "x", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)})
+			w.Write(token)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(sv.Close)
+
+	urls := ais.DefaultURLs()
+	urls.OAuthBase = sv.URL
+	urls.APIBase = sv.URL
+	return ais.NewClient("", "", urls)
+}
+
+func TestFromStream(t *testing.T) {
+	data, err := os.ReadFile("../testdata/get_ais.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	})
+
+	ctx := context.Background()
+	stream, err := client.GetAisContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, wait, err := pipeline.FromStream(ctx, &stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	num := 0
+	positions := pipeline.Filter(ctx, ch, func(a ais.AisMultiple) bool { return a.Type == responsetype.Position })
+	err = pipeline.ForEach(ctx, positions, func(a ais.AisMultiple) error {
+		num++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if num <= 0 {
+		t.Errorf("expected positions, got %d", num)
+	}
+	if err := wait(); !ais.IsEOF(err) {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestFromStream_Cancel(t *testing.T) {
+	data, err := os.ReadFile("../testdata/get_ais.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, _, _ := strings.Cut(string(data), "\n")
+
+	// The server sends a single message, and holds the stream open until the client closes it
+	closed, release := make(chan struct{}), make(chan struct{})
+	client := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(line + "\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			close(closed)
+		case <-release:
+		}
+	})
+	t.Cleanup(func() { close(release) })
+
+	stream, err := client.GetAisContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, wait, err := pipeline.FromStream(ctx, &stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ch
+	cancel()
+
+	if err := wait(); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("expected the stream to be closed when the pipeline's context is cancelled")
+	}
+}
@@ -0,0 +1,520 @@
+// Package portcall detects port calls and anchorages in streams of AIS messages, and keeps a log of them.
+//
+// A Detector is given the ports and anchorages of interest as polygons. It follows every vessel's speed over ground,
+// navigational status and position, and reports an event when a vessel has stopped for long enough in a port
+// (Arrival) or an anchorage (Anchored), and when it leaves again (Departure and Underway). Vessels which report
+// themselves as moored or at anchor outside the known areas are reported without an area.
+//
+// Events carry the destination and ETA most recently reported by the vessel, so that arrivals can be matched with
+// the voyages they end:
+//
+//	d := portcall.NewDetector(areas)
+//	for ev := range portcall.Detect(ctx, d, msgs) {
+//		if ev.Type == portcall.Arrival && !ev.DestinationMatch {
+//			// ...
+//		}
+//	}
+//	calls := d.Calls()
+package portcall
+
+import (
+	"cmp"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/geometry"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// AreaKind is the kind of an area.
+type AreaKind int
+
+const (
+	Port AreaKind = iota
+	Anchorage
+)
+
+func (k AreaKind) String() string {
+	switch k {
+	case Port:
+		return "Port"
+	case Anchorage:
+		return "Anchorage"
+	}
+	return fmt.Sprintf("AreaKind(%d)", int(k))
+}
+
+// Area is a port or an anchorage.
+type Area struct {
+	// Name is the name of the area, which is matched with the destinations reported by vessels.
+	Name string
+	// Aliases are other names the area is known by in destinations, such as its UN/LOCODE.
+	Aliases []string
+	Kind    AreaKind
+	// Geometry is the extent of the area, which must be a Polygon or MultiPolygon.
+	Geometry *geojson.Geometry
+}
+
+// Contains is true if the coordinates are inside the area.
+func (a *Area) Contains(lon, lat float64) bool {
+	return geometry.Contains(a.Geometry, lon, lat)
+}
+
+// Matches is true if the destination names the area, by its name or one of its aliases. Case, spaces and punctuation
+// are ignored, and destinations which merely contain a name, such as "NO OSL" for the alias "NOOSL" or "OSLO PIER 2"
+// for "Oslo", match it.
+func (a *Area) Matches(destination string) bool {
+	destination = normalise(destination)
+	if destination == "" {
+		return false
+	}
+	for _, name := range append([]string{a.Name}, a.Aliases...) {
+		if name := normalise(name); name != "" && strings.Contains(destination, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalise(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, s)
+}
+
+// EventType is the type of an event.
+type EventType int
+
+const (
+	// Arrival is when a vessel stops in a port, or reports itself as moored.
+	Arrival EventType = iota
+	// Departure is when a vessel which had arrived starts moving again.
+	Departure
+	// Anchored is when a vessel stops in an anchorage, or reports itself as at anchor.
+	Anchored
+	// Underway is when a vessel which had anchored starts moving again.
+	Underway
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Arrival:
+		return "Arrival"
+	case Departure:
+		return "Departure"
+	case Anchored:
+		return "Anchored"
+	case Underway:
+		return "Underway"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+// Event is a vessel arriving, departing, anchoring or getting underway.
+type Event struct {
+	Type EventType
+	Mmsi int
+	// Name is the name of the vessel, if it is known.
+	Name string
+	// Time is when the vessel stopped or started moving, which is earlier than the message the event was detected
+	// from.
+	Time time.Time
+	// Longitude and Latitude are the position of the vessel at Time.
+	Longitude float64
+	Latitude  float64
+	// Area is the area the vessel stopped in or left, or nil if it stopped outside the known areas.
+	Area *Area
+
+	// Destination and Eta are the destination and ETA most recently reported by the vessel. Eta is the zero time if
+	// it is not known.
+	Destination string
+	Eta         time.Time
+	// DestinationMatch is true if the destination names the area.
+	DestinationMatch bool
+}
+
+// Navigational statuses used to detect stops.
+const (
+	navigationalStatusAtAnchor = 1
+	navigationalStatusMoored   = 5
+)
+
+// Defaults of Detector.
+const (
+	DefaultStopSpeed = 0.5
+	DefaultMoveSpeed = 2.0
+	DefaultDwellTime = 15 * time.Minute
+)
+
+type state int
+
+const (
+	moving state = iota
+	arrived
+	anchored
+)
+
+// vessel is what a Detector knows about a vessel.
+type vessel struct {
+	name        string
+	destination string
+	eta         string
+
+	// positionTime and staticdataTime are the times of the latest position and static data of the vessel
+	positionTime   time.Time
+	staticdataTime time.Time
+
+	state state
+	area  *Area
+	call  int
+
+	// candidate is the first message of a possible change of state, if any
+	candidate *ais.Vessel
+}
+
+// Detector detects port calls and anchorages.
+//
+// A vessel is stopped when its speed over ground is at most StopSpeed, or it reports itself as moored or at anchor,
+// and it is moving when its speed is at least MoveSpeed and it does not. Speeds in between keep the vessel in the
+// state it was in, so that noise does not make it flicker between the two. A stop is reported when the vessel has
+// been stopped for DwellTime, and a departure when it has been moving for DwellTime or has left the area it stopped
+// in.
+//
+// Positions of each vessel must be given in order of time, and so must its static data, but not the two together: a
+// late position is ignored only once a later position is known, and late static data only once later static data is.
+//
+// A Detector must be constructed with the NewDetector factory function. It is not safe for concurrent use, and its
+// fields must be set before it is used.
+type Detector struct {
+	areas   []Area
+	vessels map[int]*vessel
+	calls   []PortCall
+
+	// StopSpeed is the highest speed over ground of stopped vessels, in knots. Zero means DefaultStopSpeed.
+	StopSpeed float64
+	// MoveSpeed is the lowest speed over ground of moving vessels, in knots. Zero means DefaultMoveSpeed.
+	MoveSpeed float64
+	// DwellTime is how long a vessel must stay stopped or moving before it is reported. Zero means DefaultDwellTime.
+	DwellTime time.Duration
+}
+
+// NewDetector creates a Detector for the areas. Where areas overlap, the first of them is used.
+func NewDetector(areas []Area) *Detector {
+	return &Detector{
+		areas:   areas,
+		vessels: make(map[int]*vessel),
+	}
+}
+
+func (d *Detector) area(lon, lat float64) *Area {
+	for i := range d.areas {
+		if d.areas[i].Contains(lon, lat) {
+			return &d.areas[i]
+		}
+	}
+	return nil
+}
+
+func (d *Detector) stopped(v ais.Vessel) bool {
+	if v.NavigationalStatus != nil && (*v.NavigationalStatus == navigationalStatusAtAnchor ||
+		*v.NavigationalStatus == navigationalStatusMoored) {
+		return true
+	}
+	return v.SpeedOverGround != nil && *v.SpeedOverGround <= cmp.Or(d.StopSpeed, DefaultStopSpeed)
+}
+
+func (d *Detector) moving(v ais.Vessel) bool {
+	if v.NavigationalStatus != nil && (*v.NavigationalStatus == navigationalStatusAtAnchor ||
+		*v.NavigationalStatus == navigationalStatusMoored) {
+		return false
+	}
+	return v.SpeedOverGround != nil && *v.SpeedOverGround >= cmp.Or(d.MoveSpeed, DefaultMoveSpeed)
+}
+
+// Add updates what the Detector knows about the vessel, and returns the events detected, if any. Static data updates
+// the name, destination and ETA of the vessel, and positions its state.
+func (d *Detector) Add(v ais.Vessel) []Event {
+	vs, ok := d.vessels[v.Mmsi]
+	if !ok {
+		vs = &vessel{call: -1}
+		d.vessels[v.Mmsi] = vs
+	}
+	if (v.Name != "" || v.Destination != "" || v.Eta != "") && !v.Msgtime.Before(vs.staticdataTime) {
+		vs.staticdataTime = v.Msgtime
+		if v.Name != "" {
+			vs.name = v.Name
+		}
+		if v.Destination != "" {
+			vs.destination = v.Destination
+		}
+		if v.Eta != "" {
+			vs.eta = v.Eta
+		}
+	}
+	if v.Latitude == nil || v.Longitude == nil || v.Msgtime.Before(vs.positionTime) {
+		return nil
+	}
+	vs.positionTime = v.Msgtime
+
+	dwell := cmp.Or(d.DwellTime, DefaultDwellTime)
+	switch vs.state {
+	case moving:
+		if d.moving(v) {
+			vs.candidate = nil
+			return nil
+		}
+		if !d.stopped(v) {
+			return nil
+		}
+		if vs.candidate == nil {
+			vs.candidate = &v
+		}
+		if v.Msgtime.Sub(vs.candidate.Msgtime) < dwell {
+			return nil
+		}
+		return d.stop(vs, v)
+
+	default:
+		if !d.moving(v) {
+			if d.stopped(v) {
+				vs.candidate = nil
+			}
+			return nil
+		}
+		if vs.candidate == nil {
+			vs.candidate = &v
+		}
+		left := vs.area != nil && !vs.area.Contains(*v.Longitude, *v.Latitude)
+		if !left && v.Msgtime.Sub(vs.candidate.Msgtime) < dwell {
+			return nil
+		}
+		return d.depart(vs)
+	}
+}
+
+// stop reports the vessel as stopped, if it stopped in a known area or reports itself as moored or at anchor.
+func (d *Detector) stop(vs *vessel, v ais.Vessel) []Event {
+	start := *vs.candidate
+	area := d.area(*start.Longitude, *start.Latitude)
+	var typ EventType
+	switch {
+	case area != nil && area.Kind == Port:
+		typ = Arrival
+	case area != nil && area.Kind == Anchorage:
+		typ = Anchored
+	case v.NavigationalStatus != nil && *v.NavigationalStatus == navigationalStatusMoored:
+		typ = Arrival
+	case v.NavigationalStatus != nil && *v.NavigationalStatus == navigationalStatusAtAnchor:
+		typ = Anchored
+	default:
+		// Drifting, or waiting outside the known areas
+		return nil
+	}
+
+	vs.state, vs.area, vs.candidate = arrived, area, nil
+	if typ == Anchored {
+		vs.state = anchored
+	}
+	ev := d.event(typ, v.Mmsi, vs, start)
+	vs.call = len(d.calls)
+	d.calls = append(d.calls, PortCall{
+		Mmsi:        ev.Mmsi,
+		Name:        ev.Name,
+		Area:        area,
+		Anchored:    typ == Anchored,
+		Start:       ev.Time,
+		Destination: ev.Destination,
+		Eta:         ev.Eta,
+	})
+	return []Event{ev}
+}
+
+// depart reports the vessel as moving again.
+func (d *Detector) depart(vs *vessel) []Event {
+	start := *vs.candidate
+	typ := Departure
+	if vs.state == anchored {
+		typ = Underway
+	}
+	ev := d.event(typ, start.Mmsi, vs, start)
+
+	d.calls[vs.call].End = ev.Time
+	vs.state, vs.area, vs.candidate, vs.call = moving, nil, nil, -1
+	return []Event{ev}
+}
+
+func (d *Detector) event(typ EventType, mmsi int, vs *vessel, at ais.Vessel) Event {
+	ev := Event{
+		Type:        typ,
+		Mmsi:        mmsi,
+		Name:        vs.name,
+		Time:        at.Msgtime,
+		Longitude:   *at.Longitude,
+		Latitude:    *at.Latitude,
+		Area:        vs.area,
+		Destination: vs.destination,
+	}
+	if eta, ok := ParseEta(vs.eta, at.Msgtime); ok {
+		ev.Eta = eta
+	}
+	ev.DestinationMatch = ev.Area != nil && ev.Area.Matches(ev.Destination)
+	return ev
+}
+
+// Calls returns the log of port calls and anchorages, in the order they started. Those still in progress have a
+// zero End.
+func (d *Detector) Calls() []PortCall {
+	return slices.Clone(d.calls)
+}
+
+// Detect adds every message received from in to the Detector, and sends the events detected. The channel of events
+// is closed when in is closed or the context is cancelled. It accepts the channel of StreamResponse.UnmarshalStream
+// directly, as well as channels of any of the combined types.
+func Detect[T interface{ Vessel() ais.Vessel }](ctx context.Context, d *Detector, in <-chan T) <-chan Event {
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-in:
+				if !ok {
+					return
+				}
+				for _, ev := range d.Add(msg.Vessel()) {
+					select {
+					case out <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// PortCall is a stay of a vessel in a port or an anchorage.
+type PortCall struct {
+	Mmsi int
+	Name string
+	// Area is the port or anchorage, or nil if the vessel stopped outside the known areas.
+	Area *Area
+	// Anchored is true if the vessel anchored, rather than arrived.
+	Anchored bool
+	// Start is when the vessel stopped, and End when it started moving again, or the zero time if it has not.
+	Start time.Time
+	End   time.Time
+	// Destination and Eta are the destination and ETA the vessel reported when it stopped.
+	Destination string
+	Eta         time.Time
+}
+
+// Duration returns the duration of the stay, or zero if it is still in progress.
+func (c PortCall) Duration() time.Duration {
+	if c.End.IsZero() {
+		return 0
+	}
+	return c.End.Sub(c.Start)
+}
+
+// CSVColumns are the names of the columns written by WriteCSV, in order.
+var CSVColumns = []string{"mmsi", "name", "area", "kind", "start", "end", "duration", "destination", "eta"}
+
+// WriteCSV writes the port calls as CSV, with a header of CSVColumns. Times are written in RFC 3339 format, durations
+// in seconds, and unknown values as empty cells.
+func WriteCSV(w io.Writer, calls []PortCall) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVColumns); err != nil {
+		return err
+	}
+
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	record := make([]string, len(CSVColumns))
+	for _, c := range calls {
+		area, kind, duration := "", Port.String(), ""
+		if c.Area != nil {
+			area = c.Area.Name
+		}
+		if c.Anchored {
+			kind = Anchorage.String()
+		}
+		if !c.End.IsZero() {
+			duration = strconv.FormatFloat(c.Duration().Seconds(), 'f', -1, 64)
+		}
+		record = append(record[:0],
+			strconv.Itoa(c.Mmsi),
+			c.Name,
+			area,
+			kind,
+			formatTime(c.Start),
+			formatTime(c.End),
+			duration,
+			c.Destination,
+			formatTime(c.Eta),
+		)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ParseEta parses an ETA in the format of static data, MMDDhhmm in UTC without a year, as the first such time which
+// is at most half a year before the reference time. ETAs in which the month or day are not available (zero) are not
+// parsed, while hours and minutes which are not available (24 and 60) are taken as zero.
+func ParseEta(eta string, reference time.Time) (time.Time, bool) {
+	if len(eta) != 8 {
+		return time.Time{}, false
+	}
+	var parts [4]int
+	for i := range parts {
+		n, err := strconv.Atoi(eta[2*i : 2*i+2])
+		if err != nil {
+			return time.Time{}, false
+		}
+		parts[i] = n
+	}
+	month, day, hour, minute := parts[0], parts[1], parts[2], parts[3]
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 24 || minute > 60 {
+		return time.Time{}, false
+	}
+	if hour == 24 {
+		hour = 0
+	}
+	if minute == 60 {
+		minute = 0
+	}
+
+	reference = reference.UTC()
+	earliest := reference.AddDate(0, -6, 0)
+	for year := earliest.Year(); year <= earliest.Year()+1; year++ {
+		t := time.Date(year, time.Month(month), day, hour, minute, 0, 0, time.UTC)
+		if t.Day() != day {
+			// Not a valid date in this year, such as 29 February
+			continue
+		}
+		if !t.Before(earliest) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
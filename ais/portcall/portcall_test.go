@@ -0,0 +1,172 @@
+package portcall_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/aistest"
+	"github.com/ilder-as/go-barentswatch-ais/ais/portcall"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func square(lon, lat, size float64) *geojson.Geometry {
+	return geojson.NewPolygonGeometry([][][]float64{{
+		{lon, lat}, {lon + size, lat}, {lon + size, lat + size}, {lon, lat + size}, {lon, lat},
+	}})
+}
+
+var areas = []portcall.Area{
+	{Name: "Tromsø", Aliases: []string{"NOTOS"}, Kind: portcall.Port, Geometry: square(18.9, 69.6, 0.1)},
+	{Name: "Tromsø anchorage", Kind: portcall.Anchorage, Geometry: square(19.1, 69.6, 0.1)},
+}
+
+// moored and atAnchor set the navigational status of position reports.
+func moored(p ais.Position) ais.Position {
+	p.NavigationalStatus = 5
+	return p
+}
+
+func atAnchor(p ais.Position) ais.Position {
+	p.NavigationalStatus = 1
+	return p
+}
+
+func TestDetector(t *testing.T) {
+	d := portcall.NewDetector(areas)
+	static := aistest.Staticdata(1, 0, "NORDSTJERNEN")
+	static.Destination, static.Eta = "NO TOS", "03010200"
+	msgs := []aistest.Message{
+		static,
+		// Arrives in port, and stops for an hour, with a brief speed-up which is noise
+		aistest.Position(1, 0, 18.5, 69.65, 12),
+		aistest.Position(1, 10*time.Minute, 18.95, 69.65, 0.2),
+		aistest.Position(1, 20*time.Minute, 18.95, 69.65, 1),
+		moored(aistest.Position(1, 30*time.Minute, 18.95, 69.65, 0.1)),
+		moored(aistest.Position(1, 70*time.Minute, 18.95, 69.65, 0.1)),
+		// Leaves port for the anchorage, and is reported as departed as soon as it is outside the port
+		aistest.Position(1, 75*time.Minute, 18.98, 69.65, 5),
+		aistest.Position(1, 80*time.Minute, 19.05, 69.65, 5),
+		atAnchor(aistest.Position(1, 85*time.Minute, 19.15, 69.65, 0.3)),
+		atAnchor(aistest.Position(1, 110*time.Minute, 19.15, 69.65, 0.3)),
+		// Gets underway, and is reported once it has been moving for the dwell time
+		aistest.Position(1, 120*time.Minute, 19.16, 69.65, 8),
+		aistest.Position(1, 130*time.Minute, 19.18, 69.65, 8),
+		aistest.Position(1, 140*time.Minute, 19.25, 69.65, 8),
+		// Out of order, and ignored
+		atAnchor(aistest.Position(1, 100*time.Minute, 19.15, 69.65, 0)),
+		// Drifts at sea without reporting itself as moored or at anchor, which is not a stop
+		aistest.Position(2, 0, 5, 60, 0.1),
+		aistest.Position(2, 60*time.Minute, 5, 60, 0.1),
+		// Moored outside the known areas
+		moored(aistest.Position(3, 0, 5, 60, 0)),
+		moored(aistest.Position(3, 30*time.Minute, 5, 60, 0)),
+	}
+
+	var events []portcall.Event
+	for ev := range portcall.Detect(context.Background(), d, aistest.Stream(msgs)) {
+		events = append(events, ev)
+	}
+
+	want := []struct {
+		typ     portcall.EventType
+		mmsi    int
+		minutes int
+		area    string
+	}{
+		{portcall.Arrival, 1, 10, "Tromsø"},
+		{portcall.Departure, 1, 75, "Tromsø"},
+		{portcall.Anchored, 1, 85, "Tromsø anchorage"},
+		{portcall.Underway, 1, 120, "Tromsø anchorage"},
+		{portcall.Arrival, 3, 0, ""},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), events)
+	}
+	for i, w := range want {
+		ev := events[i]
+		area := ""
+		if ev.Area != nil {
+			area = ev.Area.Name
+		}
+		if ev.Type != w.typ || ev.Mmsi != w.mmsi || !ev.Time.Equal(aistest.Start.Add(time.Duration(w.minutes)*time.Minute)) ||
+			area != w.area {
+			t.Errorf("expected %s of %d at %d minutes in %q, got %s of %d at %v in %q", w.typ, w.mmsi, w.minutes,
+				w.area, ev.Type, ev.Mmsi, ev.Time, area)
+		}
+	}
+	if ev := events[0]; !ev.DestinationMatch || ev.Name != "NORDSTJERNEN" ||
+		!ev.Eta.Equal(aistest.Start.Add(2*time.Hour)) {
+		t.Errorf("expected the arrival to match the destination and ETA, got %+v", ev)
+	}
+	if events[2].DestinationMatch {
+		t.Error("expected the anchorage not to match the destination")
+	}
+
+	calls := d.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 port calls, got %+v", calls)
+	}
+	if calls[0].Duration() != 65*time.Minute || calls[1].Duration() != 35*time.Minute || !calls[1].Anchored {
+		t.Errorf("unexpected port calls %+v", calls)
+	}
+	if calls[2].Duration() != 0 || !calls[2].End.IsZero() {
+		t.Errorf("expected the last port call in progress, got %+v", calls[2])
+	}
+
+	var buf bytes.Buffer
+	if err := portcall.WriteCSV(&buf, calls); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || records[1][2] != "Tromsø" || records[1][6] != "3900" || records[2][3] != "Anchorage" {
+		t.Errorf("unexpected records %v", records)
+	}
+}
+
+func TestDetector_Order(t *testing.T) {
+	d := portcall.NewDetector(areas)
+	var events []portcall.Event
+	for _, msg := range []aistest.Message{
+		aistest.Staticdata(1, time.Hour, "NORDSTJERNEN"),
+		aistest.Staticdata(1, 10*time.Minute, "NORDLYS"),
+		moored(aistest.Position(1, 0, 18.95, 69.65, 0)),
+		moored(aistest.Position(1, 30*time.Minute, 18.95, 69.65, 0)),
+	} {
+		events = append(events, d.Add(msg.Vessel())...)
+	}
+	if len(events) != 1 || events[0].Type != portcall.Arrival || !events[0].Time.Equal(aistest.Start) {
+		t.Fatalf("expected an arrival at the first position, got %+v", events)
+	}
+	if events[0].Name != "NORDSTJERNEN" {
+		t.Errorf("expected the older static data to be ignored, got %q", events[0].Name)
+	}
+}
+
+func TestParseEta(t *testing.T) {
+	reference := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		eta  string
+		want time.Time
+		ok   bool
+	}{
+		{"12241830", time.Date(2024, 12, 24, 18, 30, 0, 0, time.UTC), true},
+		{"01042460", time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC), true},
+		{"11010000", time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC), true},
+		{"02290000", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC), false},
+		{"00002460", time.Time{}, false},
+		{"1224", time.Time{}, false},
+	}
+	for _, test := range tests {
+		got, ok := portcall.ParseEta(test.eta, reference)
+		if ok != test.ok || (ok && !got.Equal(test.want)) {
+			t.Errorf("%s: expected %v, %t, got %v, %t", test.eta, test.want, test.ok, got, ok)
+		}
+	}
+}
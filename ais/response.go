@@ -2,13 +2,17 @@ package ais
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"net/http"
 	"reflect"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/ilder-as/go-barentswatch-ais/responsetype"
@@ -30,6 +34,9 @@ func IsEOF(err error) bool {
 	return errors.Is(err, eof)
 }
 
+// ErrStalled is the reason a stream ends when no data has been received within its IdleTimeout.
+var ErrStalled = errors.New("stream stalled")
+
 type CancelFunc func()
 
 // Response is an API response which contains the given T data type.
@@ -44,14 +51,112 @@ func (r Response[T]) Unmarshal() (T, error) {
 	return obj, json.NewDecoder(r.Body).Decode(&obj)
 }
 
+// Elements returns an iterator over the elements of a response whose body is a JSON array, such as the response from
+// GetLatestAis. Unlike Response.Unmarshal, the elements are decoded one by one as they are consumed, so the whole
+// result set is never held in memory.
+//
+// If an error is encountered, it is yielded together with a zero-valued T as the final element. The response body is
+// closed when iteration ends, including when the caller breaks out of the loop.
+func Elements[T any](r Response[[]T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer r.Body.Close()
+
+		var zero T
+		dec := json.NewDecoder(r.Body)
+
+		tok, err := dec.Token()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		if tok == nil {
+			// A null body is an empty result set
+			return
+		}
+		if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+			yield(zero, fmt.Errorf("expected JSON array, got %v", tok))
+			return
+		}
+
+		for dec.More() {
+			var obj T
+			if err := dec.Decode(&obj); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(obj, nil) {
+				return
+			}
+		}
+
+		if _, err := dec.Token(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
 // StreamResponse is an API response whose body is a continuous stream of data.
 //
 // A StreamResponse can be consumed using the `UnmarshalStream` method.
 type StreamResponse[T any] struct {
 	*http.Response
+
+	// IdleTimeout is the maximum time to wait for data before the stream is considered stalled, in which case the
+	// connection is closed and the stream ends with ErrStalled. Only time spent waiting for the server counts, not time
+	// spent by the consumer between messages. Zero means no timeout. It must be set before the stream is consumed.
+	IdleTimeout time.Duration
+
+	// MaxLineSize is the maximum size of a single line in the stream, including the line terminator. Zero means
+	// bufio.MaxScanTokenSize (64 KiB). A buffer of this size is allocated when the stream is consumed. It must be set
+	// before the stream is consumed.
+	MaxLineSize int
+
+	// LinePolicy decides what happens when a line is too long or cannot be decoded. It must be set before the stream
+	// is consumed.
+	LinePolicy LinePolicy
+
+	// OnLineError, if set, is called with every line skipped due to the SkipLine policy.
+	OnLineError func(err *LineError)
+
+	// Strict makes messages with fields which are not part of the type they are decoded into fail to decode, so that
+	// changes to the API's schemas are detected. Such messages are handled according to LinePolicy. It must be set
+	// before the stream is consumed.
+	Strict bool
+
 	streamType StreamType
-	err        error
-	ctx        context.Context
+	// combinedType is the type of the messages of a CombinedMultiple stream, as requested by the filter. If empty, the
+	// type is deduced from every message.
+	combinedType responsetype.Combined
+	err          error
+	ctx          context.Context
+}
+
+// LinePolicy decides what happens when a line in a stream is too long or cannot be decoded.
+type LinePolicy int
+
+const (
+	// AbortStream ends the stream, with a *LineError as the reason.
+	AbortStream LinePolicy = iota
+	// SkipLine skips the line, reports it to the stream's OnLineError callback, and continues with the next line.
+	SkipLine
+)
+
+// LineError is an error concerning a single line in a stream, e.g. a line which is too long or cannot be decoded.
+type LineError struct {
+	// Line is the number of the line in the stream, starting at 1.
+	Line int
+	// Data is the content of the line, or nil if the line was too long.
+	Data []byte
+	// Err is the underlying error, e.g. bufio.ErrTooLong or a *json.SyntaxError.
+	Err error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
 }
 
 // Error returns the underlying error or reason when a stream ends.
@@ -59,94 +164,222 @@ func (r *StreamResponse[T]) Error() error {
 	return r.err
 }
 
-// unmarshalDefault unmarshals streaming data sent as individual json objects
-func (r *StreamResponse[T]) unmarshalDefault() (<-chan T, error) {
-	scan := bufio.NewScanner(r.Body)
-	if scan == nil {
-		return nil, errors.New("received nil scanner")
+// decode decodes a single line of the stream. If the line carries no data, such as blank lines and comments between
+// Server Sent Events, skip is true.
+func (r *StreamResponse[T]) decode(line []byte) (res T, skip bool, err error) {
+	switch r.streamType {
+	case Simple:
+		err = r.unmarshal(line, &res)
+		return res, false, err
+	case SSE:
+		res, err = r.unmarshalSSEData(line)
+		if errors.Is(err, empty) || errors.Is(err, noMatch) {
+			return res, true, nil
+		}
+		return res, false, err
+	default:
+		return res, false, errors.New("unknown stream type")
 	}
+}
 
-	out := make(chan T)
-
-	go func() {
+// All returns an iterator over the objects in the stream.
+//
+// Iteration ends when the stream ends, or when an error is encountered, in which case the error is yielded together
+// with a zero-valued T as the final element. The end of the stream is not yielded as an error, but is reported by
+// StreamResponse.Error like any other reason for the stream ending. The underlying connection is closed when
+// iteration ends, including when the caller breaks out of the loop. To continue consuming data, another api call must
+// be made to get a new StreamResponse.
+//
+//	for msg, err := range stream.All() {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(msg)
+//	}
+func (r *StreamResponse[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
 		defer r.Body.Close()
-		defer close(out)
 
+		var zero T
+		fail := func(err error) {
+			r.err = err
+			yield(zero, err)
+		}
+
+		w := r.watch()
+		defer w.stop()
+
+		lines := newLineReader(w, r.MaxLineSize)
 		for {
-			select {
-			case <-r.ctx.Done():
-				r.err = r.ctx.Err()
+			if err := r.ctx.Err(); err != nil {
+				fail(err)
+				return
+			}
+
+			line, err := lines.next()
+			if reason := w.reason(); err != nil && reason != nil {
+				// The read failed because the watchdog closed the body
+				fail(reason)
+				return
+			} else if err == io.EOF {
+				r.err = eof
+				return
+			} else if err != nil && !errors.Is(err, bufio.ErrTooLong) {
+				fail(err)
 				return
-			default:
-				success := scan.Scan()
-				if !success {
-					if err := scan.Err(); err != nil {
-						r.err = err
-					} else {
-						r.err = eof
+			}
+
+			var res T
+			var skip bool
+			if err == nil {
+				res, skip, err = r.decode(line)
+			}
+			if skip {
+				continue
+			} else if err != nil {
+				lineErr := &LineError{Line: lines.n, Data: append([]byte(nil), line...), Err: err}
+				if r.LinePolicy == SkipLine {
+					if r.OnLineError != nil {
+						r.OnLineError(lineErr)
 					}
-					return
+					continue
 				}
+				fail(lineErr)
+				return
+			}
 
-				var res T
-				if err := json.Unmarshal(scan.Bytes(), &res); err != nil {
-					r.err = err
-					return
-				} else {
-					out <- res
-				}
+			if !yield(res, nil) {
+				return
 			}
 		}
-	}()
+	}
+}
 
-	return out, nil
+// lineReader reads lines of limited size. Unlike bufio.Scanner, it can recover from lines which are too long by
+// discarding them.
+type lineReader struct {
+	r *bufio.Reader
+	// n is the number of the line last read, starting at 1
+	n int
 }
 
-// unmarshalSSE unmarshals streaming data sent as Server Sent Events (SSE)
-func (r *StreamResponse[T]) unmarshalSSE() (<-chan T, error) {
-	scan := bufio.NewScanner(r.Body)
-	if scan == nil {
-		return nil, errors.New("received nil scanner")
+func newLineReader(r io.Reader, maxLineSize int) *lineReader {
+	if maxLineSize <= 0 {
+		maxLineSize = bufio.MaxScanTokenSize
 	}
+	return &lineReader{r: bufio.NewReaderSize(r, maxLineSize)}
+}
 
-	out := make(chan T)
+// next returns the next line, without the line terminator. The line is only valid until the next call to next. If
+// the line is too long, the remainder of the line is discarded, and bufio.ErrTooLong is returned. io.EOF is returned
+// when there are no more lines.
+func (l *lineReader) next() ([]byte, error) {
+	line, err := l.r.ReadSlice('\n')
+	if len(line) == 0 && err != nil {
+		return nil, err
+	}
+	l.n++
 
-	go func() {
-		defer r.Body.Close()
-		defer close(out)
+	if errors.Is(err, bufio.ErrBufferFull) {
+		for errors.Is(err, bufio.ErrBufferFull) {
+			_, err = l.r.ReadSlice('\n')
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return nil, bufio.ErrTooLong
+	} else if err != nil && err != io.EOF {
+		return nil, err
+	}
 
-		for {
-			select {
-			case <-r.ctx.Done():
-				r.err = r.ctx.Err()
-				return
-			default:
-				success := scan.Scan()
-				if !success {
-					if err := scan.Err(); err != nil {
-						r.err = err
-					} else {
-						r.err = eof
-					}
-					return
-				}
+	line = bytes.TrimSuffix(line, []byte("\n"))
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	return line, nil
+}
+
+// watchdog reads from the body of a stream, and closes it when the stream's context is cancelled or a read has been
+// blocked waiting for data for longer than the stream's idle timeout. Closing the body interrupts a blocked read. Time
+// spent by the consumer between reads is not counted, so that slow consumers do not stall the stream.
+type watchdog struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	stopCtx func() bool
+
+	mu  sync.Mutex
+	err error
+	// deadline is when the read in progress stalls, or zero if no read is in progress
+	deadline time.Time
+}
+
+// watch starts a watchdog for the stream. The watchdog must be stopped when the stream is no longer read.
+func (r *StreamResponse[T]) watch() *watchdog {
+	w := &watchdog{body: r.Body, timeout: r.IdleTimeout}
+	w.stopCtx = context.AfterFunc(r.ctx, func() {
+		w.abort(r.ctx.Err())
+	})
+	if w.timeout > 0 {
+		w.timer = time.AfterFunc(w.timeout, w.stall)
+		w.timer.Stop()
+	}
+	return w
+}
 
-				var res T
-				var err error
-				res, err = unmarshalSSEData[T](scan.Bytes())
-				if errors.Is(err, empty) || errors.Is(err, noMatch) {
-					continue
-				} else if err != nil {
-					r.err = err
-					return
-				} else {
-					out <- res
-				}
-			}
-		}
-	}()
+// Read reads from the body, timing out if the read is blocked for longer than the idle timeout.
+func (w *watchdog) Read(p []byte) (int, error) {
+	if w.timer == nil {
+		return w.body.Read(p)
+	}
+	w.mu.Lock()
+	w.deadline = time.Now().Add(w.timeout)
+	w.mu.Unlock()
+	w.timer.Reset(w.timeout)
+	n, err := w.body.Read(p)
+	w.mu.Lock()
+	w.deadline = time.Time{}
+	w.mu.Unlock()
+	w.timer.Stop()
+	return n, err
+}
+
+// stall aborts the stream as stalled if the read in progress has been blocked for longer than the idle timeout. The
+// timer may fire after the read it was started for has returned, or while the next read is in progress, in which case
+// the stream is not stalled.
+func (w *watchdog) stall() {
+	w.mu.Lock()
+	if w.deadline.IsZero() || time.Now().Before(w.deadline) {
+		w.mu.Unlock()
+		return
+	}
+	if w.err == nil {
+		w.err = ErrStalled
+	}
+	w.mu.Unlock()
+	w.body.Close()
+}
 
-	return out, nil
+// abort records the reason and closes the body. Only the first reason is recorded.
+func (w *watchdog) abort(reason error) {
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = reason
+	}
+	w.mu.Unlock()
+	w.body.Close()
+}
+
+// reason returns the reason the watchdog closed the body, or nil if it has not.
+func (w *watchdog) reason() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *watchdog) stop() {
+	w.stopCtx()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
 }
 
 // UnmarshalStream unmarshals a stream of serialized data into the underlying data structure.
@@ -157,13 +390,30 @@ func (r *StreamResponse[T]) unmarshalSSE() (<-chan T, error) {
 // to get a new StreamResponse.
 func (r *StreamResponse[T]) UnmarshalStream() (<-chan T, error) {
 	switch r.streamType {
-	case Simple:
-		return r.unmarshalDefault()
-	case SSE:
-		return r.unmarshalSSE()
+	case Simple, SSE:
 	default:
 		return nil, errors.New("unknown stream type")
 	}
+
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for res, err := range r.All() {
+			if err != nil {
+				return
+			}
+			select {
+			case out <- res:
+			case <-r.ctx.Done():
+				r.err = r.ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return out, nil
 }
 
 var (
@@ -171,21 +421,40 @@ var (
 	empty   = errors.New("empty")
 )
 
+// sseData matches the data lines of Server Sent Events.
+var sseData = regexp.MustCompile(`^data:\s+(\{.*\})\s*$`)
+
+// unmarshal unmarshals a single message of the stream.
+func (r *StreamResponse[T]) unmarshal(data []byte, res *T) error {
+	switch v := any(res).(type) {
+	case *CombinedMultiple:
+		return v.unmarshalAs(data, r.combinedType, r.Strict)
+	case *AisMultiple:
+		if r.Strict {
+			return v.unmarshalStrict(data)
+		}
+	default:
+		if r.Strict {
+			return unmarshalStrict(data, res)
+		}
+	}
+	return unmarshal(data, res)
+}
+
 // unmarshalSSEData unmarshals an SSE data stream
-func unmarshalSSEData[T any](raw []byte) (T, error) {
+func (r *StreamResponse[T]) unmarshalSSEData(raw []byte) (T, error) {
 	var res T
 
 	if len(raw) == 0 {
 		return res, empty
 	}
 
-	re := regexp.MustCompile(`^data:\s+(\{.*\})\s*$`)
-	match := re.FindSubmatch(raw)
+	match := sseData.FindSubmatch(raw)
 	if len(match) <= 1 {
 		return res, noMatch
 	}
 
-	err := json.Unmarshal(match[1], &res)
+	err := r.unmarshal(match[1], &res)
 	if err != nil {
 		return res, err
 	}
@@ -193,6 +462,19 @@ func unmarshalSSEData[T any](raw []byte) (T, error) {
 	return res, nil
 }
 
+// unmarshalStrict unmarshals data into v like json.Unmarshal, but fails if data has fields which are not part of v.
+func unmarshalStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if rest := bytes.TrimSpace(data[dec.InputOffset():]); len(rest) > 0 {
+		return fmt.Errorf("unexpected data after message: %.20q", rest)
+	}
+	return nil
+}
+
 // AisMultiple holds a union of the multiple response types that an AIS data request can return.
 // Use the Type property to inspect which type the message is.
 type AisMultiple struct {
@@ -202,7 +484,17 @@ type AisMultiple struct {
 	Staticdata
 }
 
+// UnmarshalJSON unmarshals the supplied JSON data into an AisMultiple.
 func (a *AisMultiple) UnmarshalJSON(data []byte) error {
+	if a.unmarshalFast(data) {
+		return nil
+	}
+	return a.unmarshalJSON(data)
+}
+
+// unmarshalJSON unmarshals the supplied JSON data into an AisMultiple using encoding/json. It handles the messages the
+// single pass decoder gives up on.
+func (a *AisMultiple) unmarshalJSON(data []byte) error {
 	typ := struct {
 		Type responsetype.Ais `json:"type"`
 	}{}
@@ -224,6 +516,39 @@ func (a *AisMultiple) UnmarshalJSON(data []byte) error {
 	}
 }
 
+// unmarshalStrict is like unmarshalJSON, but fails if data has fields which are not part of the type of the message.
+func (a *AisMultiple) unmarshalStrict(data []byte) error {
+	typ := struct {
+		Type responsetype.Ais `json:"type"`
+	}{}
+	if err := json.Unmarshal(data, &typ); err != nil {
+		return err
+	}
+
+	a.Type = typ.Type
+
+	// The messages are decoded into structs which embed the concrete types, and allow the "type" field
+	switch a.Type {
+	case responsetype.Position:
+		return unmarshalStrict(data, &struct {
+			Type string `json:"type"`
+			*Position
+		}{Position: &a.Position})
+	case responsetype.Aton:
+		return unmarshalStrict(data, &struct {
+			Type string `json:"type"`
+			*Aton
+		}{Aton: &a.Aton})
+	case responsetype.Staticdata:
+		return unmarshalStrict(data, &struct {
+			Type string `json:"type"`
+			*Staticdata
+		}{Staticdata: &a.Staticdata})
+	default:
+		return fmt.Errorf("unknown type: %s", a.Type)
+	}
+}
+
 // IsZero is true iff the receiver is a default-valued AisMultiple struct.
 func (a AisMultiple) IsZero() bool {
 	return reflect.ValueOf(a).IsZero()
@@ -334,6 +659,15 @@ type CombinedMultiple struct {
 
 // UnmarshalJSON unmarshals the supplied JSON data into a CombinedMultiple.
 func (c *CombinedMultiple) UnmarshalJSON(data []byte) error {
+	if c.unmarshalFast(data) {
+		return nil
+	}
+	return c.unmarshalJSON(data)
+}
+
+// unmarshalJSON unmarshals the supplied JSON data into a CombinedMultiple using encoding/json. It handles the messages
+// the single pass decoder gives up on.
+func (c *CombinedMultiple) unmarshalJSON(data []byte) error {
 	keys := make(map[string]json.RawMessage)
 	if err := json.Unmarshal(data, &keys); err != nil {
 		return err
@@ -376,6 +710,52 @@ func (c *CombinedMultiple) UnmarshalJSON(data []byte) error {
 	}
 }
 
+// UnmarshalJSONAs unmarshals the supplied JSON data into the given type, rather than deducing the type from the data
+// like UnmarshalJSON does. If strict is true, fields in the data which are not part of the type are an error.
+func (c *CombinedMultiple) UnmarshalJSONAs(data []byte, typ responsetype.Combined, strict bool) error {
+	return c.unmarshalAs(data, typ, strict)
+}
+
+// unmarshalAs implements UnmarshalJSONAs, and deduces the type from the data if typ is empty.
+func (c *CombinedMultiple) unmarshalAs(data []byte, typ responsetype.Combined, strict bool) error {
+	if !strict {
+		if c.unmarshalFastAs(data, typ) {
+			return nil
+		}
+		if typ == "" {
+			return c.unmarshalJSON(data)
+		}
+	}
+
+	if typ == "" {
+		var probe CombinedMultiple
+		if err := probe.UnmarshalJSON(data); err != nil {
+			return err
+		}
+		typ = probe.Type
+	}
+
+	var v any
+	switch typ {
+	case responsetype.FullJson:
+		v = &c.CombinedFullJson
+	case responsetype.SimpleJson:
+		v = &c.CombinedSimpleJson
+	case responsetype.FullGeojson:
+		v = &c.CombinedFullGeojson
+	case responsetype.SimpleGeojson:
+		v = &c.CombinedSimpleGeojson
+	default:
+		return fmt.Errorf("unknown type: %s", typ)
+	}
+
+	c.Type = typ
+	if strict {
+		return unmarshalStrict(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
 // IsZero is true iff the receiver is a default-valued CombinedMultiple struct.
 func (c CombinedMultiple) IsZero() bool {
 	return reflect.ValueOf(c).IsZero()
@@ -1,6 +1,7 @@
 package ais
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/ilder-as/go-barentswatch-ais/countrycode"
@@ -71,3 +72,45 @@ type ApiError struct {
 	Status  int    `json:"status"`
 	TraceId string `json:"traceId"`
 }
+
+// shipTypeCodes marshals ship types to JSON as numeric codes, as the API expects in filters, rather than as the names
+// ship types marshal to by default.
+type shipTypeCodes []shiptype.ShipType
+
+func (s shipTypeCodes) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("null"), nil
+	}
+	codes := make([]int, len(s))
+	for i, t := range s {
+		codes[i] = int(t)
+	}
+	return json.Marshal(codes)
+}
+
+// MarshalJSON implements json.Marshaler, encoding ship types as the numeric codes the API expects.
+func (f CombinedFilterInput) MarshalJSON() ([]byte, error) {
+	type combinedFilterInput CombinedFilterInput
+	return json.Marshal(struct {
+		combinedFilterInput
+		ShipTypes shipTypeCodes `json:"shipTypes"`
+	}{combinedFilterInput(f), shipTypeCodes(f.ShipTypes)})
+}
+
+// MarshalJSON implements json.Marshaler, encoding ship types as the numeric codes the API expects.
+func (f FilterInput) MarshalJSON() ([]byte, error) {
+	type filterInput FilterInput
+	return json.Marshal(struct {
+		filterInput
+		ShipTypes shipTypeCodes `json:"shipTypes"`
+	}{filterInput(f), shipTypeCodes(f.ShipTypes)})
+}
+
+// MarshalJSON implements json.Marshaler, encoding ship types as the numeric codes the API expects.
+func (f LatestAisFilterInput) MarshalJSON() ([]byte, error) {
+	type latestAisFilterInput LatestAisFilterInput
+	return json.Marshal(struct {
+		latestAisFilterInput
+		ShipTypes shipTypeCodes `json:"shipTypes"`
+	}{latestAisFilterInput(f), shipTypeCodes(f.ShipTypes)})
+}
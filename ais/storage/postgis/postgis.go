@@ -0,0 +1,660 @@
+// Package postgis is an archive of AIS messages in PostgreSQL with PostGIS, for production archives shared by many
+// applications. The Store implements storage.Store.
+//
+// Messages are stored in tables partitioned by day, which are created as messages arrive and dropped as a whole when
+// they are pruned. Positions are stored as geography points, with spatial indexes for queries both by area and by
+// distance, and messages are deduplicated on their MMSI, Msgtime and MessageType. Messages are inserted in batches
+// with COPY.
+//
+// The schema is created by Open, and requires the postgis extension, which Open creates if the user is allowed to.
+// The tables are:
+//
+//   - ais_positions, ais_staticdata and ais_atons, which are the messages
+//   - ais_vessels, which is the current static data of every vessel
+package postgis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/storage"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const schema = `
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+CREATE TABLE IF NOT EXISTS ais_positions (
+	mmsi integer NOT NULL,
+	msgtime timestamptz NOT NULL,
+	message_type smallint NOT NULL,
+	altitude integer,
+	position geography(Point, 4326),
+	course_over_ground double precision,
+	ais_class text NOT NULL,
+	navigational_status smallint NOT NULL,
+	rate_of_turn double precision,
+	speed_over_ground double precision,
+	true_heading smallint,
+	PRIMARY KEY (mmsi, msgtime, message_type)
+) PARTITION BY RANGE (msgtime);
+CREATE INDEX IF NOT EXISTS ais_positions_msgtime ON ais_positions (msgtime);
+CREATE INDEX IF NOT EXISTS ais_positions_position ON ais_positions USING gist (position);
+CREATE INDEX IF NOT EXISTS ais_positions_geometry ON ais_positions USING gist ((position::geometry));
+
+CREATE TABLE IF NOT EXISTS ais_staticdata (
+	mmsi integer NOT NULL,
+	msgtime timestamptz NOT NULL,
+	message_type smallint NOT NULL,
+	name text NOT NULL,
+	dimension_a integer,
+	dimension_b integer,
+	dimension_c integer,
+	dimension_d integer,
+	imo_number integer,
+	call_sign text NOT NULL,
+	destination text NOT NULL,
+	eta text NOT NULL,
+	draught integer,
+	ship_length integer,
+	ship_width integer,
+	ship_type integer,
+	position_fixing_device_type integer NOT NULL,
+	report_class text NOT NULL,
+	PRIMARY KEY (mmsi, msgtime, message_type)
+) PARTITION BY RANGE (msgtime);
+CREATE INDEX IF NOT EXISTS ais_staticdata_msgtime ON ais_staticdata (msgtime);
+
+CREATE TABLE IF NOT EXISTS ais_atons (
+	mmsi integer NOT NULL,
+	msgtime timestamptz NOT NULL,
+	message_type smallint NOT NULL,
+	position geography(Point, 4326),
+	name text NOT NULL,
+	dimension_a integer,
+	dimension_b integer,
+	dimension_c integer,
+	dimension_d integer,
+	type_of_aids_to_navigation integer NOT NULL,
+	type_of_electronic_fixing_device integer NOT NULL,
+	PRIMARY KEY (mmsi, msgtime, message_type)
+) PARTITION BY RANGE (msgtime);
+CREATE INDEX IF NOT EXISTS ais_atons_msgtime ON ais_atons (msgtime);
+CREATE INDEX IF NOT EXISTS ais_atons_geometry ON ais_atons USING gist ((position::geometry));
+
+CREATE TABLE IF NOT EXISTS ais_vessels (
+	mmsi integer PRIMARY KEY,
+	msgtime timestamptz NOT NULL,
+	message_type smallint NOT NULL,
+	name text NOT NULL,
+	dimension_a integer,
+	dimension_b integer,
+	dimension_c integer,
+	dimension_d integer,
+	imo_number integer,
+	call_sign text NOT NULL,
+	destination text NOT NULL,
+	eta text NOT NULL,
+	draught integer,
+	ship_length integer,
+	ship_width integer,
+	ship_type integer,
+	position_fixing_device_type integer NOT NULL,
+	report_class text NOT NULL
+);
+`
+
+// table describes how messages are loaded into a table. Messages are copied into a temporary table of the load
+// columns, and inserted from there, so that duplicates can be skipped and coordinates turned into geography points.
+type table struct {
+	name string
+	// load is the columns of the temporary table, with their types
+	load string
+	// columns is the columns of the table, and values the expressions of them in terms of the load columns
+	columns string
+	values  string
+}
+
+var (
+	positions = table{
+		name: "ais_positions",
+		load: `mmsi integer, msgtime timestamptz, message_type smallint, altitude integer, longitude double precision,
+latitude double precision, course_over_ground double precision, ais_class text, navigational_status smallint,
+rate_of_turn double precision, speed_over_ground double precision, true_heading smallint`,
+		columns: `mmsi, msgtime, message_type, altitude, position, course_over_ground, ais_class, navigational_status,
+rate_of_turn, speed_over_ground, true_heading`,
+		values: `mmsi, msgtime, message_type, altitude, ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography,
+course_over_ground, ais_class, navigational_status, rate_of_turn, speed_over_ground, true_heading`,
+	}
+	staticdata = table{
+		name: "ais_staticdata",
+		load: `mmsi integer, msgtime timestamptz, message_type smallint, name text, dimension_a integer,
+dimension_b integer, dimension_c integer, dimension_d integer, imo_number integer, call_sign text, destination text,
+eta text, draught integer, ship_length integer, ship_width integer, ship_type integer,
+position_fixing_device_type integer, report_class text`,
+		columns: staticdataColumns,
+		values:  staticdataColumns,
+	}
+	atons = table{
+		name: "ais_atons",
+		load: `mmsi integer, msgtime timestamptz, message_type smallint, longitude double precision,
+latitude double precision, name text, dimension_a integer, dimension_b integer, dimension_c integer,
+dimension_d integer, type_of_aids_to_navigation integer, type_of_electronic_fixing_device integer`,
+		columns: `mmsi, msgtime, message_type, position, name, dimension_a, dimension_b, dimension_c, dimension_d,
+type_of_aids_to_navigation, type_of_electronic_fixing_device`,
+		values: `mmsi, msgtime, message_type, ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography, name,
+dimension_a, dimension_b, dimension_c, dimension_d, type_of_aids_to_navigation, type_of_electronic_fixing_device`,
+	}
+)
+
+const staticdataColumns = `mmsi, msgtime, message_type, name, dimension_a, dimension_b, dimension_c, dimension_d,
+imo_number, call_sign, destination, eta, draught, ship_length, ship_width, ship_type, position_fixing_device_type,
+report_class`
+
+// loadColumns returns the names of the load columns of the table.
+func (t table) loadColumns() []string {
+	var res []string
+	for _, column := range strings.Split(t.load, ",") {
+		res = append(res, strings.Fields(column)[0])
+	}
+	return res
+}
+
+func (t table) loadTable() string {
+	return "load_" + strings.TrimPrefix(t.name, "ais_")
+}
+
+// copy copies the rows into a temporary table, which is dropped when the transaction ends.
+func (t table) copy(ctx context.Context, tx pgx.Tx, rows [][]any) error {
+	_, err := tx.Exec(ctx, "CREATE TEMPORARY TABLE "+t.loadTable()+" ("+t.load+") ON COMMIT DROP")
+	if err != nil {
+		return err
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{t.loadTable()}, t.loadColumns(), pgx.CopyFromRows(rows))
+	return err
+}
+
+// insert inserts the copied rows into the table, skipping duplicates, and returns the number of rows inserted.
+func (t table) insert(ctx context.Context, tx pgx.Tx) (int64, error) {
+	tag, err := tx.Exec(ctx, "INSERT INTO "+t.name+" ("+t.columns+") SELECT "+t.values+" FROM "+t.loadTable()+
+		" ON CONFLICT DO NOTHING")
+	return tag.RowsAffected(), err
+}
+
+var _ storage.Store = (*Store)(nil)
+
+// Store is an archive of AIS messages in PostgreSQL with PostGIS.
+//
+// A Store must be constructed with the Open factory function. It is safe for concurrent use, but its fields must be
+// set before it is used.
+type Store struct {
+	pool *pgxpool.Pool
+
+	// mu guards partitions, which are the partitions known to exist
+	mu         sync.Mutex
+	partitions map[string]bool
+
+	// Retention is how long messages are kept. Partitions of days older than Retention are dropped by Prune and by
+	// Ingest, and older messages in the oldest remaining partition are deleted. Zero keeps messages forever.
+	Retention time.Duration
+	// BatchSize is the largest number of messages Ingest stores in one transaction. Zero means
+	// storage.DefaultBatchSize.
+	BatchSize int
+	// FlushInterval is the longest time Ingest holds messages before storing them. Zero means
+	// storage.DefaultFlushInterval.
+	FlushInterval time.Duration
+}
+
+// Open connects to the database, as described by a PostgreSQL connection string or URL, and creates the tables if
+// they do not exist.
+func Open(ctx context.Context, connString string) (*Store, error) {
+	pool, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pool.Exec(ctx, schema); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("postgis: creating schema: %w", err)
+	}
+	return &Store{pool: pool, partitions: make(map[string]bool)}, nil
+}
+
+// Close closes the connections to the database.
+func (s *Store) Close() error {
+	s.pool.Close()
+	return nil
+}
+
+// Pool returns the underlying connection pool, for queries which the Store does not support.
+func (s *Store) Pool() *pgxpool.Pool {
+	return s.pool
+}
+
+// partition returns the name of the partition of the table holding the day of t.
+func partition(table string, t time.Time) string {
+	return table + "_p" + t.UTC().Format("20060102")
+}
+
+// partitionDay returns the day of the partition of the table, or false if the name is not a partition of it.
+func partitionDay(table, name string) (time.Time, bool) {
+	day, ok := strings.CutPrefix(name, table+"_p")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse("20060102", day)
+	return t, err == nil
+}
+
+// ensurePartitions creates the partitions of the table for the days of the times, if they do not exist.
+func (s *Store) ensurePartitions(ctx context.Context, table string, times []time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range times {
+		name := partition(table, t)
+		if s.partitions[name] {
+			continue
+		}
+		day := t.UTC().Truncate(24 * time.Hour)
+		_, err := s.pool.Exec(ctx, fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			name, table, day.Format(time.RFC3339), day.Add(24*time.Hour).Format(time.RFC3339)))
+		if err != nil {
+			return fmt.Errorf("postgis: creating partition %s: %w", name, err)
+		}
+		s.partitions[name] = true
+	}
+	return nil
+}
+
+func staticdataRow(d ais.Staticdata) []any {
+	return []any{
+		d.Mmsi, d.Msgtime.Truncate(storage.TimePrecision), d.MessageType,
+		d.Name, d.DimensionA, d.DimensionB, d.DimensionC, d.DimensionD,
+		d.ImoNumber, d.CallSign, d.Destination, d.Eta, d.Draught, d.ShipLength, d.ShipWidth, d.ShipType,
+		d.PositionFixingDeviceType, d.ReportClass,
+	}
+}
+
+// upsertVessels stores the latest of the copied static data of every vessel in ais_vessels, unless newer static data
+// is already stored.
+func upsertVessels(ctx context.Context, tx pgx.Tx) error {
+	var set []string
+	for _, column := range strings.Split(staticdataColumns, ",") {
+		column = strings.TrimSpace(column)
+		set = append(set, column+" = excluded."+column)
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO ais_vessels ("+staticdataColumns+") SELECT DISTINCT ON (mmsi) "+
+		staticdataColumns+" FROM "+staticdata.loadTable()+" ORDER BY mmsi, msgtime DESC "+
+		"ON CONFLICT (mmsi) DO UPDATE SET "+strings.Join(set, ", ")+" WHERE excluded.msgtime >= ais_vessels.msgtime")
+	return err
+}
+
+// Append stores the messages in a single transaction, and returns the number of messages which were not already
+// stored. Static data messages also update the static data of their vessels.
+func (s *Store) Append(ctx context.Context, msgs ...ais.AisMultiple) (int, error) {
+	rows := make(map[*table][][]any)
+	times := make(map[*table][]time.Time)
+	for _, msg := range msgs {
+		var t *table
+		var row []any
+		switch msg.Type {
+		case responsetype.Position:
+			p := msg.Position
+			t = &positions
+			row = []any{
+				p.Mmsi, p.Msgtime.Truncate(storage.TimePrecision), p.MessageType,
+				p.Altitude, p.Longitude, p.Latitude, p.CourseOverGround,
+				p.AisClass, p.NavigationalStatus, p.RateOfTurn, p.SpeedOverGround, p.TrueHeading,
+			}
+			times[t] = append(times[t], p.Msgtime)
+		case responsetype.Staticdata:
+			t = &staticdata
+			row = staticdataRow(msg.Staticdata)
+			times[t] = append(times[t], msg.Staticdata.Msgtime)
+		case responsetype.Aton:
+			a := msg.Aton
+			t = &atons
+			row = []any{
+				a.Mmsi, a.Msgtime.Truncate(storage.TimePrecision), a.MessageType,
+				a.Longitude, a.Latitude, a.Name, a.DimensionA, a.DimensionB, a.DimensionC, a.DimensionD,
+				a.TypeOfAidsToNavigation, a.TypeOfElectronicFixingDevice,
+			}
+			times[t] = append(times[t], a.Msgtime)
+		default:
+			continue
+		}
+		rows[t] = append(rows[t], row)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	for t, times := range times {
+		if err := s.ensurePartitions(ctx, t.name, times); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var n int64
+	for t, rows := range rows {
+		if err := t.copy(ctx, tx, rows); err != nil {
+			return 0, err
+		}
+		inserted, err := t.insert(ctx, tx)
+		if err != nil {
+			return 0, err
+		}
+		n += inserted
+		if t == &staticdata {
+			if err := upsertVessels(ctx, tx); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return int(n), tx.Commit(ctx)
+}
+
+// UpsertVessels stores the static data as the current static data of their vessels, unless newer static data is
+// already stored.
+func (s *Store) UpsertVessels(ctx context.Context, data ...ais.Staticdata) error {
+	if len(data) == 0 {
+		return nil
+	}
+	rows := make([][]any, len(data))
+	for i, d := range data {
+		rows[i] = staticdataRow(d)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := staticdata.copy(ctx, tx, rows); err != nil {
+		return err
+	}
+	if err := upsertVessels(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Ingest stores every message received from in with storage.Ingest, in batches of BatchSize messages at least every
+// FlushInterval. It accepts the channel of StreamResponse.UnmarshalStream directly.
+func (s *Store) Ingest(ctx context.Context, in <-chan ais.AisMultiple) error {
+	return storage.Ingest(ctx, s, in, s.BatchSize, s.FlushInterval)
+}
+
+// Prune deletes messages older than Retention, and returns the number of messages deleted. It deletes nothing if
+// Retention is zero. The current static data of vessels is kept.
+func (s *Store) Prune(ctx context.Context) (int64, error) {
+	if s.Retention <= 0 {
+		return 0, nil
+	}
+	return s.PruneBefore(ctx, time.Now().Add(-s.Retention))
+}
+
+// PruneBefore deletes messages older than t, and returns the number of messages deleted. Partitions of days before
+// the day of t are dropped, and older messages of the day of t are deleted.
+func (s *Store) PruneBefore(ctx context.Context, t time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := t.UTC().Truncate(24 * time.Hour)
+	var n int64
+	for _, table := range []string{positions.name, staticdata.name, atons.name} {
+		rows, err := s.pool.Query(ctx, `SELECT c.relname FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid
+JOIN pg_class p ON p.oid = i.inhparent
+WHERE p.relname = $1`, table)
+		if err != nil {
+			return n, err
+		}
+		names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return n, err
+		}
+
+		for _, name := range names {
+			if partitionDay, ok := partitionDay(table, name); !ok || !partitionDay.Before(day) {
+				continue
+			}
+			var count int64
+			if err := s.pool.QueryRow(ctx, "SELECT count(*) FROM "+name).Scan(&count); err != nil {
+				return n, err
+			}
+			if _, err := s.pool.Exec(ctx, "DROP TABLE "+name); err != nil {
+				return n, err
+			}
+			delete(s.partitions, name)
+			n += count
+		}
+
+		tag, err := s.pool.Exec(ctx, "DELETE FROM "+table+" WHERE msgtime < $1", t)
+		if err != nil {
+			return n, err
+		}
+		n += tag.RowsAffected()
+	}
+	return n, nil
+}
+
+// conditions builds the WHERE clause of a query, numbering the arguments as PostgreSQL requires.
+type conditions struct {
+	where []string
+	args  []any
+}
+
+func (c *conditions) add(condition string, args ...any) {
+	for _, arg := range args {
+		c.args = append(c.args, arg)
+		condition = strings.Replace(condition, "?", "$"+strconv.Itoa(len(c.args)), 1)
+	}
+	c.where = append(c.where, condition)
+}
+
+func (c *conditions) String() string {
+	if len(c.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.where, " AND ")
+}
+
+// filter adds the conditions for the vessels and time range of the query on the table aliased t.
+func (c *conditions) filter(q storage.Query) {
+	if len(q.Mmsi) > 0 {
+		c.add("t.mmsi = ANY(?)", q.Mmsi)
+	}
+	if !q.From.IsZero() {
+		c.add("t.msgtime >= ?", q.From)
+	}
+	if !q.To.IsZero() {
+		c.add("t.msgtime < ?", q.To)
+	}
+}
+
+// area adds the conditions for the area of the query on the position of the table aliased t.
+func (c *conditions) area(q storage.Query) error {
+	if q.Bounds != nil {
+		b := q.Bounds
+		c.add("t.position::geometry && ST_MakeEnvelope(?, ?, ?, ?, 4326)", b.MinLon, b.MinLat, b.MaxLon, b.MaxLat)
+	}
+	if q.Geometry != nil {
+		geometry, err := json.Marshal(q.Geometry)
+		if err != nil {
+			return err
+		}
+		c.add("ST_Covers(ST_SetSRID(ST_GeomFromGeoJSON(?), 4326), t.position::geometry)", string(geometry))
+	}
+	return nil
+}
+
+func limit(q storage.Query) string {
+	if q.Limit > 0 {
+		return " LIMIT " + strconv.Itoa(q.Limit)
+	}
+	return ""
+}
+
+const positionSelect = `t.mmsi, t.msgtime, t.message_type, t.altitude, ST_X(t.position::geometry),
+ST_Y(t.position::geometry), t.course_over_ground, t.ais_class, t.navigational_status, t.rate_of_turn,
+t.speed_over_ground, t.true_heading`
+
+func positionDest(p *ais.Position) []any {
+	return []any{&p.Mmsi, &p.Msgtime, &p.MessageType, &p.Altitude, &p.Longitude, &p.Latitude, &p.CourseOverGround,
+		&p.AisClass, &p.NavigationalStatus, &p.RateOfTurn, &p.SpeedOverGround, &p.TrueHeading}
+}
+
+func staticdataDest(d *ais.Staticdata) []any {
+	return []any{&d.Mmsi, &d.Msgtime, &d.MessageType, &d.Name, &d.DimensionA, &d.DimensionB, &d.DimensionC,
+		&d.DimensionD, &d.ImoNumber, &d.CallSign, &d.Destination, &d.Eta, &d.Draught, &d.ShipLength, &d.ShipWidth,
+		&d.ShipType, &d.PositionFixingDeviceType, &d.ReportClass}
+}
+
+// query runs the query, and scans every row with scan.
+func query[T any](ctx context.Context, pool *pgxpool.Pool, sql string, args []any, scan func(*T) []any) ([]T, error) {
+	rows, err := pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []T
+	for rows.Next() {
+		var v T
+		if err := rows.Scan(scan(&v)...); err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, rows.Err()
+}
+
+// Positions returns the positions selected by the query, ordered by time and MMSI.
+func (s *Store) Positions(ctx context.Context, q storage.Query) ([]ais.Position, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	var c conditions
+	c.filter(q)
+	if err := c.area(q); err != nil {
+		return nil, err
+	}
+
+	res, err := query(ctx, s.pool, "SELECT "+positionSelect+" FROM "+positions.name+" t"+c.String()+
+		" ORDER BY t.msgtime, t.mmsi"+limit(q), c.args, positionDest)
+	for i := range res {
+		res[i].Msgtime = res[i].Msgtime.UTC()
+	}
+	return res, err
+}
+
+// Staticdata returns the static data selected by the query, ordered by time and MMSI. Static data has no position,
+// so it is not limited by the area of the query.
+func (s *Store) Staticdata(ctx context.Context, q storage.Query) ([]ais.Staticdata, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	var c conditions
+	c.filter(q)
+
+	var columns []string
+	for _, column := range strings.Split(staticdataColumns, ",") {
+		columns = append(columns, "t."+strings.TrimSpace(column))
+	}
+	res, err := query(ctx, s.pool, "SELECT "+strings.Join(columns, ", ")+" FROM "+staticdata.name+" t"+c.String()+
+		" ORDER BY t.msgtime, t.mmsi"+limit(q), c.args, staticdataDest)
+	for i := range res {
+		res[i].Msgtime = res[i].Msgtime.UTC()
+	}
+	return res, err
+}
+
+// Atons returns the aids to navigation selected by the query, ordered by time and MMSI.
+func (s *Store) Atons(ctx context.Context, q storage.Query) ([]ais.Aton, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	var c conditions
+	c.filter(q)
+	if err := c.area(q); err != nil {
+		return nil, err
+	}
+
+	res, err := query(ctx, s.pool, `SELECT t.mmsi, t.msgtime, t.message_type, ST_X(t.position::geometry),
+ST_Y(t.position::geometry), t.name, t.dimension_a, t.dimension_b, t.dimension_c, t.dimension_d,
+t.type_of_aids_to_navigation, t.type_of_electronic_fixing_device FROM `+atons.name+" t"+c.String()+
+		" ORDER BY t.msgtime, t.mmsi"+limit(q), c.args, func(a *ais.Aton) []any {
+		return []any{&a.Mmsi, &a.Msgtime, &a.MessageType, &a.Longitude, &a.Latitude, &a.Name, &a.DimensionA,
+			&a.DimensionB, &a.DimensionC, &a.DimensionD, &a.TypeOfAidsToNavigation, &a.TypeOfElectronicFixingDevice}
+	})
+	for i := range res {
+		res[i].Msgtime = res[i].Msgtime.UTC()
+	}
+	return res, err
+}
+
+// Snapshot returns the vessels selected by the query, ordered by MMSI, each with its latest position in the time
+// range of the query and its current static data.
+func (s *Store) Snapshot(ctx context.Context, q storage.Query) ([]ais.Vessel, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The latest positions are found first, and then matched with the area
+	var latest, area conditions
+	latest.filter(q)
+	area.args = latest.args
+	if err := area.area(q); err != nil {
+		return nil, err
+	}
+
+	type row struct {
+		p ais.Position
+		d ais.Staticdata
+		// msgtime is the time of the static data, if any
+		msgtime *time.Time
+	}
+	rows, err := query(ctx, s.pool, `SELECT `+positionSelect+`, v.msgtime, coalesce(v.message_type, 0),
+coalesce(v.name, ''), v.dimension_a, v.dimension_b, v.dimension_c, v.dimension_d, v.imo_number,
+coalesce(v.call_sign, ''), coalesce(v.destination, ''), coalesce(v.eta, ''), v.draught, v.ship_length, v.ship_width,
+v.ship_type, coalesce(v.position_fixing_device_type, 0), coalesce(v.report_class, '')
+FROM (SELECT DISTINCT ON (t.mmsi) * FROM `+positions.name+` t`+latest.String()+` ORDER BY t.mmsi, t.msgtime DESC) t
+LEFT JOIN ais_vessels v ON v.mmsi = t.mmsi`+area.String()+`
+ORDER BY t.mmsi`+limit(q), area.args, func(r *row) []any {
+		d := &r.d
+		return append(positionDest(&r.p), &r.msgtime, &d.MessageType, &d.Name, &d.DimensionA, &d.DimensionB,
+			&d.DimensionC, &d.DimensionD, &d.ImoNumber, &d.CallSign, &d.Destination, &d.Eta, &d.Draught, &d.ShipLength,
+			&d.ShipWidth, &d.ShipType, &d.PositionFixingDeviceType, &d.ReportClass)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]ais.Vessel, len(rows))
+	for i, r := range rows {
+		r.p.Msgtime = r.p.Msgtime.UTC()
+		if r.msgtime != nil {
+			r.d.Mmsi = r.p.Mmsi
+			r.d.Msgtime = r.msgtime.UTC()
+		}
+		res[i] = ais.NewVessel(r.p, r.d)
+	}
+	return res, nil
+}
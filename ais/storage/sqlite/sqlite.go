@@ -0,0 +1,532 @@
+// Package sqlite is an archive of AIS messages in an embedded SQLite database, for keeping history on a single machine
+// without running a database server. It uses a pure Go build of SQLite, so it does not need cgo.
+//
+// Positions are indexed by an R*-tree, so that queries by area are fast. Messages are deduplicated on their MMSI,
+// Msgtime and MessageType, so the same message can safely be stored more than once, such as when a stream is
+// restarted with a Since time in the past. The Store implements storage.Store.
+//
+//	store, err := sqlite.Open("ais.db")
+//	// ...
+//	defer store.Close()
+//	store.Retention = 14 * 24 * time.Hour
+//
+//	res, err := client.GetAisContext(ctx)
+//	// ...
+//	ch, err := res.UnmarshalStream()
+//	// ...
+//	err = store.Ingest(ctx, ch)
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/storage"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS positions (
+	id INTEGER PRIMARY KEY,
+	mmsi INTEGER NOT NULL,
+	msgtime INTEGER NOT NULL,
+	message_type INTEGER NOT NULL,
+	altitude INTEGER,
+	longitude REAL,
+	latitude REAL,
+	course_over_ground REAL,
+	ais_class TEXT NOT NULL,
+	navigational_status INTEGER NOT NULL,
+	rate_of_turn REAL,
+	speed_over_ground REAL,
+	true_heading INTEGER,
+	UNIQUE (mmsi, msgtime, message_type)
+);
+CREATE INDEX IF NOT EXISTS positions_msgtime ON positions (msgtime);
+CREATE VIRTUAL TABLE IF NOT EXISTS positions_rtree USING rtree (id, min_lon, max_lon, min_lat, max_lat);
+CREATE TRIGGER IF NOT EXISTS positions_insert AFTER INSERT ON positions
+WHEN new.longitude IS NOT NULL AND new.latitude IS NOT NULL
+BEGIN
+	INSERT INTO positions_rtree VALUES (new.id, new.longitude, new.longitude, new.latitude, new.latitude);
+END;
+CREATE TRIGGER IF NOT EXISTS positions_delete AFTER DELETE ON positions
+BEGIN
+	DELETE FROM positions_rtree WHERE id = old.id;
+END;
+
+CREATE TABLE IF NOT EXISTS staticdata (
+	id INTEGER PRIMARY KEY,
+	mmsi INTEGER NOT NULL,
+	msgtime INTEGER NOT NULL,
+	message_type INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	dimension_a INTEGER,
+	dimension_b INTEGER,
+	dimension_c INTEGER,
+	dimension_d INTEGER,
+	imo_number INTEGER,
+	call_sign TEXT NOT NULL,
+	destination TEXT NOT NULL,
+	eta TEXT NOT NULL,
+	draught INTEGER,
+	ship_length INTEGER,
+	ship_width INTEGER,
+	ship_type INTEGER,
+	position_fixing_device_type INTEGER NOT NULL,
+	report_class TEXT NOT NULL,
+	UNIQUE (mmsi, msgtime, message_type)
+);
+CREATE INDEX IF NOT EXISTS staticdata_msgtime ON staticdata (msgtime);
+
+CREATE TABLE IF NOT EXISTS vessels (
+	mmsi INTEGER PRIMARY KEY,
+	msgtime INTEGER NOT NULL,
+	message_type INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	dimension_a INTEGER,
+	dimension_b INTEGER,
+	dimension_c INTEGER,
+	dimension_d INTEGER,
+	imo_number INTEGER,
+	call_sign TEXT NOT NULL,
+	destination TEXT NOT NULL,
+	eta TEXT NOT NULL,
+	draught INTEGER,
+	ship_length INTEGER,
+	ship_width INTEGER,
+	ship_type INTEGER,
+	position_fixing_device_type INTEGER NOT NULL,
+	report_class TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS atons (
+	id INTEGER PRIMARY KEY,
+	mmsi INTEGER NOT NULL,
+	msgtime INTEGER NOT NULL,
+	message_type INTEGER NOT NULL,
+	longitude REAL,
+	latitude REAL,
+	name TEXT NOT NULL,
+	dimension_a INTEGER,
+	dimension_b INTEGER,
+	dimension_c INTEGER,
+	dimension_d INTEGER,
+	type_of_aids_to_navigation INTEGER NOT NULL,
+	type_of_electronic_fixing_device INTEGER NOT NULL,
+	UNIQUE (mmsi, msgtime, message_type)
+);
+CREATE INDEX IF NOT EXISTS atons_msgtime ON atons (msgtime);
+`
+
+const (
+	positionColumns = `mmsi, msgtime, message_type, altitude, longitude, latitude, course_over_ground, ais_class,
+navigational_status, rate_of_turn, speed_over_ground, true_heading`
+	staticdataColumns = `mmsi, msgtime, message_type, name, dimension_a, dimension_b, dimension_c, dimension_d,
+imo_number, call_sign, destination, eta, draught, ship_length, ship_width, ship_type, position_fixing_device_type,
+report_class`
+	atonColumns = `mmsi, msgtime, message_type, longitude, latitude, name, dimension_a, dimension_b, dimension_c,
+dimension_d, type_of_aids_to_navigation, type_of_electronic_fixing_device`
+)
+
+// snapshot selects the latest position of every vessel selected by the conditions on t, along with the static data
+// of the vessel from v, if any.
+const snapshot = `
+SELECT t.mmsi, t.msgtime, t.message_type, t.altitude, t.longitude, t.latitude, t.course_over_ground, t.ais_class,
+	t.navigational_status, t.rate_of_turn, t.speed_over_ground, t.true_heading,
+	v.msgtime, coalesce(v.message_type, 0), coalesce(v.name, ''), v.dimension_a, v.dimension_b, v.dimension_c,
+	v.dimension_d, v.imo_number, coalesce(v.call_sign, ''), coalesce(v.destination, ''), coalesce(v.eta, ''),
+	v.draught, v.ship_length, v.ship_width, v.ship_type, coalesce(v.position_fixing_device_type, 0),
+	coalesce(v.report_class, '')
+FROM (SELECT t.mmsi, max(t.msgtime) AS msgtime FROM positions t%s GROUP BY t.mmsi) l
+JOIN positions t ON t.mmsi = l.mmsi AND t.msgtime = l.msgtime
+LEFT JOIN vessels v ON v.mmsi = t.mmsi%s
+ORDER BY t.mmsi`
+
+var _ storage.Store = (*Store)(nil)
+
+// Store is an archive of AIS messages in a SQLite database.
+//
+// A Store must be constructed with the Open factory function. It is safe for concurrent use, but its fields must be
+// set before it is used.
+type Store struct {
+	db *sql.DB
+
+	// Retention is how long messages are kept. Messages older than Retention are deleted by Prune and by Ingest.
+	// Zero keeps messages forever.
+	Retention time.Duration
+	// BatchSize is the largest number of messages Ingest stores in one transaction. Zero means
+	// storage.DefaultBatchSize.
+	BatchSize int
+	// FlushInterval is the longest time Ingest holds messages before storing them. Zero means
+	// storage.DefaultFlushInterval.
+	FlushInterval time.Duration
+}
+
+// Open opens the SQLite database at path, creating it and its tables if they do not exist. The path ":memory:"
+// opens a database which lives only as long as the Store.
+func Open(path string) (*Store, error) {
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if path == ":memory:" {
+		// Every connection to an in-memory database has a database of its own
+		db.SetMaxOpenConns(1)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite: creating schema: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DB returns the underlying database, for queries which the Store does not support.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// insert prepares a statement which inserts a row of the columns into the table, and does the conflict action.
+func insert(ctx context.Context, tx *sql.Tx, table, columns, conflict string) (*sql.Stmt, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", strings.Count(columns, ",")+1), ", ")
+	return tx.PrepareContext(ctx, "INSERT INTO "+table+" ("+columns+") VALUES ("+placeholders+") "+conflict)
+}
+
+// upsertVessel prepares a statement which stores static data in the vessels table, unless it is older than the
+// static data already stored.
+func upsertVessel(ctx context.Context, tx *sql.Tx) (*sql.Stmt, error) {
+	var set []string
+	for _, column := range strings.Split(staticdataColumns, ",") {
+		column = strings.TrimSpace(column)
+		set = append(set, column+" = excluded."+column)
+	}
+	return insert(ctx, tx, "vessels", staticdataColumns,
+		"ON CONFLICT (mmsi) DO UPDATE SET "+strings.Join(set, ", ")+" WHERE excluded.msgtime >= vessels.msgtime")
+}
+
+// storedTime returns the time of a message as stored, in nanoseconds since the epoch at storage.TimePrecision.
+func storedTime(t time.Time) int64 {
+	return t.Truncate(storage.TimePrecision).UnixNano()
+}
+
+func staticdataArgs(d ais.Staticdata) []any {
+	return []any{d.Mmsi, storedTime(d.Msgtime), d.MessageType, d.Name, d.DimensionA, d.DimensionB, d.DimensionC,
+		d.DimensionD, d.ImoNumber, d.CallSign, d.Destination, d.Eta, d.Draught, d.ShipLength, d.ShipWidth, d.ShipType,
+		d.PositionFixingDeviceType, d.ReportClass}
+}
+
+// Append stores the messages in a single transaction, and returns the number of messages which were not already
+// stored. Static data messages also update the static data of their vessels.
+func (s *Store) Append(ctx context.Context, msgs ...ais.AisMultiple) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	positions, err := insert(ctx, tx, "positions", positionColumns, "ON CONFLICT DO NOTHING")
+	if err != nil {
+		return 0, err
+	}
+	staticdata, err := insert(ctx, tx, "staticdata", staticdataColumns, "ON CONFLICT DO NOTHING")
+	if err != nil {
+		return 0, err
+	}
+	atons, err := insert(ctx, tx, "atons", atonColumns, "ON CONFLICT DO NOTHING")
+	if err != nil {
+		return 0, err
+	}
+	vessels, err := upsertVessel(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, msg := range msgs {
+		var res sql.Result
+		switch msg.Type {
+		case responsetype.Position:
+			p := msg.Position
+			res, err = positions.ExecContext(ctx, p.Mmsi, storedTime(p.Msgtime), p.MessageType, p.Altitude, p.Longitude,
+				p.Latitude, p.CourseOverGround, p.AisClass, p.NavigationalStatus, p.RateOfTurn, p.SpeedOverGround,
+				p.TrueHeading)
+		case responsetype.Staticdata:
+			args := staticdataArgs(msg.Staticdata)
+			if _, err := vessels.ExecContext(ctx, args...); err != nil {
+				return 0, err
+			}
+			res, err = staticdata.ExecContext(ctx, args...)
+		case responsetype.Aton:
+			a := msg.Aton
+			res, err = atons.ExecContext(ctx, a.Mmsi, storedTime(a.Msgtime), a.MessageType, a.Longitude, a.Latitude,
+				a.Name, a.DimensionA, a.DimensionB, a.DimensionC, a.DimensionD, a.TypeOfAidsToNavigation,
+				a.TypeOfElectronicFixingDevice)
+		default:
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if affected, err := res.RowsAffected(); err == nil {
+			n += int(affected)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// UpsertVessels stores the static data as the current static data of their vessels, unless newer static data is
+// already stored.
+func (s *Store) UpsertVessels(ctx context.Context, data ...ais.Staticdata) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	vessels, err := upsertVessel(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, d := range data {
+		if _, err := vessels.ExecContext(ctx, staticdataArgs(d)...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Ingest stores every message received from in with storage.Ingest, in batches of BatchSize messages at least every
+// FlushInterval. It accepts the channel of StreamResponse.UnmarshalStream directly.
+func (s *Store) Ingest(ctx context.Context, in <-chan ais.AisMultiple) error {
+	return storage.Ingest(ctx, s, in, s.BatchSize, s.FlushInterval)
+}
+
+// Prune deletes messages older than Retention, and returns the number of messages deleted. It deletes nothing if
+// Retention is zero. The current static data of vessels is kept.
+func (s *Store) Prune(ctx context.Context) (int64, error) {
+	if s.Retention <= 0 {
+		return 0, nil
+	}
+	return s.PruneBefore(ctx, time.Now().Add(-s.Retention))
+}
+
+// PruneBefore deletes messages older than t, and returns the number of messages deleted.
+func (s *Store) PruneBefore(ctx context.Context, t time.Time) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var n int64
+	for _, table := range []string{"positions", "staticdata", "atons"} {
+		res, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE msgtime < ?", t.UnixNano())
+		if err != nil {
+			return 0, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		n += affected
+	}
+	return n, tx.Commit()
+}
+
+func scanPosition(rows *sql.Rows, dest ...any) (ais.Position, error) {
+	var p ais.Position
+	var msgtime int64
+	err := rows.Scan(append([]any{&p.Mmsi, &msgtime, &p.MessageType, &p.Altitude, &p.Longitude, &p.Latitude,
+		&p.CourseOverGround, &p.AisClass, &p.NavigationalStatus, &p.RateOfTurn, &p.SpeedOverGround, &p.TrueHeading},
+		dest...)...)
+	p.Msgtime = time.Unix(0, msgtime).UTC()
+	return p, err
+}
+
+func staticdataDest(d *ais.Staticdata) []any {
+	return []any{&d.MessageType, &d.Name, &d.DimensionA, &d.DimensionB, &d.DimensionC, &d.DimensionD, &d.ImoNumber,
+		&d.CallSign, &d.Destination, &d.Eta, &d.Draught, &d.ShipLength, &d.ShipWidth, &d.ShipType,
+		&d.PositionFixingDeviceType, &d.ReportClass}
+}
+
+// Positions returns the positions selected by the query, ordered by time and MMSI.
+func (s *Store) Positions(ctx context.Context, q storage.Query) ([]ais.Position, error) {
+	return query(ctx, s.db, q, "positions", positionColumns, true, func(rows *sql.Rows) (ais.Position, error) {
+		return scanPosition(rows)
+	}, func(p ais.Position) bool {
+		return q.ContainsPoint(p.Longitude, p.Latitude)
+	})
+}
+
+// Staticdata returns the static data selected by the query, ordered by time and MMSI. Static data has no position,
+// so it is not limited by the area of the query.
+func (s *Store) Staticdata(ctx context.Context, q storage.Query) ([]ais.Staticdata, error) {
+	q.Bounds, q.Geometry = nil, nil
+	return query(ctx, s.db, q, "staticdata", staticdataColumns, false, func(rows *sql.Rows) (ais.Staticdata, error) {
+		var d ais.Staticdata
+		var msgtime int64
+		err := rows.Scan(append([]any{&d.Mmsi, &msgtime}, staticdataDest(&d)...)...)
+		d.Msgtime = time.Unix(0, msgtime).UTC()
+		return d, err
+	}, nil)
+}
+
+// Atons returns the aids to navigation selected by the query, ordered by time and MMSI.
+func (s *Store) Atons(ctx context.Context, q storage.Query) ([]ais.Aton, error) {
+	return query(ctx, s.db, q, "atons", atonColumns, false, func(rows *sql.Rows) (ais.Aton, error) {
+		var a ais.Aton
+		var msgtime int64
+		err := rows.Scan(&a.Mmsi, &msgtime, &a.MessageType, &a.Longitude, &a.Latitude, &a.Name, &a.DimensionA,
+			&a.DimensionB, &a.DimensionC, &a.DimensionD, &a.TypeOfAidsToNavigation, &a.TypeOfElectronicFixingDevice)
+		a.Msgtime = time.Unix(0, msgtime).UTC()
+		return a, err
+	}, func(a ais.Aton) bool {
+		return q.ContainsPoint(a.Longitude, a.Latitude)
+	})
+}
+
+// Snapshot returns the vessels selected by the query, ordered by MMSI, each with its latest position in the time
+// range of the query and its current static data.
+func (s *Store) Snapshot(ctx context.Context, q storage.Query) ([]ais.Vessel, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The latest positions are found first, and then matched with the area
+	var latest, area string
+	where, args := filter(q)
+	if len(where) > 0 {
+		latest = " WHERE " + strings.Join(where, " AND ")
+	}
+	if b, ok := q.Area(); ok {
+		area = " WHERE t.longitude BETWEEN ? AND ? AND t.latitude BETWEEN ? AND ?"
+		args = append(args, b.MinLon, b.MaxLon, b.MinLat, b.MaxLat)
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(snapshot, latest, area), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []ais.Vessel
+	for rows.Next() {
+		var d ais.Staticdata
+		var msgtime sql.NullInt64
+		p, err := scanPosition(rows, append([]any{&msgtime}, staticdataDest(&d)...)...)
+		if err != nil {
+			return nil, err
+		}
+		// Messages of different types may share the latest time of a vessel
+		if len(res) > 0 && res[len(res)-1].Mmsi == p.Mmsi || !q.ContainsPoint(p.Longitude, p.Latitude) {
+			continue
+		}
+		if msgtime.Valid {
+			d.Mmsi = p.Mmsi
+			d.Msgtime = time.Unix(0, msgtime.Int64).UTC()
+		}
+		res = append(res, ais.NewVessel(p, d))
+		if q.Limit > 0 && len(res) == q.Limit {
+			break
+		}
+	}
+	return res, rows.Err()
+}
+
+// filter returns the conditions on the table aliased t for the vessels and time range of the query.
+func filter(q storage.Query) (where []string, args []any) {
+	if len(q.Mmsi) > 0 {
+		where = append(where, "t.mmsi IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(q.Mmsi)), ", ")+")")
+		for _, mmsi := range q.Mmsi {
+			args = append(args, mmsi)
+		}
+	}
+	if !q.From.IsZero() {
+		where = append(where, "t.msgtime >= ?")
+		args = append(args, q.From.UnixNano())
+	}
+	if !q.To.IsZero() {
+		where = append(where, "t.msgtime < ?")
+		args = append(args, q.To.UnixNano())
+	}
+	return where, args
+}
+
+// query runs the query against the table. The area of the query is matched with the R*-tree index of the table, if
+// rtree is set, and with the bounding box of the area otherwise; keep, if not nil, refines the match. The limit of
+// the query is applied in SQL only when there is no refinement to apply after it.
+func query[T any](ctx context.Context, db *sql.DB, q storage.Query, table, columns string, rtree bool,
+	scan func(*sql.Rows) (T, error), keep func(T) bool) ([]T, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	var from string
+	var where []string
+	var args []any
+	if area, ok := q.Area(); ok {
+		if rtree {
+			from = " JOIN " + table + "_rtree r ON r.id = t.id AND r.min_lon <= ? AND r.max_lon >= ? AND r.min_lat <= ? AND r.max_lat >= ?"
+			args = append(args, area.MaxLon, area.MinLon, area.MaxLat, area.MinLat)
+		}
+		// The R*-tree stores coordinates with single precision, so the exact box is matched as well
+		where = append(where, "t.longitude BETWEEN ? AND ? AND t.latitude BETWEEN ? AND ?")
+		args = append(args, area.MinLon, area.MaxLon, area.MinLat, area.MaxLat)
+	}
+	w, a := filter(q)
+	where, args = append(where, w...), append(args, a...)
+
+	var sb strings.Builder
+	sb.WriteString("SELECT ")
+	for i, column := range strings.Split(columns, ",") {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("t." + strings.TrimSpace(column))
+	}
+	sb.WriteString(" FROM " + table + " t" + from)
+	if len(where) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+	sb.WriteString(" ORDER BY t.msgtime, t.mmsi")
+	refine := keep != nil && q.Geometry != nil
+	if q.Limit > 0 && !refine {
+		sb.WriteString(fmt.Sprintf(" LIMIT %d", q.Limit))
+	}
+
+	rows, err := db.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []T
+	for rows.Next() {
+		v, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		if refine && !keep(v) {
+			continue
+		}
+		res = append(res, v)
+		if q.Limit > 0 && len(res) == q.Limit {
+			break
+		}
+	}
+	return res, rows.Err()
+}
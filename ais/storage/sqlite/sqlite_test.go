@@ -0,0 +1,49 @@
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais/storage"
+	"github.com/ilder-as/go-barentswatch-ais/ais/storage/sqlite"
+	"github.com/ilder-as/go-barentswatch-ais/ais/storage/storagetest"
+)
+
+func open(t *testing.T) *sqlite.Store {
+	store, err := sqlite.Open(filepath.Join(t.TempDir(), "ais.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store { return open(t) })
+}
+
+func TestStore_Prune(t *testing.T) {
+	ctx := context.Background()
+	store := open(t)
+	n, err := store.Append(ctx, storagetest.Fixture(t)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Everything in the fixture is older than the retention
+	store.Retention = time.Hour
+	deleted, err := store.Prune(ctx)
+	if err != nil || deleted != int64(n) {
+		t.Fatalf("expected %d messages to be deleted, got %d, %v", n, deleted, err)
+	}
+	if res, err := store.Positions(ctx, storage.Query{}); err != nil || len(res) != 0 {
+		t.Fatalf("expected no positions after pruning, got %d, %v", len(res), err)
+	}
+	// Deleted positions must also be gone from the spatial index
+	var indexed int
+	if err := store.DB().QueryRow("SELECT count(*) FROM positions_rtree").Scan(&indexed); err != nil || indexed != 0 {
+		t.Fatalf("expected empty spatial index, got %d, %v", indexed, err)
+	}
+}
@@ -0,0 +1,139 @@
+// Package storage holds the query types shared by the archive stores in its subpackages, which persist AIS messages
+// for later queries by vessel, time and area.
+package storage
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/geometry"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// Bounds is a bounding box in degrees of longitude and latitude. Boxes crossing the antimeridian are not supported.
+type Bounds struct {
+	MinLon float64
+	MinLat float64
+	MaxLon float64
+	MaxLat float64
+}
+
+// Contains is true iff the point is inside the box or on its edge.
+func (b Bounds) Contains(lon, lat float64) bool {
+	return lon >= b.MinLon && lon <= b.MaxLon && lat >= b.MinLat && lat <= b.MaxLat
+}
+
+// intersect returns the intersection of the boxes.
+func (b Bounds) intersect(o Bounds) Bounds {
+	return Bounds{
+		MinLon: max(b.MinLon, o.MinLon),
+		MinLat: max(b.MinLat, o.MinLat),
+		MaxLon: min(b.MaxLon, o.MaxLon),
+		MaxLat: min(b.MaxLat, o.MaxLat),
+	}
+}
+
+// Query selects archived messages. Every criterion that is set must match; the zero Query selects every message.
+type Query struct {
+	// Mmsi limits the query to the supplied vessels.
+	Mmsi []int
+	// From and To limit the query to messages with From <= Msgtime < To. Zero times are unbounded.
+	From time.Time
+	To   time.Time
+	// Bounds limits the query to messages positioned inside the box. Messages without position, such as static data,
+	// are not limited by it.
+	Bounds *Bounds
+	// Geometry limits the query to messages positioned inside the polygon or multipolygon. Like Bounds, it does not
+	// limit messages without position.
+	Geometry *geojson.Geometry
+	// Limit is the maximum number of messages returned. Zero is unlimited.
+	Limit int
+}
+
+// Validate checks that the query does not end before it starts, and that its Geometry, if any, is a polygon or
+// multipolygon.
+func (q Query) Validate() error {
+	if !q.To.IsZero() && q.To.Before(q.From) {
+		return errors.New("storage: query ends before it starts")
+	}
+	if q.Geometry == nil {
+		return nil
+	}
+	switch q.Geometry.Type {
+	case geojson.GeometryPolygon:
+		if len(q.Geometry.Polygon) == 0 {
+			return errors.New("storage: polygon has no rings")
+		}
+	case geojson.GeometryMultiPolygon:
+		if len(q.Geometry.MultiPolygon) == 0 {
+			return errors.New("storage: multipolygon has no polygons")
+		}
+	default:
+		return errors.New("storage: geometry must be a polygon or multipolygon, got " + string(q.Geometry.Type))
+	}
+	return nil
+}
+
+// Area returns the box covering the area selected by Bounds and Geometry, for use with spatial indexes, and whether
+// the query selects an area at all.
+func (q Query) Area() (Bounds, bool) {
+	var area *Bounds
+	if q.Bounds != nil {
+		b := *q.Bounds
+		area = &b
+	}
+	if q.Geometry != nil {
+		g := geometryBounds(q.Geometry)
+		if area == nil {
+			area = &g
+		} else {
+			*area = area.intersect(g)
+		}
+	}
+	if area == nil {
+		return Bounds{}, false
+	}
+	return *area, true
+}
+
+// ContainsPoint is true iff the position is inside the area selected by Bounds and Geometry. Positions which are
+// unknown are only contained when the query selects no area.
+func (q Query) ContainsPoint(lon, lat *float64) bool {
+	if q.Bounds == nil && q.Geometry == nil {
+		return true
+	}
+	if lon == nil || lat == nil {
+		return false
+	}
+	if q.Bounds != nil && !q.Bounds.Contains(*lon, *lat) {
+		return false
+	}
+	return q.Geometry == nil || Contains(q.Geometry, *lon, *lat)
+}
+
+// Contains is true iff the point is inside the polygon or multipolygon. Points inside holes are outside the polygon.
+// Other geometries contain no points.
+func Contains(g *geojson.Geometry, lon, lat float64) bool {
+	return geometry.Contains(g, lon, lat)
+}
+
+func geometryBounds(g *geojson.Geometry) Bounds {
+	b := Bounds{MinLon: 180, MinLat: 90, MaxLon: -180, MaxLat: -90}
+	extend := func(rings [][][]float64) {
+		for _, ring := range rings {
+			for _, p := range ring {
+				b.MinLon, b.MaxLon = min(b.MinLon, p[0]), max(b.MaxLon, p[0])
+				b.MinLat, b.MaxLat = min(b.MinLat, p[1]), max(b.MaxLat, p[1])
+			}
+		}
+	}
+	switch g.Type {
+	case geojson.GeometryPolygon:
+		extend(g.Polygon)
+	case geojson.GeometryMultiPolygon:
+		for _, p := range g.MultiPolygon {
+			extend(p)
+		}
+	}
+	return b
+}
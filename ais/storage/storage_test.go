@@ -0,0 +1,40 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/storage"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+func TestQuery(t *testing.T) {
+	q := storage.Query{
+		Bounds:   &storage.Bounds{MinLon: 5, MinLat: 5, MaxLon: 20, MaxLat: 20},
+		Geometry: ais.BoundingBox(0, 0, 10, 10),
+	}
+	if err := q.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	area, ok := q.Area()
+	if !ok || area != (storage.Bounds{MinLon: 5, MinLat: 5, MaxLon: 10, MaxLat: 10}) {
+		t.Errorf("expected intersection of bounds and geometry, got %+v", area)
+	}
+
+	lon, lat := 7.0, 7.0
+	if !q.ContainsPoint(&lon, &lat) {
+		t.Errorf("expected %f, %f to be contained", lon, lat)
+	}
+	lon = 12
+	if q.ContainsPoint(&lon, &lat) {
+		t.Errorf("expected %f, %f not to be contained", lon, lat)
+	}
+	if q.ContainsPoint(nil, nil) || !(storage.Query{}).ContainsPoint(nil, nil) {
+		t.Error("expected unknown positions to be contained only by queries without area")
+	}
+
+	q.Geometry = geojson.NewPointGeometry([]float64{1, 1})
+	if err := q.Validate(); err == nil {
+		t.Error("expected point geometry to be invalid")
+	}
+}
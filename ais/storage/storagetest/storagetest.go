@@ -0,0 +1,296 @@
+// Package storagetest implements the conformance tests of storage.Store, which every backend runs from its own tests:
+//
+//	func TestStore(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storage.Store {
+//			return open(t)
+//		})
+//	}
+package storagetest
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/storage"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+	geojson "github.com/paulmach/go.geojson"
+)
+
+// Run runs the conformance tests against stores returned by open. Every store it returns must be empty, and is closed
+// by its cleanup, if any.
+func Run(t *testing.T, open func(t *testing.T) storage.Store) {
+	t.Run("Append", func(t *testing.T) { testAppend(t, open(t)) })
+	t.Run("Positions", func(t *testing.T) { testPositions(t, open(t)) })
+	t.Run("Staticdata", func(t *testing.T) { testStaticdata(t, open(t)) })
+	t.Run("Atons", func(t *testing.T) { testAtons(t, open(t)) })
+	t.Run("Snapshot", func(t *testing.T) { testSnapshot(t, open(t)) })
+	t.Run("Ingest", func(t *testing.T) { testIngest(t, open(t)) })
+}
+
+// Fixture returns the messages of ais/testdata/get_ais.txt, which the conformance tests store.
+func Fixture(t *testing.T) []ais.AisMultiple {
+	t.Helper()
+	_, file, _, _ := runtime.Caller(0)
+	f, err := os.Open(filepath.Join(filepath.Dir(file), "../../testdata/get_ais.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var res []ais.AisMultiple
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg ais.AisMultiple
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// appendFixture stores the fixture, and returns its messages.
+func appendFixture(t *testing.T, store storage.Store) []ais.AisMultiple {
+	t.Helper()
+	msgs := Fixture(t)
+	if _, err := store.Append(context.Background(), msgs...); err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+// positions returns the number of positions of the messages which match the predicate, deduplicated as by a store.
+func positions(msgs []ais.AisMultiple, match func(ais.Position) bool) int {
+	type key struct {
+		mmsi        int
+		msgtime     time.Time
+		messageType int
+	}
+	seen := make(map[key]bool)
+	for _, msg := range msgs {
+		p := msg.Position
+		if msg.Type == responsetype.Position && match(p) {
+			seen[key{p.Mmsi, p.Msgtime.Truncate(storage.TimePrecision).UTC(), p.MessageType}] = true
+		}
+	}
+	return len(seen)
+}
+
+func testAppend(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	msgs := Fixture(t)
+
+	n, err := store.Append(ctx, msgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 || n > len(msgs) {
+		t.Fatalf("expected up to %d messages to be stored, got %d", len(msgs), n)
+	}
+	if n, err := store.Append(ctx, msgs...); err != nil || n != 0 {
+		t.Fatalf("expected duplicates to be ignored, got %d, %v", n, err)
+	}
+
+	// Times are only kept to TimePrecision, so messages which differ by less are duplicates
+	lat, lon := 70.0, 20.0
+	msgtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := ais.Position{Mmsi: 999_000_001, Msgtime: msgtime, MessageType: 1, Latitude: &lat, Longitude: &lon}
+	q := p
+	q.Msgtime = msgtime.Add(storage.TimePrecision - time.Nanosecond)
+	n, err = store.Append(ctx, ais.AisMultiple{Type: responsetype.Position, Position: p},
+		ais.AisMultiple{Type: responsetype.Position, Position: q})
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 message to be stored, got %d, %v", n, err)
+	}
+	res, err := store.Positions(ctx, storage.Query{Mmsi: []int{p.Mmsi}})
+	if err != nil || len(res) != 1 || !res[0].Msgtime.Equal(msgtime) {
+		t.Fatalf("expected the position at %v, got %+v, %v", msgtime, res, err)
+	}
+}
+
+func testPositions(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	msgs := appendFixture(t, store)
+
+	first := msgs[0].Position
+	from := first.Msgtime.Truncate(storage.TimePrecision)
+	triangle := geojson.NewPolygonGeometry([][][]float64{{{0, 55}, {10, 55}, {5, 65}, {0, 55}}})
+	bounds := storage.Bounds{MinLon: 2, MinLat: 58, MaxLon: 6, MaxLat: 62}
+	tests := []struct {
+		name  string
+		query storage.Query
+		match func(ais.Position) bool
+	}{
+		{"all", storage.Query{}, func(ais.Position) bool { return true }},
+		{"mmsi", storage.Query{Mmsi: []int{first.Mmsi}}, func(p ais.Position) bool { return p.Mmsi == first.Mmsi }},
+		{"time", storage.Query{From: from, To: from.Add(time.Second)}, func(p ais.Position) bool {
+			msgtime := p.Msgtime.Truncate(storage.TimePrecision)
+			return !msgtime.Before(from) && msgtime.Before(from.Add(time.Second))
+		}},
+		{"bounds", storage.Query{Bounds: &bounds}, func(p ais.Position) bool {
+			return p.Longitude != nil && p.Latitude != nil && bounds.Contains(*p.Longitude, *p.Latitude)
+		}},
+		{"polygon", storage.Query{Geometry: triangle}, func(p ais.Position) bool {
+			return p.Longitude != nil && p.Latitude != nil && storage.Contains(triangle, *p.Longitude, *p.Latitude)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := store.Positions(ctx, test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := positions(msgs, test.match)
+			if len(res) != want || want == 0 {
+				t.Fatalf("expected %d positions, got %d", want, len(res))
+			}
+			for i, p := range res {
+				if !test.match(p) {
+					t.Fatalf("unexpected position %+v", p)
+				}
+				if i > 0 && p.Msgtime.Before(res[i-1].Msgtime) {
+					t.Fatal("expected positions ordered by time")
+				}
+			}
+
+			test.query.Limit = 3
+			if res, err := store.Positions(ctx, test.query); err != nil || len(res) != min(3, want) {
+				t.Fatalf("expected %d positions with limit, got %d, %v", min(3, want), len(res), err)
+			}
+		})
+	}
+}
+
+func testStaticdata(t *testing.T, store storage.Store) {
+	appendFixture(t, store)
+	bounds := storage.Bounds{MinLon: 2, MinLat: 58, MaxLon: 6, MaxLat: 62}
+	staticdata, err := store.Staticdata(context.Background(), storage.Query{Bounds: &bounds})
+	if err != nil || len(staticdata) == 0 {
+		t.Fatalf("expected static data regardless of area, got %d, %v", len(staticdata), err)
+	}
+}
+
+func testAtons(t *testing.T, store storage.Store) {
+	appendFixture(t, store)
+	bounds := storage.Bounds{MinLon: 2, MinLat: 58, MaxLon: 6, MaxLat: 62}
+	atons, err := store.Atons(context.Background(), storage.Query{Bounds: &bounds})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range atons {
+		if !bounds.Contains(*a.Longitude, *a.Latitude) {
+			t.Fatalf("unexpected aton %+v outside bounds", a)
+		}
+	}
+}
+
+func testSnapshot(t *testing.T, store storage.Store) {
+	ctx := context.Background()
+	msgs := appendFixture(t, store)
+
+	latest := make(map[int]ais.Position)
+	names := make(map[int]string)
+	for _, msg := range msgs {
+		switch msg.Type {
+		case responsetype.Position:
+			if p, ok := latest[msg.Position.Mmsi]; !ok || msg.Position.Msgtime.After(p.Msgtime) {
+				latest[msg.Position.Mmsi] = msg.Position
+			}
+		case responsetype.Staticdata:
+			names[msg.Staticdata.Mmsi] = msg.Staticdata.Name
+		}
+	}
+
+	vessels, err := store.Snapshot(ctx, storage.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vessels) != len(latest) {
+		t.Fatalf("expected %d vessels, got %d", len(latest), len(vessels))
+	}
+	named := 0
+	for i, v := range vessels {
+		if i > 0 && vessels[i-1].Mmsi >= v.Mmsi {
+			t.Fatal("expected vessels ordered by MMSI")
+		}
+		p := latest[v.Mmsi]
+		if v.Msgtime.Before(p.Msgtime.Truncate(storage.TimePrecision)) {
+			t.Fatalf("expected vessel %d at its latest position, got %v", v.Mmsi, v.Msgtime)
+		}
+		if *v.Latitude != *p.Latitude || *v.Longitude != *p.Longitude {
+			t.Fatalf("expected vessel %d at %f, %f, got %f, %f", v.Mmsi, *p.Latitude, *p.Longitude, *v.Latitude,
+				*v.Longitude)
+		}
+		if name, ok := names[v.Mmsi]; ok {
+			named++
+			if v.Name != name {
+				t.Fatalf("expected vessel %d named %q, got %q", v.Mmsi, name, v.Name)
+			}
+		}
+	}
+	if named == 0 {
+		t.Error("expected some vessels with static data")
+	}
+
+	bounds := storage.Bounds{MinLon: 2, MinLat: 58, MaxLon: 6, MaxLat: 62}
+	inside, err := store.Snapshot(ctx, storage.Query{Bounds: &bounds})
+	if err != nil || len(inside) == 0 {
+		t.Fatalf("expected vessels within bounds, got %d, %v", len(inside), err)
+	}
+	for _, v := range inside {
+		if !bounds.Contains(*v.Longitude, *v.Latitude) {
+			t.Fatalf("unexpected vessel %+v outside bounds", v)
+		}
+	}
+
+	// Static data older than the stored static data is ignored
+	mmsi := vessels[0].Mmsi
+	older := ais.Staticdata{Mmsi: mmsi, Msgtime: time.Unix(0, 0), Name: "OLDER"}
+	newer := ais.Staticdata{Mmsi: mmsi, Msgtime: time.Now(), Name: "NEWER"}
+	if err := store.UpsertVessels(ctx, newer, older); err != nil {
+		t.Fatal(err)
+	}
+	vessels, err = store.Snapshot(ctx, storage.Query{Mmsi: []int{mmsi}})
+	if err != nil || len(vessels) != 1 || vessels[0].Name != "NEWER" {
+		t.Fatalf("expected the newer static data, got %+v, %v", vessels, err)
+	}
+}
+
+func testIngest(t *testing.T, store storage.Store) {
+	msgs := Fixture(t)
+
+	in := make(chan ais.AisMultiple)
+	go func() {
+		defer close(in)
+		for _, msg := range msgs {
+			in <- msg
+		}
+	}()
+	if err := storage.Ingest(context.Background(), store, in, 100, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := store.Positions(context.Background(), storage.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := positions(msgs, func(ais.Position) bool { return true }); len(res) != want {
+		t.Errorf("expected %d positions, got %d", want, len(res))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := storage.Ingest(ctx, store, make(chan ais.AisMultiple), 0, 0); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+)
+
+// Store is an archive of AIS messages. It is implemented by the backends in the subpackages of storage, so that
+// applications can move between them without changing their schema or queries.
+//
+// Tracks are queried with Positions, for a vessel's MMSI and a time range. The current static data of vessels is kept
+// apart from the history of static data messages, so that snapshots of vessels are cheap to query.
+type Store interface {
+	// Append stores the messages, and returns the number of messages which were not already stored. Only Position,
+	// Staticdata and Aton messages are stored, deduplicated on their MMSI, Msgtime and MessageType. Msgtime is
+	// truncated to TimePrecision, so messages which differ only by less are duplicates. Static data messages also
+	// update the static data of their vessels, as by UpsertVessels.
+	Append(ctx context.Context, msgs ...ais.AisMultiple) (int, error)
+	// UpsertVessels stores the static data as the current static data of their vessels, unless newer static data is
+	// already stored. It does not add to the history of static data messages.
+	UpsertVessels(ctx context.Context, data ...ais.Staticdata) error
+	// Positions returns the positions selected by the query, ordered by time and MMSI.
+	Positions(ctx context.Context, q Query) ([]ais.Position, error)
+	// Staticdata returns the static data messages selected by the query, ordered by time and MMSI. The area of the
+	// query does not apply to static data, which has no position.
+	Staticdata(ctx context.Context, q Query) ([]ais.Staticdata, error)
+	// Atons returns the aid to navigation messages selected by the query, ordered by time and MMSI.
+	Atons(ctx context.Context, q Query) ([]ais.Aton, error)
+	// Snapshot returns the vessels selected by the query, ordered by MMSI, each with its latest position in the time
+	// range of the query and its current static data. The area of the query applies to the latest positions.
+	Snapshot(ctx context.Context, q Query) ([]ais.Vessel, error)
+	// Prune deletes the messages which are older than the retention of the store, and returns the number of
+	// messages deleted.
+	Prune(ctx context.Context) (int64, error)
+	// Close closes the store.
+	Close() error
+}
+
+// TimePrecision is the precision to which every Store keeps the times of messages, that of PostgreSQL timestamps.
+const TimePrecision = time.Microsecond
+
+// Defaults of Ingest.
+const (
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = time.Second
+)
+
+// pruneInterval is how often Ingest prunes the store.
+const pruneInterval = time.Hour
+
+// Ingest stores every message received from in, until in is closed or the context is cancelled. It accepts the
+// channel of StreamResponse.UnmarshalStream directly. Messages are appended in batches of up to batchSize messages,
+// at least every flushInterval, and the messages received before the context was cancelled are appended before it
+// returns. Zero sizes and intervals mean DefaultBatchSize and DefaultFlushInterval. The store is pruned when Ingest
+// starts and every hour while it runs.
+//
+// It returns the context's error if the context was cancelled, the first error of the store, or nil. Errors of the
+// stream itself are reported by the StreamResponse.
+func Ingest(ctx context.Context, s Store, in <-chan ais.AisMultiple, batchSize int, flushInterval time.Duration) error {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if flushInterval <= 0 {
+		flushInterval = DefaultFlushInterval
+	}
+
+	if _, err := s.Prune(ctx); err != nil {
+		return err
+	}
+
+	batch := make([]ais.AisMultiple, 0, batchSize)
+	flush := func(ctx context.Context) error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := s.Append(ctx, batch...)
+		clear(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	flushTicker := time.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+	pruneTicker := time.NewTicker(pruneInterval)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := flush(context.WithoutCancel(ctx)); err != nil {
+				return err
+			}
+			return ctx.Err()
+		case msg, ok := <-in:
+			if !ok {
+				return flush(ctx)
+			}
+			batch = append(batch, msg)
+			if len(batch) == batchSize {
+				if err := flush(ctx); err != nil {
+					return err
+				}
+			}
+		case <-flushTicker.C:
+			if err := flush(ctx); err != nil {
+				return err
+			}
+		case <-pruneTicker.C:
+			if _, err := s.Prune(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Package track simplifies and resamples vessel tracks, so that they can be drawn without sending every position to
+// the browser.
+//
+// Tracks are slices of positions of a single vessel, ordered by time, such as the result of GetAis. The simplifiers
+// return the positions they keep along with their indices in the track, so that other data about the positions can
+// be kept alongside them:
+//
+//	points, kept := track.DouglasPeucker(positions, 25)
+//
+// Positions without coordinates are never kept. Distances are measured on a local projection of the track, which is
+// accurate for tracks spanning up to a few hundred kilometres.
+package track
+
+import (
+	"container/heap"
+	"math"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/navmath"
+)
+
+// projection is an equirectangular projection to metres, centred on a point of the track.
+type projection struct {
+	lon0, lat0, scale float64
+}
+
+func newProjection(p ais.Position) projection {
+	return projection{lon0: *p.Longitude, lat0: *p.Latitude, scale: math.Cos(*p.Latitude * math.Pi / 180)}
+}
+
+func (pr projection) project(p ais.Position) (x, y float64) {
+	dLon := math.Remainder(*p.Longitude-pr.lon0, 360)
+	x = dLon * math.Pi / 180 * navmath.EarthRadius * pr.scale
+	y = (*p.Latitude - pr.lat0) * math.Pi / 180 * navmath.EarthRadius
+	return x, y
+}
+
+type point struct {
+	x, y  float64
+	index int
+}
+
+// located returns the positions of the track with coordinates, projected.
+func located(track []ais.Position) []point {
+	var res []point
+	var pr projection
+	for i, p := range track {
+		if p.Latitude == nil || p.Longitude == nil {
+			continue
+		}
+		if res == nil {
+			pr = newProjection(p)
+		}
+		x, y := pr.project(p)
+		res = append(res, point{x, y, i})
+	}
+	return res
+}
+
+// kept returns the positions of the track with the indices.
+func kept(track []ais.Position, indices []int) ([]ais.Position, []int) {
+	res := make([]ais.Position, len(indices))
+	for i, index := range indices {
+		res[i] = track[index]
+	}
+	return res, indices
+}
+
+// segmentDistance returns the distance from p to the segment from a to b.
+func segmentDistance(p, a, b point) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = max(0, min(1, ((p.x-a.x)*dx+(p.y-a.y)*dy)/l))
+	}
+	return math.Hypot(p.x-(a.x+t*dx), p.y-(a.y+t*dy))
+}
+
+// DouglasPeucker simplifies the track with the Douglas–Peucker algorithm, keeping the positions needed for the
+// simplified track to stay within tolerance metres of every position of the track. The first and last positions are
+// always kept.
+func DouglasPeucker(track []ais.Position, tolerance float64) ([]ais.Position, []int) {
+	points := located(track)
+	if len(points) <= 2 {
+		return kept(track, indices(points))
+	}
+
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	stack := [][2]int{{0, len(points) - 1}}
+	for len(stack) > 0 {
+		first, last := stack[len(stack)-1][0], stack[len(stack)-1][1]
+		stack = stack[:len(stack)-1]
+
+		farthest, distance := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			if d := segmentDistance(points[i], points[first], points[last]); d > distance {
+				farthest, distance = i, d
+			}
+		}
+		if farthest >= 0 {
+			keep[farthest] = true
+			stack = append(stack, [2]int{first, farthest}, [2]int{farthest, last})
+		}
+	}
+
+	var res []int
+	for i, p := range points {
+		if keep[i] {
+			res = append(res, p.index)
+		}
+	}
+	return kept(track, res)
+}
+
+func indices(points []point) []int {
+	res := make([]int, len(points))
+	for i, p := range points {
+		res[i] = p.index
+	}
+	return res
+}
+
+// vertex is a position in the linked list of Visvalingam.
+type vertex struct {
+	point
+	area       float64
+	prev, next *vertex
+	// heapIndex is the index of the vertex in the heap, or -1 if it has been removed
+	heapIndex int
+}
+
+func (v *vertex) updateArea() {
+	a, b, c := v.prev.point, v.point, v.next.point
+	v.area = math.Abs((b.x-a.x)*(c.y-a.y)-(c.x-a.x)*(b.y-a.y)) / 2
+}
+
+type vertexHeap []*vertex
+
+func (h vertexHeap) Len() int           { return len(h) }
+func (h vertexHeap) Less(i, j int) bool { return h[i].area < h[j].area }
+func (h vertexHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex, h[j].heapIndex = i, j
+}
+func (h *vertexHeap) Push(x any) {
+	v := x.(*vertex)
+	v.heapIndex = len(*h)
+	*h = append(*h, v)
+}
+func (h *vertexHeap) Pop() any {
+	old := *h
+	v := old[len(old)-1]
+	v.heapIndex = -1
+	*h = old[:len(old)-1]
+	return v
+}
+
+// Visvalingam simplifies the track with the Visvalingam–Whyatt algorithm, repeatedly removing the position which
+// forms the triangle of least area with its neighbours, until every remaining triangle has an area of at least the
+// square of tolerance metres. It removes small wiggles while keeping broad turns better than DouglasPeucker. The
+// first and last positions are always kept.
+func Visvalingam(track []ais.Position, tolerance float64) ([]ais.Position, []int) {
+	points := located(track)
+	if len(points) <= 2 {
+		return kept(track, indices(points))
+	}
+
+	vertices := make([]vertex, len(points))
+	for i := range vertices {
+		vertices[i] = vertex{point: points[i], heapIndex: -1}
+		if i > 0 {
+			vertices[i].prev = &vertices[i-1]
+			vertices[i-1].next = &vertices[i]
+		}
+	}
+	h := make(vertexHeap, 0, len(vertices)-2)
+	for i := 1; i < len(vertices)-1; i++ {
+		vertices[i].updateArea()
+		heap.Push(&h, &vertices[i])
+	}
+
+	minArea := tolerance * tolerance
+	for h.Len() > 0 && h[0].area < minArea {
+		v := heap.Pop(&h).(*vertex)
+		v.prev.next, v.next.prev = v.next, v.prev
+		// The area of a neighbour never drops below that of the removed vertex, so that the order of removal is
+		// that of the effective areas
+		for _, n := range []*vertex{v.prev, v.next} {
+			if n.heapIndex >= 0 {
+				n.updateArea()
+				n.area = max(n.area, v.area)
+				heap.Fix(&h, n.heapIndex)
+			}
+		}
+	}
+
+	var res []int
+	for v := &vertices[0]; v != nil; v = v.next {
+		res = append(res, v.index)
+	}
+	return kept(track, res)
+}
+
+// ThinByHeading thins the track, keeping the positions where the vessel's heading has changed by at least minChange
+// degrees since the last position kept, so that turns are preserved while straight legs are reduced to their ends.
+// A position is also kept when maxInterval has passed since the last position kept, unless maxInterval is zero. The
+// first and last positions are always kept.
+//
+// The heading of a position is its true heading, or its course over ground if the heading is not available, or else
+// the bearing from the previous position.
+func ThinByHeading(track []ais.Position, minChange float64, maxInterval time.Duration) ([]ais.Position, []int) {
+	points := located(track)
+	if len(points) <= 2 {
+		return kept(track, indices(points))
+	}
+
+	res := []int{points[0].index}
+	last := points[0].index
+	lastHeading, known := heading(track[last], points[0], points[0])
+	for i := 1; i < len(points)-1; i++ {
+		p := track[points[i].index]
+		h, ok := heading(p, points[i-1], points[i])
+		turned := ok && known && math.Abs(math.Remainder(h-lastHeading, 360)) >= minChange
+		late := maxInterval > 0 && p.Msgtime.Sub(track[last].Msgtime) >= maxInterval
+		if !known && ok {
+			lastHeading, known = h, true
+		}
+		if turned || late {
+			res = append(res, points[i].index)
+			last = points[i].index
+			if ok {
+				lastHeading, known = h, true
+			}
+		}
+	}
+	res = append(res, points[len(points)-1].index)
+	return kept(track, res)
+}
+
+// heading returns the heading of the position, which is at the point and follows prev.
+func heading(p ais.Position, prev, at point) (float64, bool) {
+	if p.TrueHeading != nil && *p.TrueHeading >= 0 && *p.TrueHeading < 360 {
+		return float64(*p.TrueHeading), true
+	}
+	if p.CourseOverGround != nil && *p.CourseOverGround >= 0 && *p.CourseOverGround < 360 {
+		return *p.CourseOverGround, true
+	}
+	if prev == at {
+		return 0, false
+	}
+	return math.Mod(math.Atan2(at.x-prev.x, at.y-prev.y)*180/math.Pi+360, 360), true
+}
+
+// Resample resamples the track to one position every interval, from the time of its first position to the time of
+// its last. Positions between those of the track are interpolated: coordinates, speed and rate of turn linearly, and
+// course and heading along the shorter turn. Their other fields, and values which are missing from either of the
+// positions around them, are those of the position before them. Gaps in the
+// track longer than maxGap are not filled, unless maxGap is zero.
+func Resample(track []ais.Position, interval time.Duration, maxGap time.Duration) []ais.Position {
+	points := located(track)
+	if len(points) == 0 || interval <= 0 {
+		return nil
+	}
+
+	var res []ais.Position
+	first := track[points[0].index].Msgtime
+	last := track[points[len(points)-1].index].Msgtime
+	j := 0
+	for t := first; !t.After(last); t = t.Add(interval) {
+		// Find the positions a and b of the track with a at or before t, and b after it
+		for j+1 < len(points) && !track[points[j+1].index].Msgtime.After(t) {
+			j++
+		}
+		a := track[points[j].index]
+		if j+1 == len(points) || a.Msgtime.Equal(t) {
+			res = append(res, a)
+			res[len(res)-1].Msgtime = t
+			continue
+		}
+		b := track[points[j+1].index]
+		if maxGap > 0 && b.Msgtime.Sub(a.Msgtime) > maxGap {
+			continue
+		}
+		res = append(res, interpolate(a, b, t))
+	}
+	return res
+}
+
+// interpolate returns the position at t, between a and b.
+func interpolate(a, b ais.Position, t time.Time) ais.Position {
+	f := float64(t.Sub(a.Msgtime)) / float64(b.Msgtime.Sub(a.Msgtime))
+	lerp := func(x, y float64) float64 { return x + f*(y-x) }
+	angle := func(x, y float64) float64 { return math.Mod(x+f*math.Remainder(y-x, 360)+360, 360) }
+
+	res := a
+	res.Msgtime = t
+	lat := lerp(*a.Latitude, *b.Latitude)
+	lon := math.Remainder(*a.Longitude+f*math.Remainder(*b.Longitude-*a.Longitude, 360), 360)
+	res.Latitude, res.Longitude = &lat, &lon
+	if a.SpeedOverGround != nil && b.SpeedOverGround != nil {
+		sog := lerp(*a.SpeedOverGround, *b.SpeedOverGround)
+		res.SpeedOverGround = &sog
+	}
+	if a.RateOfTurn != nil && b.RateOfTurn != nil {
+		rot := lerp(*a.RateOfTurn, *b.RateOfTurn)
+		res.RateOfTurn = &rot
+	}
+	if a.CourseOverGround != nil && b.CourseOverGround != nil {
+		cog := angle(*a.CourseOverGround, *b.CourseOverGround)
+		res.CourseOverGround = &cog
+	}
+	if a.TrueHeading != nil && b.TrueHeading != nil && *a.TrueHeading < 360 && *b.TrueHeading < 360 {
+		heading := int(math.Round(angle(float64(*a.TrueHeading), float64(*b.TrueHeading)))) % 360
+		res.TrueHeading = &heading
+	}
+	return res
+}
@@ -0,0 +1,114 @@
+package track_test
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/aistest"
+	"github.com/ilder-as/go-barentswatch-ais/ais/track"
+)
+
+// metre is roughly one metre of latitude, in degrees.
+const metre = 1 / 111195.0
+
+// zigzag returns a track heading north from 60°N, 5°E along the meridian, 100 metres between positions 10 seconds
+// apart, with a sideways offset of wiggle metres at every other position, and a turn to the east at position 10.
+func zigzag(wiggle float64) []ais.Position {
+	var res []ais.Position
+	sog := 10 * 3600 / 1852.0
+	for i := range 20 {
+		offset := 0.0
+		if i%2 == 1 {
+			offset = wiggle
+		}
+		north, east := float64(min(i, 10))*100, float64(max(0, i-10))*100
+		if i <= 10 {
+			east += offset
+		} else {
+			north += offset
+		}
+		lat := 60 + north*metre
+		lon := 5 + east*metre/math.Cos(60*math.Pi/180)
+		res = append(res, aistest.Position(1, time.Duration(i)*10*time.Second, lon, lat, sog))
+	}
+	return res
+}
+
+func TestDouglasPeucker(t *testing.T) {
+	tr := zigzag(5)
+	// A position without coordinates is never kept
+	tr = slices.Insert(tr, 3, ais.Position{Mmsi: 1, Msgtime: tr[2].Msgtime})
+
+	points, kept := track.DouglasPeucker(tr, 10)
+	if !slices.Equal(kept, []int{0, 11, 20}) {
+		t.Errorf("expected the ends and the turn to be kept, got %v", kept)
+	}
+	for i, p := range points {
+		if p.Msgtime != tr[kept[i]].Msgtime {
+			t.Errorf("expected position %d to be position %d of the track", i, kept[i])
+		}
+	}
+
+	if _, kept := track.DouglasPeucker(tr, 1); len(kept) != 20 {
+		t.Errorf("expected every position with coordinates to be kept with a small tolerance, got %v", kept)
+	}
+}
+
+func TestVisvalingam(t *testing.T) {
+	tr := zigzag(5)
+	if _, kept := track.Visvalingam(tr, 100); !slices.Equal(kept, []int{0, 10, 19}) {
+		t.Errorf("expected the ends and the turn to be kept, got %v", kept)
+	}
+	if _, kept := track.Visvalingam(tr, 1); len(kept) != 20 {
+		t.Errorf("expected every position to be kept with a small tolerance, got %v", kept)
+	}
+	if _, kept := track.Visvalingam(tr[:2], 1000); len(kept) != 2 {
+		t.Errorf("expected short tracks to be kept, got %v", kept)
+	}
+}
+
+func TestThinByHeading(t *testing.T) {
+	tr := zigzag(0)
+	points, kept := track.ThinByHeading(tr, 45, 0)
+	if !slices.Equal(kept, []int{0, 11, 19}) || len(points) != 3 {
+		t.Errorf("expected the ends and the first position after the turn to be kept, got %v", kept)
+	}
+
+	// Reported headings are used over bearings
+	heading := 0
+	tr[5].TrueHeading = &heading
+	if _, kept := track.ThinByHeading(tr, 45, 50*time.Second); !slices.Equal(kept, []int{0, 5, 10, 11, 16, 19}) {
+		t.Errorf("expected positions to be kept every 50 seconds, got %v", kept)
+	}
+}
+
+func TestResample(t *testing.T) {
+	sog2 := 20.0
+	cog1, cog2 := 350.0, 20.0
+	a := aistest.Position(1, 0, 179.9, 60, 10)
+	b := aistest.Position(1, 100*time.Second, -179.9, 61, sog2)
+	c := aistest.Position(1, 1000*time.Second, -179.9, 62, 0)
+	a.CourseOverGround, b.CourseOverGround = &cog1, &cog2
+	c.SpeedOverGround = nil
+
+	res := track.Resample([]ais.Position{a, b, c}, 25*time.Second, 0)
+	if len(res) != 41 {
+		t.Fatalf("expected 41 positions, got %d", len(res))
+	}
+	mid := res[2]
+	if !mid.Msgtime.Equal(aistest.Start.Add(50*time.Second)) || math.Abs(*mid.Latitude-60.5) > 1e-9 ||
+		math.Abs(math.Abs(*mid.Longitude)-180) > 1e-9 || *mid.SpeedOverGround != 15 ||
+		math.Abs(*mid.CourseOverGround-5) > 1e-9 {
+		t.Errorf("unexpected interpolated position %+v", mid)
+	}
+	if res[4].Latitude != b.Latitude || *res[5].SpeedOverGround != sog2 {
+		t.Errorf("expected positions of the track to be kept, and values missing after them not to be interpolated")
+	}
+
+	if res := track.Resample([]ais.Position{a, b, c}, 25*time.Second, 5*time.Minute); len(res) != 6 {
+		t.Errorf("expected the gap not to be filled, got %d positions", len(res))
+	}
+}
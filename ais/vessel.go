@@ -0,0 +1,446 @@
+package ais
+
+import (
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+)
+
+// Vessel is a normalised view of a vessel, which can be converted to and from every response type which describes
+// vessels, so that code handling vessels can be written once regardless of the endpoint or model format the data was
+// fetched from.
+//
+// Fields which are not part of the type a Vessel was converted from are nil or empty. Converting a Vessel to another
+// type leaves out the fields which are not part of that type.
+type Vessel struct {
+	Mmsi int `json:"mmsi"`
+	// Msgtime is the time of the latest message about the vessel.
+	Msgtime time.Time `json:"msgtime"`
+	Name    string    `json:"name"`
+	// PositionTime and StaticdataTime are the times of the latest position report and the latest static data report
+	// about the vessel. Reports older than these are ignored by UpdatePosition and UpdateStaticdata, so that messages
+	// received out of order do not overwrite newer data. Vessels converted from combined messages have both set to
+	// the time of the message.
+	PositionTime   time.Time `json:"-"`
+	StaticdataTime time.Time `json:"-"`
+
+	Latitude           *float64 `json:"latitude"`
+	Longitude          *float64 `json:"longitude"`
+	Altitude           *int     `json:"altitude"`
+	CourseOverGround   *float64 `json:"courseOverGround"`
+	SpeedOverGround    *float64 `json:"speedOverGround"`
+	RateOfTurn         *float64 `json:"rateOfTurn"`
+	TrueHeading        *int     `json:"trueHeading"`
+	NavigationalStatus *int     `json:"navigationalStatus"`
+
+	ShipType                 *int   `json:"shipType"`
+	ImoNumber                *int   `json:"imoNumber"`
+	CallSign                 string `json:"callSign"`
+	Destination              string `json:"destination"`
+	Eta                      string `json:"eta"`
+	Draught                  *int   `json:"draught"`
+	ShipLength               *int   `json:"shipLength"`
+	ShipWidth                *int   `json:"shipWidth"`
+	DimensionA               *int   `json:"dimensionA"`
+	DimensionB               *int   `json:"dimensionB"`
+	DimensionC               *int   `json:"dimensionC"`
+	DimensionD               *int   `json:"dimensionD"`
+	PositionFixingDeviceType int    `json:"positionFixingDeviceType"`
+	// AisClass is the class of the vessel's transponder, "A" or "B", known as AisClass in Position and as ReportClass
+	// in the other types.
+	AisClass string `json:"aisClass"`
+}
+
+// NewVessel combines a position report and static data about the same vessel. Either may be zero-valued.
+func NewVessel(p Position, s Staticdata) Vessel {
+	var v Vessel
+	v.UpdateStaticdata(s)
+	v.UpdatePosition(p)
+	return v
+}
+
+// Update updates the vessel with a message from an AIS stream. Aton messages are ignored.
+func (v *Vessel) Update(a AisMultiple) {
+	switch a.Type {
+	case responsetype.Position:
+		v.UpdatePosition(a.Position)
+	case responsetype.Staticdata:
+		v.UpdateStaticdata(a.Staticdata)
+	}
+}
+
+// UpdatePosition updates the vessel with the fields of a position report. A zero-valued Position, and a position
+// report older than the latest one the vessel was updated with, are ignored.
+func (v *Vessel) UpdatePosition(p Position) {
+	if p.IsZero() || p.Msgtime.Before(v.PositionTime) {
+		return
+	}
+	v.Mmsi = p.Mmsi
+	v.PositionTime = p.Msgtime
+	v.updateMsgtime(p.Msgtime)
+	v.Latitude = clone(p.Latitude)
+	v.Longitude = clone(p.Longitude)
+	v.Altitude = clone(p.Altitude)
+	v.CourseOverGround = clone(p.CourseOverGround)
+	v.SpeedOverGround = clone(p.SpeedOverGround)
+	v.RateOfTurn = clone(p.RateOfTurn)
+	v.TrueHeading = clone(p.TrueHeading)
+	v.NavigationalStatus = &p.NavigationalStatus
+	v.AisClass = p.AisClass
+}
+
+// UpdateStaticdata updates the vessel with the fields of a static data report. A zero-valued Staticdata, and a static
+// data report older than the latest one the vessel was updated with, are ignored.
+func (v *Vessel) UpdateStaticdata(s Staticdata) {
+	if s.IsZero() || s.Msgtime.Before(v.StaticdataTime) {
+		return
+	}
+	v.Mmsi = s.Mmsi
+	v.StaticdataTime = s.Msgtime
+	v.updateMsgtime(s.Msgtime)
+	v.Name = s.Name
+	v.ShipType = clone(s.ShipType)
+	v.ImoNumber = clone(s.ImoNumber)
+	v.CallSign = s.CallSign
+	v.Destination = s.Destination
+	v.Eta = s.Eta
+	v.Draught = clone(s.Draught)
+	v.ShipLength = clone(s.ShipLength)
+	v.ShipWidth = clone(s.ShipWidth)
+	v.DimensionA = clone(s.DimensionA)
+	v.DimensionB = clone(s.DimensionB)
+	v.DimensionC = clone(s.DimensionC)
+	v.DimensionD = clone(s.DimensionD)
+	v.PositionFixingDeviceType = s.PositionFixingDeviceType
+	v.AisClass = s.ReportClass
+}
+
+func (v *Vessel) updateMsgtime(t time.Time) {
+	if t.After(v.Msgtime) {
+		v.Msgtime = t
+	}
+}
+
+// orTime returns t, or fallback if t is zero.
+func orTime(t, fallback time.Time) time.Time {
+	if t.IsZero() {
+		return fallback
+	}
+	return t
+}
+
+// clone returns a pointer to a copy of the value p points to, so that converted values do not share memory.
+func clone[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+// deref returns the value p points to, or the zero value if p is nil.
+func deref[T any](p *T) T {
+	var v T
+	if p != nil {
+		v = *p
+	}
+	return v
+}
+
+// Vessel returns the vessel described by the message. Aton messages, and messages of unknown type, return a
+// zero-valued Vessel.
+func (a AisMultiple) Vessel() Vessel {
+	var v Vessel
+	v.Update(a)
+	return v
+}
+
+// Vessel returns the vessel described by the position report.
+func (a Position) Vessel() Vessel {
+	return NewVessel(a, Staticdata{})
+}
+
+// Vessel returns the vessel described by the static data report.
+func (a Staticdata) Vessel() Vessel {
+	return NewVessel(Position{}, a)
+}
+
+// Vessel returns the vessel described by the message, according to its Type. Messages of unknown type return a
+// zero-valued Vessel.
+func (c CombinedMultiple) Vessel() Vessel {
+	switch c.Type {
+	case responsetype.SimpleJson:
+		return c.CombinedSimpleJson.Vessel()
+	case responsetype.FullJson:
+		return c.CombinedFullJson.Vessel()
+	case responsetype.SimpleGeojson:
+		return c.CombinedSimpleGeojson.Vessel()
+	case responsetype.FullGeojson:
+		return c.CombinedFullGeojson.Vessel()
+	default:
+		return Vessel{}
+	}
+}
+
+// Vessel returns the vessel described by the message.
+func (a CombinedSimpleJson) Vessel() Vessel {
+	return Vessel{
+		Mmsi:             a.Mmsi,
+		Msgtime:          a.Msgtime,
+		PositionTime:     a.Msgtime,
+		StaticdataTime:   a.Msgtime,
+		Name:             a.Name,
+		Latitude:         clone(a.Latitude),
+		Longitude:        clone(a.Longitude),
+		CourseOverGround: clone(a.CourseOverGround),
+		SpeedOverGround:  clone(a.SpeedOverGround),
+		RateOfTurn:       clone(a.RateOfTurn),
+		TrueHeading:      clone(a.TrueHeading),
+		ShipType:         clone(a.ShipType),
+	}
+}
+
+// Vessel returns the vessel described by the message.
+func (a CombinedFullJson) Vessel() Vessel {
+	return Vessel{
+		Mmsi:                     a.Mmsi,
+		Msgtime:                  a.Msgtime,
+		PositionTime:             a.Msgtime,
+		StaticdataTime:           a.Msgtime,
+		Name:                     a.Name,
+		Latitude:                 clone(a.Latitude),
+		Longitude:                clone(a.Longitude),
+		Altitude:                 clone(a.Altitude),
+		CourseOverGround:         clone(a.CourseOverGround),
+		SpeedOverGround:          clone(a.SpeedOverGround),
+		RateOfTurn:               clone(a.RateOfTurn),
+		TrueHeading:              clone(a.TrueHeading),
+		NavigationalStatus:       &a.NavigationalStatus,
+		ShipType:                 clone(a.ShipType),
+		ImoNumber:                clone(a.ImoNumber),
+		CallSign:                 a.CallSign,
+		Destination:              a.Destination,
+		Eta:                      a.Eta,
+		Draught:                  clone(a.Draught),
+		ShipLength:               clone(a.ShipLength),
+		ShipWidth:                clone(a.ShipWidth),
+		DimensionA:               clone(a.DimensionA),
+		DimensionB:               clone(a.DimensionB),
+		DimensionC:               clone(a.DimensionC),
+		DimensionD:               clone(a.DimensionD),
+		PositionFixingDeviceType: a.PositionFixingDeviceType,
+		AisClass:                 a.ReportClass,
+	}
+}
+
+// Vessel returns the vessel described by the message.
+func (a CombinedSimpleGeojson) Vessel() Vessel {
+	p := a.Properties
+	v := Vessel{
+		Mmsi:             p.Mmsi,
+		Msgtime:          p.Msgtime,
+		PositionTime:     p.Msgtime,
+		StaticdataTime:   p.Msgtime,
+		Name:             p.Name,
+		CourseOverGround: clone(p.CourseOverGround),
+		SpeedOverGround:  clone(p.SpeedOverGround),
+		RateOfTurn:       clone(p.RateOfTurn),
+		TrueHeading:      clone(p.TrueHeading),
+		ShipType:         clone(p.ShipType),
+	}
+	v.Longitude, v.Latitude = fromCoordinates(a.Geometry.Coordinates)
+	return v
+}
+
+// Vessel returns the vessel described by the message.
+func (a CombinedFullGeojson) Vessel() Vessel {
+	p := a.Properties
+	v := Vessel{
+		Mmsi:                     p.Mmsi,
+		Msgtime:                  p.Msgtime,
+		PositionTime:             p.Msgtime,
+		StaticdataTime:           p.Msgtime,
+		Name:                     p.Name,
+		CourseOverGround:         clone(p.CourseOverGround),
+		SpeedOverGround:          clone(p.SpeedOverGround),
+		RateOfTurn:               clone(p.RateOfTurn),
+		TrueHeading:              clone(p.TrueHeading),
+		NavigationalStatus:       &p.NavigationalStatus,
+		ShipType:                 clone(p.ShipType),
+		ImoNumber:                clone(p.ImoNumber),
+		CallSign:                 p.CallSign,
+		Destination:              p.Destination,
+		Eta:                      p.Eta,
+		Draught:                  clone(p.Draught),
+		ShipLength:               clone(p.ShipLength),
+		ShipWidth:                clone(p.ShipWidth),
+		DimensionA:               clone(p.DimensionA),
+		DimensionB:               clone(p.DimensionB),
+		DimensionC:               clone(p.DimensionC),
+		DimensionD:               clone(p.DimensionD),
+		PositionFixingDeviceType: p.PositionFixingDeviceType,
+		AisClass:                 p.ReportClass,
+	}
+	v.Longitude, v.Latitude = fromCoordinates(a.Geometry.Coordinates)
+	return v
+}
+
+// fromCoordinates returns the longitude and latitude of a GeoJSON point, or nil if the point has no coordinates.
+func fromCoordinates(coordinates []float64) (lon, lat *float64) {
+	if len(coordinates) < 2 {
+		return nil, nil
+	}
+	lonValue, latValue := coordinates[0], coordinates[1]
+	return &lonValue, &latValue
+}
+
+// coordinates returns the GeoJSON coordinates of the vessel's position, or nil if the position is unknown.
+func (v Vessel) coordinates() []float64 {
+	if v.Longitude == nil || v.Latitude == nil {
+		return nil
+	}
+	return []float64{*v.Longitude, *v.Latitude}
+}
+
+// AsPosition converts the vessel to a position report, at PositionTime if known and otherwise at Msgtime. The
+// MessageType of the report is zero.
+func (v Vessel) AsPosition() Position {
+	return Position{
+		Mmsi:               v.Mmsi,
+		Msgtime:            orTime(v.PositionTime, v.Msgtime),
+		Altitude:           clone(v.Altitude),
+		Longitude:          clone(v.Longitude),
+		Latitude:           clone(v.Latitude),
+		CourseOverGround:   clone(v.CourseOverGround),
+		AisClass:           v.AisClass,
+		NavigationalStatus: deref(v.NavigationalStatus),
+		RateOfTurn:         clone(v.RateOfTurn),
+		SpeedOverGround:    clone(v.SpeedOverGround),
+		TrueHeading:        clone(v.TrueHeading),
+	}
+}
+
+// AsStaticdata converts the vessel to a static data report, at StaticdataTime if known and otherwise at Msgtime. The
+// MessageType of the report is zero.
+func (v Vessel) AsStaticdata() Staticdata {
+	return Staticdata{
+		Mmsi:                     v.Mmsi,
+		Msgtime:                  orTime(v.StaticdataTime, v.Msgtime),
+		Name:                     v.Name,
+		DimensionA:               clone(v.DimensionA),
+		DimensionB:               clone(v.DimensionB),
+		DimensionC:               clone(v.DimensionC),
+		DimensionD:               clone(v.DimensionD),
+		ImoNumber:                clone(v.ImoNumber),
+		CallSign:                 v.CallSign,
+		Destination:              v.Destination,
+		Eta:                      v.Eta,
+		Draught:                  clone(v.Draught),
+		ShipLength:               clone(v.ShipLength),
+		ShipWidth:                clone(v.ShipWidth),
+		ShipType:                 clone(v.ShipType),
+		PositionFixingDeviceType: v.PositionFixingDeviceType,
+		ReportClass:              v.AisClass,
+	}
+}
+
+// AsSimpleJson converts the vessel to a CombinedSimpleJson message.
+func (v Vessel) AsSimpleJson() CombinedSimpleJson {
+	return CombinedSimpleJson{
+		CourseOverGround: clone(v.CourseOverGround),
+		Latitude:         clone(v.Latitude),
+		Longitude:        clone(v.Longitude),
+		Name:             v.Name,
+		RateOfTurn:       clone(v.RateOfTurn),
+		ShipType:         clone(v.ShipType),
+		SpeedOverGround:  clone(v.SpeedOverGround),
+		TrueHeading:      clone(v.TrueHeading),
+		Mmsi:             v.Mmsi,
+		Msgtime:          v.Msgtime,
+	}
+}
+
+// AsFullJson converts the vessel to a CombinedFullJson message.
+func (v Vessel) AsFullJson() CombinedFullJson {
+	return CombinedFullJson{
+		CourseOverGround:         clone(v.CourseOverGround),
+		Latitude:                 clone(v.Latitude),
+		Longitude:                clone(v.Longitude),
+		Name:                     v.Name,
+		RateOfTurn:               clone(v.RateOfTurn),
+		ShipType:                 clone(v.ShipType),
+		SpeedOverGround:          clone(v.SpeedOverGround),
+		TrueHeading:              clone(v.TrueHeading),
+		Mmsi:                     v.Mmsi,
+		Msgtime:                  v.Msgtime,
+		Altitude:                 clone(v.Altitude),
+		NavigationalStatus:       deref(v.NavigationalStatus),
+		ImoNumber:                clone(v.ImoNumber),
+		CallSign:                 v.CallSign,
+		Destination:              v.Destination,
+		Eta:                      v.Eta,
+		Draught:                  clone(v.Draught),
+		ShipLength:               clone(v.ShipLength),
+		ShipWidth:                clone(v.ShipWidth),
+		DimensionA:               clone(v.DimensionA),
+		DimensionB:               clone(v.DimensionB),
+		DimensionC:               clone(v.DimensionC),
+		DimensionD:               clone(v.DimensionD),
+		PositionFixingDeviceType: v.PositionFixingDeviceType,
+		ReportClass:              v.AisClass,
+	}
+}
+
+// AsSimpleGeojson converts the vessel to a CombinedSimpleGeojson feature. If the position of the vessel is unknown,
+// the feature's geometry has no coordinates.
+func (v Vessel) AsSimpleGeojson() CombinedSimpleGeojson {
+	var g CombinedSimpleGeojson
+	g.Type = "Feature"
+	g.Geometry.Type = "Point"
+	g.Geometry.Coordinates = v.coordinates()
+
+	p := &g.Properties
+	p.Mmsi = v.Mmsi
+	p.Name = v.Name
+	p.Msgtime = v.Msgtime
+	p.SpeedOverGround = clone(v.SpeedOverGround)
+	p.CourseOverGround = clone(v.CourseOverGround)
+	p.RateOfTurn = clone(v.RateOfTurn)
+	p.ShipType = clone(v.ShipType)
+	p.TrueHeading = clone(v.TrueHeading)
+	return g
+}
+
+// AsFullGeojson converts the vessel to a CombinedFullGeojson feature. If the position of the vessel is unknown, the
+// feature's geometry has no coordinates.
+func (v Vessel) AsFullGeojson() CombinedFullGeojson {
+	var g CombinedFullGeojson
+	g.Type = "Feature"
+	g.Geometry.Type = "Point"
+	g.Geometry.Coordinates = v.coordinates()
+
+	p := &g.Properties
+	p.Mmsi = v.Mmsi
+	p.Name = v.Name
+	p.Msgtime = v.Msgtime
+	p.SpeedOverGround = clone(v.SpeedOverGround)
+	p.CourseOverGround = clone(v.CourseOverGround)
+	p.NavigationalStatus = deref(v.NavigationalStatus)
+	p.RateOfTurn = clone(v.RateOfTurn)
+	p.ShipType = clone(v.ShipType)
+	p.TrueHeading = clone(v.TrueHeading)
+	p.CallSign = v.CallSign
+	p.Destination = v.Destination
+	p.Eta = v.Eta
+	p.ImoNumber = clone(v.ImoNumber)
+	p.DimensionA = clone(v.DimensionA)
+	p.DimensionB = clone(v.DimensionB)
+	p.DimensionC = clone(v.DimensionC)
+	p.DimensionD = clone(v.DimensionD)
+	p.Draught = clone(v.Draught)
+	p.ShipLength = clone(v.ShipLength)
+	p.ShipWidth = clone(v.ShipWidth)
+	p.PositionFixingDeviceType = v.PositionFixingDeviceType
+	p.ReportClass = v.AisClass
+	return g
+}
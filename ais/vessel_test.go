@@ -0,0 +1,196 @@
+package ais_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/navmath"
+	"github.com/ilder-as/go-barentswatch-ais/responsetype"
+)
+
+// decodeFixture decodes every line of a fixture into a T.
+func decodeFixture[T any](t *testing.T, filename string) []T {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var res []T
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var obj T
+		if err := json.Unmarshal(scanner.Bytes(), &obj); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, obj)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestVessel_RoundTrip(t *testing.T) {
+	for _, msg := range decodeFixture[ais.CombinedSimpleJson](t, "testdata/combined_simple_json.txt") {
+		if res := msg.Vessel().AsSimpleJson(); !reflect.DeepEqual(res, msg) {
+			t.Fatalf("expected %+v, got %+v", msg, res)
+		}
+	}
+	for _, msg := range decodeFixture[ais.CombinedFullJson](t, "testdata/combined_full_json.txt") {
+		if res := msg.Vessel().AsFullJson(); !reflect.DeepEqual(res, msg) {
+			t.Fatalf("expected %+v, got %+v", msg, res)
+		}
+	}
+	for _, msg := range decodeFixture[ais.CombinedSimpleGeojson](t, "testdata/combined_simple_geojson.txt") {
+		if res := msg.Vessel().AsSimpleGeojson(); !reflect.DeepEqual(res, msg) {
+			t.Fatalf("expected %+v, got %+v", msg, res)
+		}
+	}
+	for _, msg := range decodeFixture[ais.CombinedFullGeojson](t, "testdata/combined_full_geojson.txt") {
+		if res := msg.Vessel().AsFullGeojson(); !reflect.DeepEqual(res, msg) {
+			t.Fatalf("expected %+v, got %+v", msg, res)
+		}
+	}
+
+	for _, msg := range decodeFixture[ais.AisMultiple](t, "testdata/get_ais.txt") {
+		v := msg.Vessel()
+		switch msg.Type {
+		case responsetype.Position:
+			expected := msg.Position
+			expected.MessageType = 0
+			if res := v.AsPosition(); !reflect.DeepEqual(res, expected) {
+				t.Fatalf("expected %+v, got %+v", expected, res)
+			}
+		case responsetype.Staticdata:
+			expected := msg.Staticdata
+			expected.MessageType = 0
+			if res := v.AsStaticdata(); !reflect.DeepEqual(res, expected) {
+				t.Fatalf("expected %+v, got %+v", expected, res)
+			}
+		case responsetype.Aton:
+			if !reflect.ValueOf(v).IsZero() {
+				t.Fatalf("expected Aton to give zero-valued vessel, got %+v", v)
+			}
+		}
+	}
+}
+
+func TestVessel_Geojson(t *testing.T) {
+	msgs := decodeFixture[ais.CombinedMultiple](t, "testdata/combined_full_geojson.txt")
+	msg := msgs[0]
+	v := msg.Vessel()
+	if v.Longitude == nil || *v.Longitude != msg.AsFullGeojson().Geometry.Coordinates[0] ||
+		v.Latitude == nil || *v.Latitude != msg.AsFullGeojson().Geometry.Coordinates[1] {
+		t.Fatalf("expected coordinates %v, got %v, %v", msg.AsFullGeojson().Geometry.Coordinates, v.Longitude, v.Latitude)
+	}
+
+	// The vessel does not share memory with the message
+	*v.Longitude = 0
+	if msg.AsFullGeojson().Geometry.Coordinates[0] == 0 {
+		t.Error("expected vessel not to share coordinates with the message")
+	}
+
+	full := v.AsFullJson()
+	if *full.Longitude != 0 || *full.Latitude != *v.Latitude || full.Mmsi != msg.AsFullGeojson().Properties.Mmsi {
+		t.Errorf("unexpected conversion to full JSON %+v", full)
+	}
+
+	v.Latitude = nil
+	if c := v.AsSimpleGeojson().Geometry.Coordinates; c != nil {
+		t.Errorf("expected no coordinates for unknown position, got %v", c)
+	}
+}
+
+func TestNewVessel(t *testing.T) {
+	var position, static ais.AisMultiple
+	for _, msg := range decodeFixture[ais.AisMultiple](t, "testdata/get_ais.txt") {
+		if msg.Type == responsetype.Staticdata && static.IsZero() {
+			static = msg
+		}
+	}
+	for _, msg := range decodeFixture[ais.AisMultiple](t, "testdata/get_ais.txt") {
+		if msg.Type == responsetype.Position && msg.Position.Mmsi == static.Staticdata.Mmsi {
+			position = msg
+		}
+	}
+	if position.IsZero() {
+		// Make up a position report for the vessel
+		lat, lon := 60.0, 5.0
+		position = ais.AisMultiple{Type: responsetype.Position, Position: ais.Position{
+			Mmsi:      static.Staticdata.Mmsi,
+			Msgtime:   static.Staticdata.Msgtime.Add(-1),
+			Latitude:  &lat,
+			Longitude: &lon,
+		}}
+	}
+
+	v := ais.NewVessel(position.Position, static.Staticdata)
+	if v.Mmsi != static.Staticdata.Mmsi || v.Name != static.Staticdata.Name || v.Latitude == nil {
+		t.Errorf("expected vessel to combine position and static data, got %+v", v)
+	}
+	latest := static.Staticdata.Msgtime
+	if position.Position.Msgtime.After(latest) {
+		latest = position.Position.Msgtime
+	}
+	if !v.Msgtime.Equal(latest) {
+		t.Errorf("expected time of latest message %s, got %s", latest, v.Msgtime)
+	}
+
+	var updated ais.Vessel
+	updated.Update(static)
+	updated.Update(position)
+	if !reflect.DeepEqual(updated, v) {
+		t.Errorf("expected updating with messages to equal NewVessel, got %+v and %+v", updated, v)
+	}
+}
+
+func TestVessel_DistanceTo(t *testing.T) {
+	lat, lon := 70.0, 20.0
+	v := ais.Vessel{Latitude: &lat, Longitude: &lon}
+	atonLat, atonLon := 71.0, 20.0
+	aton := ais.Aton{Latitude: &atonLat, Longitude: &atonLon}
+
+	if d, ok := v.DistanceTo(aton); !ok || math.Abs(d-111195) > 1 {
+		t.Errorf("expected a distance of a degree, got %f, %v", d, ok)
+	}
+	if b, ok := aton.BearingTo(v); !ok || math.Abs(b-180) > 1e-9 {
+		t.Errorf("expected a bearing of 180, got %f, %v", b, ok)
+	}
+	if _, ok := v.DistanceTo(ais.Position{Latitude: &lat}); ok {
+		t.Error("expected no distance to a position without coordinates")
+	}
+	if d, ok := (ais.CombinedSimpleJson{Latitude: &lat, Longitude: &lon}).DistanceTo(navmath.Point{Latitude: 70, Longitude: 20}); !ok || d != 0 {
+		t.Errorf("expected no distance to the same point, got %f, %v", d, ok)
+	}
+}
+
+func TestVessel_OutOfOrder(t *testing.T) {
+	noon := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	newLat, oldLat := 70.5, 70.0
+	var v ais.Vessel
+	v.UpdatePosition(ais.Position{Mmsi: 1, Msgtime: noon, Latitude: &newLat})
+	v.UpdateStaticdata(ais.Staticdata{Mmsi: 1, Msgtime: noon.Add(time.Minute), Destination: "TROMSO"})
+
+	// A delayed position report and static data report arrive after newer ones
+	v.UpdatePosition(ais.Position{Mmsi: 1, Msgtime: noon.Add(-time.Minute), Latitude: &oldLat})
+	v.UpdateStaticdata(ais.Staticdata{Mmsi: 1, Msgtime: noon, Destination: "BODO"})
+	if *v.Latitude != newLat || v.Destination != "TROMSO" {
+		t.Errorf("expected older reports to be ignored, got latitude %f and destination %s", *v.Latitude, v.Destination)
+	}
+
+	// A position report older than the latest static data, but newer than the latest position, is applied
+	v.UpdatePosition(ais.Position{Mmsi: 1, Msgtime: noon.Add(30 * time.Second), Latitude: &oldLat})
+	if *v.Latitude != oldLat || !v.PositionTime.Equal(noon.Add(30*time.Second)) || !v.Msgtime.Equal(noon.Add(time.Minute)) {
+		t.Errorf("expected the position to be updated, got %+v", v)
+	}
+	if p := v.AsPosition(); !p.Msgtime.Equal(v.PositionTime) {
+		t.Errorf("expected the position report at the time of the position, got %v", p.Msgtime)
+	}
+}
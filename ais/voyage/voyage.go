@@ -0,0 +1,340 @@
+// Package voyage splits the position history of vessels into trips, and summarises every trip for fuel and emission
+// reports.
+//
+// A Segmenter follows the positions of every vessel, and ends a trip when the vessel stops for long enough or when
+// its positions have a long gap. Every trip is summarised with the distance sailed, its duration, its mean and
+// maximum speed over ground, where it started and ended, and the static data which was in effect during it:
+//
+//	s := voyage.NewSegmenter()
+//	var trips []voyage.Trip
+//	for _, msg := range msgs {
+//		trips = append(trips, s.Add(msg.Vessel())...)
+//	}
+//	trips = append(trips, s.Flush()...)
+//	err := voyage.WriteCSV(f, trips)
+package voyage
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/navmath"
+)
+
+// Point is a position of a vessel at a time.
+type Point struct {
+	Time      time.Time `json:"time"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+}
+
+// Static is the static data of a vessel which was in effect from a time during a trip.
+type Static struct {
+	Time        time.Time `json:"time"`
+	Destination string    `json:"destination"`
+	Eta         string    `json:"eta"`
+	// Draught is the draught of the vessel in decimetres, if known.
+	Draught *int `json:"draught"`
+}
+
+// Trip is a voyage of a vessel between two stops.
+type Trip struct {
+	Mmsi int    `json:"mmsi"`
+	Name string `json:"name"`
+	// ShipType is the ship type of the vessel, if it is known.
+	ShipType *int  `json:"shipType"`
+	Start    Point `json:"start"`
+	End      Point `json:"end"`
+	// Distance is the great-circle distance sailed between the positions of the trip, in nautical miles.
+	Distance float64 `json:"distance"`
+	// MeanSpeed and MaxSpeed are the mean and maximum speed over ground reported during the trip, in knots.
+	MeanSpeed float64 `json:"meanSpeed"`
+	MaxSpeed  float64 `json:"maxSpeed"`
+	// Positions is the number of positions in the trip.
+	Positions int `json:"positions"`
+	// Static is the static data in effect at the start of the trip, if known, followed by every change of
+	// destination, ETA or draught during it.
+	Static []Static `json:"static"`
+
+	speedSum float64
+	speeds   int
+}
+
+// Duration returns the time from the start to the end of the trip.
+func (t Trip) Duration() time.Duration {
+	return t.End.Time.Sub(t.Start.Time)
+}
+
+// MarshalJSON marshals the trip with its duration in seconds, as "duration".
+func (t Trip) MarshalJSON() ([]byte, error) {
+	type trip Trip
+	return json.Marshal(struct {
+		trip
+		Duration float64 `json:"duration"`
+	}{trip(t), t.Duration().Seconds()})
+}
+
+func (t *Trip) add(p Point, sog *float64) {
+	if t.Positions > 0 {
+		t.Distance += distance(t.End, p)
+	}
+	t.End = p
+	t.Positions++
+	if sog != nil {
+		t.speedSum += *sog
+		t.speeds++
+		t.MaxSpeed = max(t.MaxSpeed, *sog)
+		t.MeanSpeed = t.speedSum / float64(t.speeds)
+	}
+}
+
+// distance returns the great-circle distance between the points in nautical miles.
+func distance(a, b Point) float64 {
+	return navmath.MetresToNauticalMiles(navmath.Distance(
+		navmath.Point{Latitude: a.Latitude, Longitude: a.Longitude},
+		navmath.Point{Latitude: b.Latitude, Longitude: b.Longitude},
+	))
+}
+
+// Defaults of Segmenter.
+const (
+	DefaultStopSpeed = 0.5
+	DefaultStopTime  = 30 * time.Minute
+	DefaultMaxGap    = 2 * time.Hour
+)
+
+// vessel is what a Segmenter knows about a vessel.
+type vessel struct {
+	name     string
+	shipType *int
+	static   *Static
+
+	// positionTime is the time of the latest position, which gaps are measured from, and staticdataTime that of the
+	// latest static data
+	positionTime   time.Time
+	staticdataTime time.Time
+
+	// trip is the trip in progress, if any
+	trip *Trip
+	// stop is the trip as it was at the first position of the stop in progress, if any
+	stop *Trip
+}
+
+// Segmenter splits the positions of vessels into trips.
+//
+// A vessel is stopped when its speed over ground is at most StopSpeed. A trip ends at the first position of a stop
+// which lasts for StopTime, and the next trip starts at the first position after it where the vessel is moving. A
+// trip also ends at the last position before a gap longer than MaxGap, and the next trip starts after the gap.
+//
+// Positions of each vessel must be given in order of time, and late positions are ignored. Gaps are measured between
+// positions alone, so static data received during a gap does not bridge it.
+//
+// A Segmenter must be constructed with the NewSegmenter factory function. It is not safe for concurrent use, and its
+// fields must be set before it is used.
+type Segmenter struct {
+	vessels map[int]*vessel
+
+	// StopSpeed is the highest speed over ground of stopped vessels, in knots. Zero means DefaultStopSpeed.
+	StopSpeed float64
+	// StopTime is how long a vessel must be stopped to end a trip. Zero means DefaultStopTime.
+	StopTime time.Duration
+	// MaxGap is the longest time between positions in a trip. Zero means DefaultMaxGap.
+	MaxGap time.Duration
+	// MinPositions is the smallest number of positions in a trip. Shorter trips are dropped.
+	MinPositions int
+}
+
+// NewSegmenter creates a Segmenter.
+func NewSegmenter() *Segmenter {
+	return &Segmenter{vessels: make(map[int]*vessel)}
+}
+
+// Add updates what the Segmenter knows about the vessel, and returns the trip which ended, if any. Static data
+// updates the name, ship type and static data in effect, and positions extend or end trips.
+func (s *Segmenter) Add(v ais.Vessel) []Trip {
+	vs, ok := s.vessels[v.Mmsi]
+	if !ok {
+		vs = &vessel{}
+		s.vessels[v.Mmsi] = vs
+	}
+	if hasStatic(v) && !v.Msgtime.Before(vs.staticdataTime) {
+		vs.staticdataTime = v.Msgtime
+		s.updateStatic(vs, v)
+	}
+	if v.Latitude == nil || v.Longitude == nil || v.Msgtime.Before(vs.positionTime) {
+		return nil
+	}
+	gap := !vs.positionTime.IsZero() && v.Msgtime.Sub(vs.positionTime) > cmp.Or(s.MaxGap, DefaultMaxGap)
+	vs.positionTime = v.Msgtime
+
+	var res []Trip
+	if gap && vs.trip != nil {
+		res = s.end(vs, vs.ending())
+	}
+
+	p := Point{Time: v.Msgtime, Latitude: *v.Latitude, Longitude: *v.Longitude}
+	stopped := v.SpeedOverGround != nil && *v.SpeedOverGround <= cmp.Or(s.StopSpeed, DefaultStopSpeed)
+	switch {
+	case vs.trip == nil && stopped:
+		return res
+	case vs.trip == nil:
+		vs.trip = &Trip{Mmsi: v.Mmsi, Name: vs.name, ShipType: vs.shipType, Start: p}
+		if vs.static != nil {
+			vs.trip.Static = []Static{*vs.static}
+			vs.trip.Static[0].Time = p.Time
+		}
+	}
+
+	vs.trip.add(p, v.SpeedOverGround)
+	switch {
+	case !stopped:
+		vs.stop = nil
+	case vs.stop == nil:
+		stop := *vs.trip
+		stop.Static = slices.Clone(stop.Static)
+		vs.stop = &stop
+	case p.Time.Sub(vs.stop.End.Time) >= cmp.Or(s.StopTime, DefaultStopTime):
+		res = append(res, s.end(vs, *vs.stop)...)
+	}
+	return res
+}
+
+// hasStatic is true if the vessel carries static data.
+func hasStatic(v ais.Vessel) bool {
+	return v.Name != "" || v.ShipType != nil || v.Destination != "" || v.Eta != "" || v.Draught != nil
+}
+
+func (s *Segmenter) updateStatic(vs *vessel, v ais.Vessel) {
+	if v.Name != "" {
+		vs.name = v.Name
+	}
+	if v.ShipType != nil {
+		vs.shipType = v.ShipType
+	}
+	if v.Destination == "" && v.Eta == "" && v.Draught == nil {
+		return
+	}
+
+	static := Static{Time: v.Msgtime, Destination: v.Destination, Eta: v.Eta, Draught: v.Draught}
+	changed := vs.static == nil || static.Destination != vs.static.Destination || static.Eta != vs.static.Eta ||
+		!equal(static.Draught, vs.static.Draught)
+	vs.static = &static
+	if changed && vs.trip != nil {
+		vs.trip.Static = append(vs.trip.Static, static)
+	}
+	if vs.trip != nil {
+		vs.trip.Name, vs.trip.ShipType = vs.name, vs.shipType
+	}
+}
+
+func equal(a, b *int) bool {
+	return a == b || a != nil && b != nil && *a == *b
+}
+
+// ending returns the trip in progress as it ends when the vessel stops reporting, which is before the stop in progress,
+// if any.
+func (vs *vessel) ending() Trip {
+	if vs.stop != nil {
+		return *vs.stop
+	}
+	return *vs.trip
+}
+
+// end ends the trip in progress as the given trip, and returns it unless it is too short.
+func (s *Segmenter) end(vs *vessel, trip Trip) []Trip {
+	vs.trip, vs.stop = nil, nil
+	// Static data received after the end of the trip is not part of it
+	for len(trip.Static) > 1 && trip.Static[len(trip.Static)-1].Time.After(trip.End.Time) {
+		trip.Static = trip.Static[:len(trip.Static)-1]
+	}
+	if trip.Positions < max(2, s.MinPositions) {
+		return nil
+	}
+	return []Trip{trip}
+}
+
+// Flush ends the trips in progress, and returns them ordered by MMSI.
+func (s *Segmenter) Flush() []Trip {
+	var res []Trip
+	for _, vs := range s.vessels {
+		if vs.trip != nil {
+			res = append(res, s.end(vs, vs.ending())...)
+		}
+	}
+	slices.SortFunc(res, func(a, b Trip) int { return cmp.Compare(a.Mmsi, b.Mmsi) })
+	return res
+}
+
+// WriteJSON writes the trips as a JSON array.
+func WriteJSON(w io.Writer, trips []Trip) error {
+	if trips == nil {
+		trips = []Trip{}
+	}
+	return json.NewEncoder(w).Encode(trips)
+}
+
+// CSVColumns are the names of the columns written by WriteCSV, in order.
+var CSVColumns = []string{
+	"mmsi", "name", "shipType", "startTime", "startLatitude", "startLongitude", "endTime", "endLatitude",
+	"endLongitude", "duration", "distance", "meanSpeed", "maxSpeed", "positions", "destination", "startDraught",
+	"endDraught",
+}
+
+// WriteCSV writes the trips as CSV, with a header of CSVColumns. Times are written in RFC 3339 format, durations in
+// seconds and distances in nautical miles. The destination is the last destination of the trip, and the draughts
+// are the first and last of it, and unknown values are written as empty cells.
+func WriteCSV(w io.Writer, trips []Trip) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVColumns); err != nil {
+		return err
+	}
+
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	formatInt := func(i *int) string {
+		if i == nil {
+			return ""
+		}
+		return strconv.Itoa(*i)
+	}
+	record := make([]string, len(CSVColumns))
+	for _, t := range trips {
+		var destination string
+		var startDraught, endDraught *int
+		if len(t.Static) > 0 {
+			first, last := t.Static[0], t.Static[len(t.Static)-1]
+			destination, startDraught, endDraught = last.Destination, first.Draught, last.Draught
+		}
+		record = append(record[:0],
+			strconv.Itoa(t.Mmsi),
+			t.Name,
+			formatInt(t.ShipType),
+			t.Start.Time.Format(time.RFC3339),
+			formatFloat(t.Start.Latitude),
+			formatFloat(t.Start.Longitude),
+			t.End.Time.Format(time.RFC3339),
+			formatFloat(t.End.Latitude),
+			formatFloat(t.End.Longitude),
+			formatFloat(t.Duration().Seconds()),
+			formatFloat(t.Distance),
+			formatFloat(t.MeanSpeed),
+			formatFloat(t.MaxSpeed),
+			strconv.Itoa(t.Positions),
+			destination,
+			formatInt(startDraught),
+			formatInt(endDraught),
+		)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
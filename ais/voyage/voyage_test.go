@@ -0,0 +1,173 @@
+package voyage_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ilder-as/go-barentswatch-ais/ais"
+	"github.com/ilder-as/go-barentswatch-ais/ais/internal/aistest"
+	"github.com/ilder-as/go-barentswatch-ais/ais/voyage"
+)
+
+// sail returns a position report of vessel 1.
+func sail(after time.Duration, lon, lat, sog float64) ais.Position {
+	return aistest.Position(1, after, lon, lat, sog)
+}
+
+// static returns a static data report of vessel 1 with the destination and draught.
+func static(after time.Duration, destination string, draught int) ais.Staticdata {
+	s := aistest.Staticdata(1, after, "NORDSTJERNEN")
+	s.Destination, s.Draught = destination, &draught
+	return s
+}
+
+func TestSegmenter(t *testing.T) {
+	s := voyage.NewSegmenter()
+	msgs := []aistest.Message{
+		static(0, "BODO", 50),
+		// Moored before the first trip
+		sail(0, 14.4, 67.28, 0),
+		sail(60*time.Minute, 14.4, 67.28, 0),
+		// Sails north along the meridian, one minute of latitude (one nautical mile) every 6 minutes, with a brief
+		// stop which does not end the trip
+		sail(70*time.Minute, 14.4, 67.30, 10),
+		sail(76*time.Minute, 14.4, 67.30+1.0/60, 12),
+		sail(82*time.Minute, 14.4, 67.30+2.0/60, 0.2),
+		sail(90*time.Minute, 14.4, 67.30+2.0/60, 8),
+		static(95*time.Minute, "SVOLVAER", 52),
+		sail(100*time.Minute, 14.4, 67.30+3.0/60, 0),
+		// Stops, which ends the trip at the first position of the stop
+		sail(120*time.Minute, 14.4, 67.30+3.0/60, 0),
+		sail(140*time.Minute, 14.4, 67.30+3.0/60, 0),
+		// A second trip, ended by a gap
+		sail(150*time.Minute, 14.4, 67.40, 10),
+		sail(160*time.Minute, 14.4, 67.45, 10),
+		sail(400*time.Minute, 14.4, 68.00, 10),
+		sail(410*time.Minute, 14.4, 68.05, 10),
+		// Another vessel, still sailing
+		aistest.Position(2, 0, 5, 60, 10),
+		aistest.Position(2, 10*time.Minute, 5, 60.1, 11),
+	}
+
+	var trips []voyage.Trip
+	for _, msg := range msgs {
+		trips = append(trips, s.Add(msg.Vessel())...)
+	}
+	trips = append(trips, s.Flush()...)
+	if len(trips) != 4 {
+		t.Fatalf("expected 4 trips, got %+v", trips)
+	}
+
+	first := trips[0]
+	if !first.Start.Time.Equal(aistest.Start.Add(70*time.Minute)) ||
+		!first.End.Time.Equal(aistest.Start.Add(100*time.Minute)) || first.Duration() != 30*time.Minute ||
+		first.Positions != 5 {
+		t.Errorf("unexpected start, end or positions of trip %+v", first)
+	}
+	if math.Abs(first.Distance-3) > 0.01 {
+		t.Errorf("expected a distance of 3 nautical miles, got %f", first.Distance)
+	}
+	if first.MaxSpeed != 12 || math.Abs(first.MeanSpeed-6.04) > 1e-9 {
+		t.Errorf("expected maximum speed 12 and mean speed 6.04, got %f and %f", first.MaxSpeed, first.MeanSpeed)
+	}
+	if first.Name != "NORDSTJERNEN" || len(first.Static) != 2 || first.Static[0].Destination != "BODO" ||
+		first.Static[1].Destination != "SVOLVAER" || *first.Static[1].Draught != 52 {
+		t.Errorf("unexpected static data %+v", first.Static)
+	}
+
+	second := trips[1]
+	if second.Positions != 2 || !second.End.Time.Equal(aistest.Start.Add(160*time.Minute)) || len(second.Static) != 1 {
+		t.Errorf("expected the second trip to end before the gap, got %+v", second)
+	}
+	if trips[2].Mmsi != 1 || trips[2].Positions != 2 || trips[3].Mmsi != 2 {
+		t.Errorf("expected the trips in progress to be flushed, got %+v", trips[2:])
+	}
+
+	var buf bytes.Buffer
+	if err := voyage.WriteJSON(&buf, trips[:1]); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[0]["duration"] != 1800.0 || decoded[0]["mmsi"] != 1.0 {
+		t.Errorf("unexpected JSON %s", buf.Bytes())
+	}
+
+	buf.Reset()
+	if err := voyage.WriteCSV(&buf, trips); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || records[1][9] != "1800" || records[1][14] != "SVOLVAER" || records[1][15] != "50" ||
+		records[1][16] != "52" || records[4][15] != "" {
+		t.Errorf("unexpected records %v", records)
+	}
+}
+
+func TestSegmenter_Gap(t *testing.T) {
+	// Static data keeps being received while no positions are, which does not bridge the gap
+	msgs := []aistest.Message{sail(0, 14.4, 67.30, 10), sail(10*time.Minute, 14.4, 67.35, 10)}
+	for at := 12 * time.Minute; at < 10*time.Hour; at += 6 * time.Minute {
+		msgs = append(msgs, static(at, "SVOLVAER", 52))
+	}
+	msgs = append(msgs, sail(10*time.Hour, 14.4, 68.00, 10), sail(10*time.Hour+10*time.Minute, 14.4, 68.05, 10))
+
+	s := voyage.NewSegmenter()
+	var trips []voyage.Trip
+	for _, msg := range msgs {
+		trips = append(trips, s.Add(msg.Vessel())...)
+	}
+	trips = append(trips, s.Flush()...)
+	if len(trips) != 2 || !trips[0].End.Time.Equal(aistest.Start.Add(10*time.Minute)) ||
+		!trips[1].Start.Time.Equal(aistest.Start.Add(10*time.Hour)) {
+		t.Fatalf("expected the gap to split the trip, got %+v", trips)
+	}
+}
+
+func TestSegmenter_StopBeforeGap(t *testing.T) {
+	// The vessel stops for less than StopTime before it stops reporting, which ends the trip where it stopped
+	s := voyage.NewSegmenter()
+	var trips []voyage.Trip
+	for _, msg := range []ais.Position{
+		sail(0, 14.4, 67.30, 10),
+		sail(10*time.Minute, 14.4, 67.35, 10),
+		sail(20*time.Minute, 14.4, 67.40, 0),
+		sail(25*time.Minute, 14.4, 67.40, 0),
+		sail(5*time.Hour, 14.4, 68.00, 10),
+		sail(5*time.Hour+10*time.Minute, 14.4, 68.05, 10),
+	} {
+		trips = append(trips, s.Add(msg.Vessel())...)
+	}
+	if len(trips) != 1 || trips[0].Positions != 3 || !trips[0].End.Time.Equal(aistest.Start.Add(20*time.Minute)) {
+		t.Fatalf("expected the trip to end at the first position of the stop, got %+v", trips)
+	}
+}
+
+func TestSegmenter_Order(t *testing.T) {
+	s := voyage.NewSegmenter()
+	var trips []voyage.Trip
+	for _, msg := range []aistest.Message{
+		static(time.Hour, "SVOLVAER", 52),
+		static(30*time.Minute, "BODO", 50),
+		sail(0, 14.4, 67.30, 10),
+		sail(10*time.Minute, 14.4, 67.35, 10),
+	} {
+		trips = append(trips, s.Add(msg.Vessel())...)
+	}
+	trips = append(trips, s.Flush()...)
+	if len(trips) != 1 || trips[0].Positions != 2 {
+		t.Fatalf("expected a trip of both positions, got %+v", trips)
+	}
+	if len(trips[0].Static) != 1 || trips[0].Static[0].Destination != "SVOLVAER" {
+		t.Errorf("expected the older static data to be ignored, got %+v", trips[0].Static)
+	}
+}
@@ -0,0 +1,237 @@
+package countrycode
+
+// countries is the table backing all lookups in this package. Where several entries share an ISO code or MID, the
+// first entry takes precedence in reverse lookups.
+var countries = []country{
+	{Afghanistan, "AFG", 4, "Afghanistan", []int{401}},
+	{Albania, "ALB", 8, "Albania", []int{201}},
+	{Algeria, "DZA", 12, "Algeria", []int{605}},
+	{AmericanSamoa, "ASM", 16, "American Samoa", []int{559}},
+	{Andorra, "AND", 20, "Andorra", []int{202}},
+	{Angola, "AGO", 24, "Angola", []int{603}},
+	{Anguilla, "AIA", 660, "Anguilla", []int{301}},
+	{Antarctica, "ATA", 10, "Antarctica", nil},
+	{AntiguaBarbuda, "ATG", 28, "Antigua and Barbuda", []int{304, 305}},
+	{Argentina, "ARG", 32, "Argentina", []int{701}},
+	{Armenia, "ARM", 51, "Armenia", []int{216}},
+	{Aruba, "ABW", 533, "Aruba", []int{307}},
+	// Ascension Island has no ISO 3166-1 codes of its own, being part of St. Helena, Ascension and Tristan da Cunha. IO,
+	// which the API uses for it, is the alpha-2 code of British Indian Ocean Territory, whose other codes do not apply.
+	{AscensionIs, "", 0, "Ascension Island", []int{608}},
+	{Australia, "AUS", 36, "Australia", []int{503}},
+	{Austria, "AUT", 40, "Austria", []int{203}},
+	{Azerbaijan, "AZE", 31, "Azerbaijan", []int{423}},
+	{Bahamas, "BHS", 44, "Bahamas", []int{308, 309, 311}},
+	{Bahrain, "BHR", 48, "Bahrain", []int{408}},
+	{Bangladesh, "BGD", 50, "Bangladesh", []int{405}},
+	{Barbados, "BRB", 52, "Barbados", []int{314}},
+	{Belarus, "BLR", 112, "Belarus", []int{206}},
+	{Belgium, "BEL", 56, "Belgium", []int{205}},
+	{Belize, "BLZ", 84, "Belize", []int{312}},
+	{Benin, "BEN", 204, "Benin", []int{610}},
+	{Bermuda, "BMU", 60, "Bermuda", []int{310}},
+	{Bhutan, "BTN", 64, "Bhutan", []int{410}},
+	{Bolivia, "BOL", 68, "Bolivia", []int{720}},
+	{BosniaAndHerzegovina, "BIH", 70, "Bosnia and Herzegovina", []int{478}},
+	{Botswana, "BWA", 72, "Botswana", []int{611}},
+	{Brazil, "BRA", 76, "Brazil", []int{710}},
+	{BritishVirginIs, "VGB", 92, "British Virgin Islands", []int{378}},
+	{Brunei, "BRN", 96, "Brunei", []int{508}},
+	{Bulgaria, "BGR", 100, "Bulgaria", []int{207}},
+	{BurkinaFaso, "BFA", 854, "Burkina Faso", []int{633}},
+	{Burundi, "BDI", 108, "Burundi", []int{609}},
+	{Cambodia, "KHM", 116, "Cambodia", []int{514, 515}},
+	{Cameroon, "CMR", 120, "Cameroon", []int{613}},
+	{Canada, "CAN", 124, "Canada", []int{316}},
+	{CapeVerde, "CPV", 132, "Cape Verde", []int{617}},
+	{CaymanIs, "CYM", 136, "Cayman Islands", []int{319}},
+	{CenAfrRep, "CAF", 140, "Central African Republic", []int{612}},
+	{Chad, "TCD", 148, "Chad", []int{670}},
+	{Chile, "CHL", 152, "Chile", []int{725}},
+	{China, "CHN", 156, "China", []int{412, 413, 414}},
+	{ChristmasIs, "CXR", 162, "Christmas Islands", []int{516}},
+	{CocosIs, "CCK", 166, "Cocos Islands", []int{523}},
+	{Colombia, "COL", 170, "Colombia", []int{730}},
+	{Comoros, "COM", 174, "Comoros", []int{616, 620}},
+	{Congo, "COG", 178, "Congo", []int{615}},
+	{CookIs, "COK", 184, "Cook Islands", []int{518}},
+	{CostaRica, "CRI", 188, "Costa Rica", []int{321}},
+	{Croatia, "HRV", 191, "Croatia", []int{238}},
+	{Cuba, "CUB", 192, "Cuba", []int{323}},
+	{Curacao, "CUW", 531, "Curacao", []int{306}},
+	{Cyprus, "CYP", 196, "Cyprus", []int{209, 210, 212}},
+	{CzechRepublic, "CZE", 203, "Czech Republic", []int{270}},
+	{DPRKorea, "PRK", 408, "DPR Korea", []int{445}},
+	{DRCongo, "COD", 180, "DR Congo", []int{676}},
+	{Denmark, "DNK", 208, "Denmark", []int{219, 220}},
+	{Djibouti, "DJI", 262, "Djibouti", []int{621}},
+	{Dominica, "DMA", 212, "Dominica", []int{325}},
+	{DominicanRep, "DOM", 214, "Dominican Republic", []int{327}},
+	{Ecuador, "ECU", 218, "Ecuador", []int{735}},
+	{Egypt, "EGY", 818, "Egypt", []int{622}},
+	{ElSalvador, "SLV", 222, "El Salvador", []int{359}},
+	{EquGuinea, "GNQ", 226, "Equatorial Guinea", []int{631}},
+	{Eritrea, "ERI", 232, "Eritrea", []int{625}},
+	{Estonia, "EST", 233, "Estonia", []int{276}},
+	{Ethiopia, "ETH", 231, "Ethiopia", []int{624}},
+	{FYRMacedonia, "MKD", 807, "FYR Macedonia", []int{274}},
+	{FaroeIs, "FRO", 234, "Faroe Islands", []int{231}},
+	{Fiji, "FJI", 242, "Fiji", []int{520}},
+	{Finland, "FIN", 246, "Finland", []int{230}},
+	{France, "FRA", 250, "France", []int{226, 227, 228}},
+	{FrenchPolynesia, "PYF", 258, "French Polynesia", []int{546}},
+	{Gabon, "GAB", 266, "Gabon", []int{626}},
+	{Gambia, "GMB", 270, "Gambia", []int{629}},
+	{Georgia, "GEO", 268, "Georgia", []int{213}},
+	{Germany, "DEU", 276, "Germany", []int{211, 218}},
+	{Ghana, "GHA", 288, "Ghana", []int{627}},
+	{Gibraltar, "GIB", 292, "Gibraltar", []int{236}},
+	{Greece, "GRC", 300, "Greece", []int{237, 239, 240, 241}},
+	{Greenland, "GRL", 304, "Greenland", []int{331}},
+	{Grenada, "GRD", 308, "Grenada", []int{330}},
+	{Guadeloupe, "GLP", 312, "Guadeloupe", []int{329}},
+	{Guatemala, "GTM", 320, "Guatemala", []int{332}},
+	{Guiana, "GUF", 254, "Guiana", []int{745}},
+	{Guinea, "GIN", 324, "Guinea", []int{632}},
+	{GuineaBissau, "GNB", 624, "Guinea Bissau", []int{630}},
+	{Guyana, "GUY", 328, "Guyana", []int{750}},
+	{Haiti, "HTI", 332, "Haiti", []int{336}},
+	{Honduras, "HND", 340, "Honduras", []int{334}},
+	{HongKong, "HKG", 344, "Hong Kong", []int{477}},
+	{Hungary, "HUN", 348, "Hungary", []int{243}},
+	{Iceland, "ISL", 352, "Iceland", []int{251}},
+	{India, "IND", 356, "India", []int{419}},
+	{Indonesia, "IDN", 360, "Indonesia", []int{525}},
+	{Iran, "IRN", 364, "Iran", []int{422}},
+	{Iraq, "IRQ", 368, "Iraq", []int{425}},
+	{Ireland, "IRL", 372, "Ireland", []int{250}},
+	{Israel, "ISR", 376, "Israel", []int{428}},
+	{Italy, "ITA", 380, "Italy", []int{247}},
+	{IvoryCoast, "CIV", 384, "Ivory Coast", []int{619}},
+	{Jamaica, "JAM", 388, "Jamaica", []int{339}},
+	{Japan, "JPN", 392, "Japan", []int{431, 432}},
+	{Jordan, "JOR", 400, "Jordan", []int{438}},
+	{Kazakhstan, "KAZ", 398, "Kazakhstan", []int{436}},
+	{Kenya, "KEN", 404, "Kenya", []int{634}},
+	{Kiribati, "KIR", 296, "Kiribati", []int{529}},
+	{Korea, "KOR", 410, "Korea", []int{440, 441}},
+	{Kuwait, "KWT", 414, "Kuwait", []int{447}},
+	{KyrgyzRepublic, "KGZ", 417, "Kyrgyz Republic", []int{451}},
+	{Laos, "LAO", 418, "Laos", []int{531}},
+	{Latvia, "LVA", 428, "Latvia", []int{275}},
+	{Lebanon, "LBN", 422, "Lebanon", []int{450}},
+	{Lesotho, "LSO", 426, "Lesotho", []int{644}},
+	{Liberia, "LBR", 430, "Liberia", []int{636, 637}},
+	{Libya, "LBY", 434, "Libya", []int{642}},
+	{Liechtenstein, "LIE", 438, "Liechtenstein", []int{252}},
+	{Lithuania, "LTU", 440, "Lithuania", []int{277}},
+	{Luxembourg, "LUX", 442, "Luxembourg", []int{253}},
+	{Macao, "MAC", 446, "Macao", []int{453}},
+	{Madagascar, "MDG", 450, "Madagascar", []int{647}},
+	{Malawi, "MWI", 454, "Malawi", []int{655}},
+	{Malaysia, "MYS", 458, "Malaysia", []int{533}},
+	{Maldives, "MDV", 462, "Maldives", []int{455}},
+	{Mali, "MLI", 466, "Mali", []int{649}},
+	{Malta, "MLT", 470, "Malta", []int{215, 229, 248, 249, 256}},
+	{MarshallIs, "MHL", 584, "Marshall Islands", []int{538}},
+	{Martinique, "MTQ", 474, "Martinique", []int{347}},
+	{Mauritania, "MRT", 478, "Mauritania", []int{654}},
+	{Mauritius, "MUS", 480, "Mauritius", []int{645}},
+	{Mexico, "MEX", 484, "Mexico", []int{345}},
+	{Micronesia, "FSM", 583, "Micronesia", []int{510}},
+	{Moldova, "MDA", 498, "Moldova", []int{214}},
+	{Monaco, "MCO", 492, "Monaco", []int{254}},
+	{Mongolia, "MNG", 496, "Mongolia", []int{457}},
+	{Montenegro, "MNE", 499, "Montenegro", []int{262}},
+	{Montserrat, "MSR", 500, "Montserrat", []int{348}},
+	{Morocco, "MAR", 504, "Morocco", []int{242}},
+	{Mozambique, "MOZ", 508, "Mozambique", []int{650}},
+	{Myanmar, "MMR", 104, "Myanmar", []int{506}},
+	{NMarianaIs, "MNP", 580, "Northern Mariana Islands", []int{536}},
+	{Namibia, "NAM", 516, "Namibia", []int{659}},
+	{Nauru, "NRU", 520, "Nauru", []int{544}},
+	{Nepal, "NPL", 524, "Nepal", []int{459}},
+	{Netherlands, "NLD", 528, "Netherlands", []int{244, 245, 246}},
+	{NewCaledonia, "NCL", 540, "New Caledonia", []int{540}},
+	{NewZealand, "NZL", 554, "New Zealand", []int{512}},
+	{Nicaragua, "NIC", 558, "Nicaragua", []int{350}},
+	{Niger, "NER", 562, "Niger", []int{656}},
+	{Nigeria, "NGA", 566, "Nigeria", []int{657}},
+	{Niue, "NIU", 570, "Niue", []int{542}},
+	{Norway, "NOR", 578, "Norway", []int{257, 258, 259}},
+	{Oman, "OMN", 512, "Oman", []int{461}},
+	{Pakistan, "PAK", 586, "Pakistan", []int{463}},
+	{Palau, "PLW", 585, "Palau", []int{511}},
+	{Palestine, "PSE", 275, "Palestine", []int{443}},
+	{Panama, "PAN", 591, "Panama", []int{351, 352, 353, 354, 355, 356, 357, 370, 371, 372, 373, 374}},
+	{PapuaNewGuinea, "PNG", 598, "Papua New Guinea", []int{553}},
+	{Paraguay, "PRY", 600, "Paraguay", []int{755}},
+	{Peru, "PER", 604, "Peru", []int{760}},
+	{Philippines, "PHL", 608, "Philippines", []int{548}},
+	{PitcairnIs, "PCN", 612, "Pitcairn Islands", []int{555}},
+	{Poland, "POL", 616, "Poland", []int{261}},
+	{Portugal, "PRT", 620, "Portugal", []int{204, 255, 263}},
+	{PuertoRico, "PRI", 630, "Puerto Rico", []int{358}},
+	{Qatar, "QAT", 634, "Qatar", []int{466}},
+	{Reunion, "REU", 638, "Reunion", []int{660}},
+	{Romania, "ROU", 642, "Romania", []int{264}},
+	{Russia, "RUS", 643, "Russia", []int{273}},
+	{Rwanda, "RWA", 646, "Rwanda", []int{661}},
+	{Samoa, "WSM", 882, "Samoa", []int{561}},
+	{SanMarino, "SMR", 674, "San Marino", []int{268}},
+	{SaoTomePrincipe, "STP", 678, "Sao Tome and Principe", []int{668}},
+	{SaudiArabia, "SAU", 682, "Saudi Arabia", []int{403}},
+	{Senegal, "SEN", 686, "Senegal", []int{663}},
+	{Serbia, "SRB", 688, "Serbia", []int{279}},
+	{Seychelles, "SYC", 690, "Seychelles", []int{664}},
+	{SierraLeone, "SLE", 694, "Sierra Leone", []int{667}},
+	{Singapore, "SGP", 702, "Singapore", []int{563, 564, 565, 566}},
+	{Slovakia, "SVK", 703, "Slovakia", []int{267}},
+	{Slovenia, "SVN", 705, "Slovenia", []int{278}},
+	{SolomonIs, "SLB", 90, "Solomon Islands", []int{557}},
+	{Somalia, "SOM", 706, "Somalia", []int{666}},
+	{SouthAfrica, "ZAF", 710, "South Africa", []int{601}},
+	{Spain, "ESP", 724, "Spain", []int{224, 225}},
+	{SriLanka, "LKA", 144, "Sri Lanka", []int{417}},
+	{StHelena, "SHN", 654, "St. Helena", []int{665}},
+	{StKittsNevis, "KNA", 659, "St. Kitts and Nevis", []int{341}},
+	{StLucia, "LCA", 662, "St. Lucia", []int{343}},
+	{StPaulAmsterdamIs, "ATF", 260, "St. Paul Amsterdam Island", []int{501, 607, 618, 635}},
+	{StPierreMiquelon, "SPM", 666, "St. Pierre Miquelon", []int{361}},
+	{StVincentGrenadines, "VCT", 670, "St. Vincent and the Grenadines", []int{375, 376, 377}},
+	{Sudan, "SDN", 729, "Sudan", []int{662}},
+	{Suriname, "SUR", 740, "Suriname", []int{765}},
+	{Swaziland, "SWZ", 748, "Swaziland", []int{669}},
+	{Sweden, "SWE", 752, "Sweden", []int{265, 266}},
+	{Switzerland, "CHE", 756, "Switzerland", []int{269}},
+	{Syria, "SYR", 760, "Syria", []int{468}},
+	{Taiwan, "TWN", 158, "Taiwan", []int{416}},
+	{Tajikistan, "TJK", 762, "Tajikistan", []int{472}},
+	{Tanzania, "TZA", 834, "Tanzania", []int{674, 677}},
+	{Thailand, "THA", 764, "Thailand", []int{567}},
+	{Togo, "TGO", 768, "Togo", []int{671}},
+	{Tonga, "TON", 776, "Tonga", []int{570}},
+	{TrinidadTobago, "TTO", 780, "Trinidad and Tobago", []int{362}},
+	{Tunisia, "TUN", 788, "Tunisia", []int{672}},
+	{Turkey, "TUR", 792, "Turkey", []int{271}},
+	{Turkmenistan, "TKM", 795, "Turkmenistan", []int{434}},
+	{TurksCaicosIs, "TCA", 796, "Turks and Caicos Islands", []int{364}},
+	{Tuvalu, "TUV", 798, "Tuvalu", []int{572}},
+	{UAE, "ARE", 784, "United Arab Emirates", []int{470, 471}},
+	{UnitedKingdom, "GBR", 826, "United Kingdom", []int{232, 233, 234, 235}},
+	{UK, "GBR", 826, "United Kingdom", []int{232, 233, 234, 235}},
+	{USA, "USA", 840, "United States of America", []int{303, 338, 366, 367, 368, 369}},
+	{USVirginIs, "VIR", 850, "US Virgin Islands", []int{379}},
+	{Uganda, "UGA", 800, "Uganda", []int{675}},
+	{Ukraine, "UKR", 804, "Ukraine", []int{272}},
+	{Uruguay, "URY", 858, "Uruguay", []int{770}},
+	{Uzbekistan, "UZB", 860, "Uzbekistan", []int{437}},
+	{Vanuatu, "VUT", 548, "Vanuatu", []int{576, 577}},
+	{Vatican, "VAT", 336, "Vatican", []int{208}},
+	{Venezuela, "VEN", 862, "Venezuela", []int{775}},
+	{Vietnam, "VNM", 704, "Vietnam", []int{574}},
+	{WallisFutunaIs, "WLF", 876, "Wallis and Futuna Islands", []int{578}},
+	{Yemen, "YEM", 887, "Yemen", []int{473, 475}},
+	{Zambia, "ZMB", 894, "Zambia", []int{678}},
+	{Zimbabwe, "ZWE", 716, "Zimbabwe", []int{679}},
+}
@@ -1,702 +1,408 @@
 package countrycode
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CountryCode is an ISO 3166-1 alpha-2 country code, as used by the API to filter on flag state.
 type CountryCode string
 
 const (
 	Afghanistan          CountryCode = "AF"
-	Albania                          = "AL"
-	Algeria                          = "DZ"
-	AmericanSamoa                    = "AS"
-	Andorra                          = "AD"
-	Angola                           = "AO"
-	Anguilla                         = "AI"
-	Antarctica                       = "AQ"
-	AntiguaBarbuda                   = "AG"
-	Argentina                        = "AR"
-	Armenia                          = "AM"
-	Aruba                            = "AW"
-	AscensionIs                      = "IO"
-	Australia                        = "AU"
-	Austria                          = "AT"
-	Azerbaijan                       = "AZ"
-	Bahamas                          = "BS"
-	Bahrain                          = "BH"
-	Bangladesh                       = "BD"
-	Barbados                         = "BB"
-	Belarus                          = "BY"
-	Belgium                          = "BE"
-	Belize                           = "BZ"
-	Benin                            = "BJ"
-	Bermuda                          = "BM"
-	Bhutan                           = "BT"
-	Bolivia                          = "BO"
-	BosniaAndHerzegovina             = "BA"
-	Botswana                         = "BW"
-	Brazil                           = "BR"
-	BritishVirginIs                  = "VG"
-	Brunei                           = "BN"
-	Bulgaria                         = "BG"
-	BurkinaFaso                      = "BF"
-	Burundi                          = "BI"
-	Cambodia                         = "KH"
-	Cameroon                         = "CM"
-	Canada                           = "CA"
-	CapeVerde                        = "CV"
-	CaymanIs                         = "KY"
-	CenAfrRep                        = "CF"
-	Chad                             = "TD"
-	Chile                            = "CL"
-	China                            = "CN"
-	ChristmasIs                      = "CX"
-	CocosIs                          = "CC"
-	Colombia                         = "CO"
-	Comoros                          = "KM"
-	Congo                            = "CG"
-	CookIs                           = "CK"
-	CostaRica                        = "CR"
-	Croatia                          = "HR"
-	Cuba                             = "CU"
-	Curacao                          = "CW"
-	Cyprus                           = "CY"
-	CzechRepublic                    = "CZ"
-	DPRKorea                         = "KP"
-	DRCongo                          = "CD"
-	Denmark                          = "DK"
-	Djibouti                         = "DJ"
-	Dominica                         = "DM"
-	DominicanRep                     = "DO"
-	Ecuador                          = "EC"
-	Egypt                            = "EG"
-	ElSalvador                       = "SV"
-	EquGuinea                        = "GQ"
-	Eritrea                          = "ER"
-	Estonia                          = "EE"
-	Ethiopia                         = "ET"
-	FYRMacedonia                     = "MK"
-	FaroeIs                          = "FO"
-	Fiji                             = "FJ"
-	Finland                          = "FI"
-	France                           = "FR"
-	FrenchPolynesia                  = "PF"
-	Gabon                            = "GA"
-	Gambia                           = "GM"
-	Georgia                          = "GE"
-	Germany                          = "DE"
-	Ghana                            = "GH"
-	Gibraltar                        = "GI"
-	Greece                           = "GR"
-	Greenland                        = "GL"
-	Grenada                          = "GD"
-	Guadeloupe                       = "GP"
-	Guatemala                        = "GT"
-	Guiana                           = "GF"
-	Guinea                           = "GN"
-	GuineaBissau                     = "GW"
-	Guyana                           = "GY"
-	Haiti                            = "HT"
-	Honduras                         = "HN"
-	HongKong                         = "HK"
-	Hungary                          = "HU"
-	Iceland                          = "IS"
-	India                            = "IN"
-	Indonesia                        = "ID"
-	Iran                             = "IR"
-	Iraq                             = "IQ"
-	Ireland                          = "IE"
-	Israel                           = "IL"
-	Italy                            = "IT"
-	IvoryCoast                       = "CI"
-	Jamaica                          = "JM"
-	Japan                            = "JP"
-	Jordan                           = "JO"
-	Kazakhstan                       = "KZ"
-	Kenya                            = "KE"
-	Kiribati                         = "KI"
-	Korea                            = "KR"
-	Kuwait                           = "KW"
-	KyrgyzRepublic                   = "KG"
-	Laos                             = "LA"
-	Latvia                           = "LV"
-	Lebanon                          = "LB"
-	Lesotho                          = "LS"
-	Liberia                          = "LR"
-	Libya                            = "LY"
-	Liechtenstein                    = "LI"
-	Lithuania                        = "LT"
-	Luxembourg                       = "LU"
-	Macao                            = "MO"
-	Madagascar                       = "MG"
-	Malawi                           = "MW"
-	Malaysia                         = "MY"
-	Maldives                         = "MV"
-	Mali                             = "ML"
-	Malta                            = "MT"
-	MarshallIs                       = "MH"
-	Martinique                       = "MQ"
-	Mauritania                       = "MR"
-	Mauritius                        = "MU"
-	Mexico                           = "MX"
-	Micronesia                       = "FM"
-	Moldova                          = "MD"
-	Monaco                           = "MC"
-	Mongolia                         = "MN"
-	Montenegro                       = "ME"
-	Montserrat                       = "MS"
-	Morocco                          = "MA"
-	Mozambique                       = "MZ"
-	Myanmar                          = "MM"
-	NMarianaIs                       = "MP"
-	Namibia                          = "NA"
-	Nauru                            = "NR"
-	Nepal                            = "NP"
-	Netherlands                      = "NL"
-	NewCaledonia                     = "NC"
-	NewZealand                       = "NZ"
-	Nicaragua                        = "NI"
-	Niger                            = "NE"
-	Nigeria                          = "NG"
-	Niue                             = "NU"
-	Norway                           = "NO"
-	Oman                             = "OM"
-	Pakistan                         = "PK"
-	Palau                            = "PW"
-	Palestine                        = "PS"
-	Panama                           = "PA"
-	PapuaNewGuinea                   = "PG"
-	Paraguay                         = "PY"
-	Peru                             = "PE"
-	Philippines                      = "PH"
-	PitcairnIs                       = "PN"
-	Poland                           = "PL"
-	Portugal                         = "PT"
-	PuertoRico                       = "PR"
-	Qatar                            = "QA"
-	Reunion                          = "RE"
-	Romania                          = "RO"
-	Russia                           = "RU"
-	Rwanda                           = "RW"
-	Samoa                            = "WS"
-	SanMarino                        = "SM"
-	SaoTomePrincipe                  = "ST"
-	SaudiArabia                      = "SA"
-	Senegal                          = "SN"
-	Serbia                           = "RS"
-	Seychelles                       = "SC"
-	SierraLeone                      = "SL"
-	Singapore                        = "SG"
-	Slovakia                         = "SK"
-	Slovenia                         = "SI"
-	SolomonIs                        = "SB"
-	Somalia                          = "SO"
-	SouthAfrica                      = "ZA"
-	Spain                            = "ES"
-	SriLanka                         = "LK"
-	StHelena                         = "SH"
-	StKittsNevis                     = "KN"
-	StLucia                          = "LC"
-	StPaulAmsterdamIs                = "TF"
-	StPierreMiquelon                 = "PM"
-	StVincentGrenadines              = "VC"
-	Sudan                            = "SD"
-	Suriname                         = "SR"
-	Swaziland                        = "SZ"
-	Sweden                           = "SE"
-	Switzerland                      = "CH"
-	Syria                            = "SY"
-	Taiwan                           = "TW"
-	Tajikistan                       = "TJ"
-	Tanzania                         = "TZ"
-	Thailand                         = "TH"
-	Togo                             = "TG"
-	Tonga                            = "TO"
-	TrinidadTobago                   = "TT"
-	Tunisia                          = "TN"
-	Turkey                           = "TR"
-	Turkmenistan                     = "TM"
-	TurksCaicosIs                    = "TC"
-	Tuvalu                           = "TV"
-	UAE                              = "AE"
-	UK                               = "UK"
-	USA                              = "US"
-	USVirginIs                       = "VI"
-	Uganda                           = "UG"
-	Ukraine                          = "UA"
-	UnitedKingdom                    = "GB"
-	Uruguay                          = "UY"
-	Uzbekistan                       = "UZ"
-	Vanuatu                          = "VU"
-	Vatican                          = "VA"
-	Venezuela                        = "VE"
-	Vietnam                          = "VN"
-	WallisFutunaIs                   = "WF"
-	Yemen                            = "YE"
-	Zambia                           = "ZM"
-	Zimbabwe                         = "ZW"
+	Albania              CountryCode = "AL"
+	Algeria              CountryCode = "DZ"
+	AmericanSamoa        CountryCode = "AS"
+	Andorra              CountryCode = "AD"
+	Angola               CountryCode = "AO"
+	Anguilla             CountryCode = "AI"
+	Antarctica           CountryCode = "AQ"
+	AntiguaBarbuda       CountryCode = "AG"
+	Argentina            CountryCode = "AR"
+	Armenia              CountryCode = "AM"
+	Aruba                CountryCode = "AW"
+	AscensionIs          CountryCode = "IO"
+	Australia            CountryCode = "AU"
+	Austria              CountryCode = "AT"
+	Azerbaijan           CountryCode = "AZ"
+	Bahamas              CountryCode = "BS"
+	Bahrain              CountryCode = "BH"
+	Bangladesh           CountryCode = "BD"
+	Barbados             CountryCode = "BB"
+	Belarus              CountryCode = "BY"
+	Belgium              CountryCode = "BE"
+	Belize               CountryCode = "BZ"
+	Benin                CountryCode = "BJ"
+	Bermuda              CountryCode = "BM"
+	Bhutan               CountryCode = "BT"
+	Bolivia              CountryCode = "BO"
+	BosniaAndHerzegovina CountryCode = "BA"
+	Botswana             CountryCode = "BW"
+	Brazil               CountryCode = "BR"
+	BritishVirginIs      CountryCode = "VG"
+	Brunei               CountryCode = "BN"
+	Bulgaria             CountryCode = "BG"
+	BurkinaFaso          CountryCode = "BF"
+	Burundi              CountryCode = "BI"
+	Cambodia             CountryCode = "KH"
+	Cameroon             CountryCode = "CM"
+	Canada               CountryCode = "CA"
+	CapeVerde            CountryCode = "CV"
+	CaymanIs             CountryCode = "KY"
+	CenAfrRep            CountryCode = "CF"
+	Chad                 CountryCode = "TD"
+	Chile                CountryCode = "CL"
+	China                CountryCode = "CN"
+	ChristmasIs          CountryCode = "CX"
+	CocosIs              CountryCode = "CC"
+	Colombia             CountryCode = "CO"
+	Comoros              CountryCode = "KM"
+	Congo                CountryCode = "CG"
+	CookIs               CountryCode = "CK"
+	CostaRica            CountryCode = "CR"
+	Croatia              CountryCode = "HR"
+	Cuba                 CountryCode = "CU"
+	Curacao              CountryCode = "CW"
+	Cyprus               CountryCode = "CY"
+	CzechRepublic        CountryCode = "CZ"
+	DPRKorea             CountryCode = "KP"
+	DRCongo              CountryCode = "CD"
+	Denmark              CountryCode = "DK"
+	Djibouti             CountryCode = "DJ"
+	Dominica             CountryCode = "DM"
+	DominicanRep         CountryCode = "DO"
+	Ecuador              CountryCode = "EC"
+	Egypt                CountryCode = "EG"
+	ElSalvador           CountryCode = "SV"
+	EquGuinea            CountryCode = "GQ"
+	Eritrea              CountryCode = "ER"
+	Estonia              CountryCode = "EE"
+	Ethiopia             CountryCode = "ET"
+	FYRMacedonia         CountryCode = "MK"
+	FaroeIs              CountryCode = "FO"
+	Fiji                 CountryCode = "FJ"
+	Finland              CountryCode = "FI"
+	France               CountryCode = "FR"
+	FrenchPolynesia      CountryCode = "PF"
+	Gabon                CountryCode = "GA"
+	Gambia               CountryCode = "GM"
+	Georgia              CountryCode = "GE"
+	Germany              CountryCode = "DE"
+	Ghana                CountryCode = "GH"
+	Gibraltar            CountryCode = "GI"
+	Greece               CountryCode = "GR"
+	Greenland            CountryCode = "GL"
+	Grenada              CountryCode = "GD"
+	Guadeloupe           CountryCode = "GP"
+	Guatemala            CountryCode = "GT"
+	Guiana               CountryCode = "GF"
+	Guinea               CountryCode = "GN"
+	GuineaBissau         CountryCode = "GW"
+	Guyana               CountryCode = "GY"
+	Haiti                CountryCode = "HT"
+	Honduras             CountryCode = "HN"
+	HongKong             CountryCode = "HK"
+	Hungary              CountryCode = "HU"
+	Iceland              CountryCode = "IS"
+	India                CountryCode = "IN"
+	Indonesia            CountryCode = "ID"
+	Iran                 CountryCode = "IR"
+	Iraq                 CountryCode = "IQ"
+	Ireland              CountryCode = "IE"
+	Israel               CountryCode = "IL"
+	Italy                CountryCode = "IT"
+	IvoryCoast           CountryCode = "CI"
+	Jamaica              CountryCode = "JM"
+	Japan                CountryCode = "JP"
+	Jordan               CountryCode = "JO"
+	Kazakhstan           CountryCode = "KZ"
+	Kenya                CountryCode = "KE"
+	Kiribati             CountryCode = "KI"
+	Korea                CountryCode = "KR"
+	Kuwait               CountryCode = "KW"
+	KyrgyzRepublic       CountryCode = "KG"
+	Laos                 CountryCode = "LA"
+	Latvia               CountryCode = "LV"
+	Lebanon              CountryCode = "LB"
+	Lesotho              CountryCode = "LS"
+	Liberia              CountryCode = "LR"
+	Libya                CountryCode = "LY"
+	Liechtenstein        CountryCode = "LI"
+	Lithuania            CountryCode = "LT"
+	Luxembourg           CountryCode = "LU"
+	Macao                CountryCode = "MO"
+	Madagascar           CountryCode = "MG"
+	Malawi               CountryCode = "MW"
+	Malaysia             CountryCode = "MY"
+	Maldives             CountryCode = "MV"
+	Mali                 CountryCode = "ML"
+	Malta                CountryCode = "MT"
+	MarshallIs           CountryCode = "MH"
+	Martinique           CountryCode = "MQ"
+	Mauritania           CountryCode = "MR"
+	Mauritius            CountryCode = "MU"
+	Mexico               CountryCode = "MX"
+	Micronesia           CountryCode = "FM"
+	Moldova              CountryCode = "MD"
+	Monaco               CountryCode = "MC"
+	Mongolia             CountryCode = "MN"
+	Montenegro           CountryCode = "ME"
+	Montserrat           CountryCode = "MS"
+	Morocco              CountryCode = "MA"
+	Mozambique           CountryCode = "MZ"
+	Myanmar              CountryCode = "MM"
+	NMarianaIs           CountryCode = "MP"
+	Namibia              CountryCode = "NA"
+	Nauru                CountryCode = "NR"
+	Nepal                CountryCode = "NP"
+	Netherlands          CountryCode = "NL"
+	NewCaledonia         CountryCode = "NC"
+	NewZealand           CountryCode = "NZ"
+	Nicaragua            CountryCode = "NI"
+	Niger                CountryCode = "NE"
+	Nigeria              CountryCode = "NG"
+	Niue                 CountryCode = "NU"
+	Norway               CountryCode = "NO"
+	Oman                 CountryCode = "OM"
+	Pakistan             CountryCode = "PK"
+	Palau                CountryCode = "PW"
+	Palestine            CountryCode = "PS"
+	Panama               CountryCode = "PA"
+	PapuaNewGuinea       CountryCode = "PG"
+	Paraguay             CountryCode = "PY"
+	Peru                 CountryCode = "PE"
+	Philippines          CountryCode = "PH"
+	PitcairnIs           CountryCode = "PN"
+	Poland               CountryCode = "PL"
+	Portugal             CountryCode = "PT"
+	PuertoRico           CountryCode = "PR"
+	Qatar                CountryCode = "QA"
+	Reunion              CountryCode = "RE"
+	Romania              CountryCode = "RO"
+	Russia               CountryCode = "RU"
+	Rwanda               CountryCode = "RW"
+	Samoa                CountryCode = "WS"
+	SanMarino            CountryCode = "SM"
+	SaoTomePrincipe      CountryCode = "ST"
+	SaudiArabia          CountryCode = "SA"
+	Senegal              CountryCode = "SN"
+	Serbia               CountryCode = "RS"
+	Seychelles           CountryCode = "SC"
+	SierraLeone          CountryCode = "SL"
+	Singapore            CountryCode = "SG"
+	Slovakia             CountryCode = "SK"
+	Slovenia             CountryCode = "SI"
+	SolomonIs            CountryCode = "SB"
+	Somalia              CountryCode = "SO"
+	SouthAfrica          CountryCode = "ZA"
+	Spain                CountryCode = "ES"
+	SriLanka             CountryCode = "LK"
+	StHelena             CountryCode = "SH"
+	StKittsNevis         CountryCode = "KN"
+	StLucia              CountryCode = "LC"
+	StPaulAmsterdamIs    CountryCode = "TF"
+	StPierreMiquelon     CountryCode = "PM"
+	StVincentGrenadines  CountryCode = "VC"
+	Sudan                CountryCode = "SD"
+	Suriname             CountryCode = "SR"
+	Swaziland            CountryCode = "SZ"
+	Sweden               CountryCode = "SE"
+	Switzerland          CountryCode = "CH"
+	Syria                CountryCode = "SY"
+	Taiwan               CountryCode = "TW"
+	Tajikistan           CountryCode = "TJ"
+	Tanzania             CountryCode = "TZ"
+	Thailand             CountryCode = "TH"
+	Togo                 CountryCode = "TG"
+	Tonga                CountryCode = "TO"
+	TrinidadTobago       CountryCode = "TT"
+	Tunisia              CountryCode = "TN"
+	Turkey               CountryCode = "TR"
+	Turkmenistan         CountryCode = "TM"
+	TurksCaicosIs        CountryCode = "TC"
+	Tuvalu               CountryCode = "TV"
+	UAE                  CountryCode = "AE"
+	// UK is not an ISO 3166 code, but is kept for compatibility. It is equivalent to UnitedKingdom.
+	UK             CountryCode = "UK"
+	USA            CountryCode = "US"
+	USVirginIs     CountryCode = "VI"
+	Uganda         CountryCode = "UG"
+	Ukraine        CountryCode = "UA"
+	UnitedKingdom  CountryCode = "GB"
+	Uruguay        CountryCode = "UY"
+	Uzbekistan     CountryCode = "UZ"
+	Vanuatu        CountryCode = "VU"
+	Vatican        CountryCode = "VA"
+	Venezuela      CountryCode = "VE"
+	Vietnam        CountryCode = "VN"
+	WallisFutunaIs CountryCode = "WF"
+	Yemen          CountryCode = "YE"
+	Zambia         CountryCode = "ZM"
+	Zimbabwe       CountryCode = "ZW"
+)
+
+// country holds the data associated with a single country code.
+type country struct {
+	code    CountryCode
+	alpha3  string
+	numeric int
+	name    string
+	mids    []int
+}
+
+var (
+	byCode    = make(map[CountryCode]*country, len(countries))
+	byAlpha3  = make(map[string]*country, len(countries))
+	byNumeric = make(map[int]*country, len(countries))
+	byName    = make(map[string]*country, len(countries))
+	byMID     = make(map[int]*country)
 )
 
+func init() {
+	for i := range countries {
+		c := &countries[i]
+		if _, ok := byCode[c.code]; !ok {
+			byCode[c.code] = c
+		}
+		if _, ok := byAlpha3[c.alpha3]; !ok && c.alpha3 != "" {
+			byAlpha3[c.alpha3] = c
+		}
+		if _, ok := byNumeric[c.numeric]; !ok && c.numeric != 0 {
+			byNumeric[c.numeric] = c
+		}
+		if name := strings.ToLower(c.name); byName[name] == nil {
+			byName[name] = c
+		}
+		for _, mid := range c.mids {
+			if _, ok := byMID[mid]; !ok {
+				byMID[mid] = c
+			}
+		}
+	}
+}
+
+// Valid is true iff the country code is known to this package.
+func (c CountryCode) Valid() bool {
+	_, ok := byCode[c]
+	return ok
+}
+
+// ToCountryName returns the English name of the country, or "Unknown" if the country code is not known.
 func (c CountryCode) ToCountryName() string {
-	// If you care about nanosecond lookup-times, this could probably be done more efficiently.
+	if country, ok := byCode[c]; ok {
+		return country.name
+	}
+	return "Unknown"
+}
+
+// Alpha3 returns the ISO 3166-1 alpha-3 code of the country, e.g. "NOR", or an empty string if the country code is
+// not known or the country has none.
+func (c CountryCode) Alpha3() string {
+	if country, ok := byCode[c]; ok {
+		return country.alpha3
+	}
+	return ""
+}
 
-	switch c {
-	case "AF":
-		return "Afghanistan"
-	case "AL":
-		return "Albania"
-	case "DZ":
-		return "Algeria"
-	case "AS":
-		return "American Samoa"
-	case "AD":
-		return "Andorra"
-	case "AO":
-		return "Angola"
-	case "AI":
-		return "Anguilla"
-	case "AQ":
-		return "Antarctica"
-	case "AG":
-		return "Antigua and Barbuda"
-	case "AR":
-		return "Argentina"
-	case "AM":
-		return "Armenia"
-	case "AW":
-		return "Aruba"
-	case "IO":
-		return "AscensionIs"
-	case "AU":
-		return "Australia"
-	case "AT":
-		return "Austria"
-	case "AZ":
-		return "Azerbaijan"
-	case "BS":
-		return "Bahamas"
-	case "BH":
-		return "Bahrain"
-	case "BD":
-		return "Bangladesh"
-	case "BB":
-		return "Barbados"
-	case "BY":
-		return "Belarus"
-	case "BE":
-		return "Belgium"
-	case "BZ":
-		return "Belize"
-	case "BJ":
-		return "Benin"
-	case "BM":
-		return "Bermuda"
-	case "BT":
-		return "Bhutan"
-	case "BO":
-		return "Bolivia"
-	case "BA":
-		return "Bosnia and Herzegovina"
-	case "BW":
-		return "Botswana"
-	case "BR":
-		return "Brazil"
-	case "VG":
-		return "British Virgin Islands"
-	case "BN":
-		return "Brunei"
-	case "BG":
-		return "Bulgaria"
-	case "BF":
-		return "Burkina Faso"
-	case "BI":
-		return "Burundi"
-	case "KH":
-		return "Cambodia"
-	case "CM":
-		return "Cameroon"
-	case "CA":
-		return "Canada"
-	case "CV":
-		return "Cape Verde"
-	case "KY":
-		return "Cayman Islands"
-	case "CF":
-		return "Central African Republic"
-	case "TD":
-		return "Chad"
-	case "CL":
-		return "Chile"
-	case "CN":
-		return "China"
-	case "CX":
-		return "Christmas Islands"
-	case "CC":
-		return "Cocos Islands"
-	case "CO":
-		return "Colombia"
-	case "KM":
-		return "Comoros"
-	case "CG":
-		return "Congo"
-	case "CK":
-		return "Cook Islands"
-	case "CR":
-		return "Costa Rica"
-	case "HR":
-		return "Croatia"
-	case "CU":
-		return "Cuba"
-	case "CW":
-		return "Curacao"
-	case "CY":
-		return "Cyprus"
-	case "CZ":
-		return "Czech Republic"
-	case "KP":
-		return "DPR Korea"
-	case "CD":
-		return "DR Congo"
-	case "DK":
-		return "Denmark"
-	case "DJ":
-		return "Djibouti"
-	case "DM":
-		return "Dominica"
-	case "DO":
-		return "Dominican Republic"
-	case "EC":
-		return "Ecuador"
-	case "EG":
-		return "Egypt"
-	case "SV":
-		return "El Salvador"
-	case "GQ":
-		return "Equatorial Guinea"
-	case "ER":
-		return "Eritrea"
-	case "EE":
-		return "Estonia"
-	case "ET":
-		return "Ethiopia"
-	case "MK":
-		return "FYR Macedonia"
-	case "FO":
-		return "Faroe Islands"
-	case "FJ":
-		return "Fiji"
-	case "FI":
-		return "Finland"
-	case "FR":
-		return "France"
-	case "PF":
-		return "French Polynesia"
-	case "GA":
-		return "Gabon"
-	case "GM":
-		return "Gambia"
-	case "GE":
-		return "Georgia"
-	case "DE":
-		return "Germany"
-	case "GH":
-		return "Ghana"
-	case "GI":
-		return "Gibraltar"
-	case "GR":
-		return "Greece"
-	case "GL":
-		return "Greenland"
-	case "GD":
-		return "Grenada"
-	case "GP":
-		return "Guadeloupe"
-	case "GT":
-		return "Guatemala"
-	case "GF":
-		return "Guiana"
-	case "GN":
-		return "Guinea"
-	case "GW":
-		return "Guinea Bissau"
-	case "GY":
-		return "Guyana"
-	case "HT":
-		return "Haiti"
-	case "HN":
-		return "Honduras"
-	case "HK":
-		return "Hong Kong"
-	case "HU":
-		return "Hungary"
-	case "IS":
-		return "Iceland"
-	case "IN":
-		return "India"
-	case "ID":
-		return "Indonesia"
-	case "IR":
-		return "Iran"
-	case "IQ":
-		return "Iraq"
-	case "IE":
-		return "Ireland"
-	case "IL":
-		return "Israel"
-	case "IT":
-		return "Italy"
-	case "CI":
-		return "IvoryCoast"
-	case "JM":
-		return "Jamaica"
-	case "JP":
-		return "Japan"
-	case "JO":
-		return "Jordan"
-	case "KZ":
-		return "Kazakhstan"
-	case "KE":
-		return "Kenya"
-	case "KI":
-		return "Kiribati"
-	case "KR":
-		return "Korea"
-	case "KW":
-		return "Kuwait"
-	case "KG":
-		return "Kyrgyz Republic"
-	case "LA":
-		return "Laos"
-	case "LV":
-		return "Latvia"
-	case "LB":
-		return "Lebanon"
-	case "LS":
-		return "Lesotho"
-	case "LR":
-		return "Liberia"
-	case "LY":
-		return "Libya"
-	case "LI":
-		return "Liechtenstein"
-	case "LT":
-		return "Lithuania"
-	case "LU":
-		return "Luxembourg"
-	case "MO":
-		return "Macao"
-	case "MG":
-		return "Madagascar"
-	case "MW":
-		return "Malawi"
-	case "MY":
-		return "Malaysia"
-	case "MV":
-		return "Maldives"
-	case "ML":
-		return "Mali"
-	case "MT":
-		return "Malta"
-	case "MH":
-		return "MarshallIs"
-	case "MQ":
-		return "Martinique"
-	case "MR":
-		return "Mauritania"
-	case "MU":
-		return "Mauritius"
-	case "MX":
-		return "Mexico"
-	case "FM":
-		return "Micronesia"
-	case "MD":
-		return "Moldova"
-	case "MC":
-		return "Monaco"
-	case "MN":
-		return "Mongolia"
-	case "ME":
-		return "Montenegro"
-	case "MS":
-		return "Montserrat"
-	case "MA":
-		return "Morocco"
-	case "MZ":
-		return "Mozambique"
-	case "MM":
-		return "Myanmar"
-	case "MP":
-		return "NMarianaIs"
-	case "NA":
-		return "Namibia"
-	case "NR":
-		return "Nauru"
-	case "NP":
-		return "Nepal"
-	case "NL":
-		return "Netherlands"
-	case "NC":
-		return "NewCaledonia"
-	case "NZ":
-		return "NewZealand"
-	case "NI":
-		return "Nicaragua"
-	case "NE":
-		return "Niger"
-	case "NG":
-		return "Nigeria"
-	case "NU":
-		return "Niue"
-	case "NO":
-		return "Norway"
-	case "OM":
-		return "Oman"
-	case "PK":
-		return "Pakistan"
-	case "PW":
-		return "Palau"
-	case "PS":
-		return "Palestine"
-	case "PA":
-		return "Panama"
-	case "PG":
-		return "Papua New Guinea"
-	case "PY":
-		return "Paraguay"
-	case "PE":
-		return "Peru"
-	case "PH":
-		return "Philippines"
-	case "PN":
-		return "Pitcairn Islands"
-	case "PL":
-		return "Poland"
-	case "PT":
-		return "Portugal"
-	case "PR":
-		return "Puerto Rico"
-	case "QA":
-		return "Qatar"
-	case "RE":
-		return "Reunion"
-	case "RO":
-		return "Romania"
-	case "RU":
-		return "Russia"
-	case "RW":
-		return "Rwanda"
-	case "WS":
-		return "Samoa"
-	case "SM":
-		return "San Marino"
-	case "ST":
-		return "Sao Tome and Principe"
-	case "SA":
-		return "SaudiArabia"
-	case "SN":
-		return "Senegal"
-	case "RS":
-		return "Serbia"
-	case "SC":
-		return "Seychelles"
-	case "SL":
-		return "SierraLeone"
-	case "SG":
-		return "Singapore"
-	case "SK":
-		return "Slovakia"
-	case "SI":
-		return "Slovenia"
-	case "SB":
-		return "SolomonIs"
-	case "SO":
-		return "Somalia"
-	case "ZA":
-		return "South Africa"
-	case "ES":
-		return "Spain"
-	case "LK":
-		return "SriLanka"
-	case "SH":
-		return "StHelena"
-	case "KN":
-		return "St. Kitts and Nevis"
-	case "LC":
-		return "St. Lucia"
-	case "TF":
-		return "St. Paul Amsterdam Island"
-	case "PM":
-		return "St. Pierre Miquelon"
-	case "VC":
-		return "St. Vincent and the Grenadines"
-	case "SD":
-		return "Sudan"
-	case "SR":
-		return "Suriname"
-	case "SZ":
-		return "Swaziland"
-	case "SE":
-		return "Sweden"
-	case "CH":
-		return "Switzerland"
-	case "SY":
-		return "Syria"
-	case "TW":
-		return "Taiwan"
-	case "TJ":
-		return "Tajikistan"
-	case "TZ":
-		return "Tanzania"
-	case "TH":
-		return "Thailand"
-	case "TG":
-		return "Togo"
-	case "TO":
-		return "Tonga"
-	case "TT":
-		return "Trinidad and Tobago"
-	case "TN":
-		return "Tunisia"
-	case "TR":
-		return "Turkey"
-	case "TM":
-		return "Turkmenistan"
-	case "TC":
-		return "Turks and Caicos Islands"
-	case "TV":
-		return "Tuvalu"
-	case "AE":
-		return "United Arab Emirates"
-	case "UK":
-		return "United Kingdom"
-	case "US":
-		return "United States of America"
-	case "VI":
-		return "US Virgin Islands"
-	case "UG":
-		return "Uganda"
-	case "UA":
-		return "Ukraine"
-	case "GB":
-		return "United Kingdom"
-	case "UY":
-		return "Uruguay"
-	case "UZ":
-		return "Uzbekistan"
-	case "VU":
-		return "Vanuatu"
-	case "VA":
-		return "Vatican"
-	case "VE":
-		return "Venezuela"
-	case "VN":
-		return "Vietnam"
-	case "WF":
-		return "Wallis and Futuna Islands"
-	case "YE":
-		return "Yemen"
-	case "ZM":
-		return "Zambia"
-	case "ZW":
-		return "Zimbabwe"
+// Numeric returns the ISO 3166-1 numeric code of the country, e.g. 578, or 0 if the country code is not known or the
+// country has none.
+func (c CountryCode) Numeric() int {
+	if country, ok := byCode[c]; ok {
+		return country.numeric
+	}
+	return 0
+}
+
+// MIDs returns the Maritime Identification Digits (MID) allocated to the country by the ITU, e.g. 257, 258 and 259 for
+// Norway. Territories are included with the country code they are registered under in this package.
+func (c CountryCode) MIDs() []int {
+	country, ok := byCode[c]
+	if !ok {
+		return nil
+	}
+	return append([]int(nil), country.mids...)
+}
+
+// Parse returns the country code matching the supplied string, which can be an ISO 3166-1 alpha-2, alpha-3 or
+// numeric code, or the country name as returned by ToCountryName. Matching is case-insensitive.
+func Parse(s string) (CountryCode, error) {
+	s = strings.TrimSpace(s)
+	upper := strings.ToUpper(s)
+
+	if c, ok := byCode[CountryCode(upper)]; ok {
+		return c.code, nil
+	}
+	if c, ok := byAlpha3[upper]; ok {
+		return c.code, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if c, ok := byNumeric[n]; ok {
+			return c.code, nil
+		}
+	}
+	if c, ok := byName[strings.ToLower(s)]; ok {
+		return c.code, nil
+	}
+
+	return "", fmt.Errorf("unknown country: %q", s)
+}
+
+// FromMID returns the country code to which the Maritime Identification Digits (MID) are allocated.
+func FromMID(mid int) (CountryCode, bool) {
+	if c, ok := byMID[mid]; ok {
+		return c.code, true
+	}
+	return "", false
+}
+
+// FromMMSI returns the country code of the flag state of an MMSI, based on its Maritime Identification Digits (MID).
+//
+// Ship stations (MIDXXXXXX), group ship stations (0MIDXXXXX), coast stations (00MIDXXXX), SAR aircraft (111MIDXXX),
+// handheld radios (8MIDXXXXX), craft associated with a parent ship (98MIDXXXX) and aids to navigation (99MIDXXXX) are
+// supported.
+func FromMMSI(mmsi int) (CountryCode, bool) {
+	if mmsi < 0 || mmsi > 999999999 {
+		return "", false
+	}
+
+	var mid int
+	switch {
+	case mmsi < 10000000:
+		// 00MIDXXXX
+		mid = mmsi / 10000
+	case mmsi < 100000000:
+		// 0MIDXXXXX
+		mid = mmsi / 100000
+	case mmsi/1000000 == 111:
+		// 111MIDXXX
+		mid = mmsi / 1000 % 1000
+	case mmsi/10000000 == 98 || mmsi/10000000 == 99:
+		// 98MIDXXXX, 99MIDXXXX
+		mid = mmsi / 10000 % 1000
+	case mmsi/100000000 == 8:
+		// 8MIDXXXXX
+		mid = mmsi / 100000 % 1000
 	default:
-		return "Unknown"
+		// MIDXXXXXX
+		mid = mmsi / 1000000
+	}
+
+	return FromMID(mid)
+}
+
+// MarshalText implements encoding.TextMarshaler. Country codes are marshalled as they are, known or not, so that
+// filters can be marshalled before they are validated.
+func (c CountryCode) MarshalText() ([]byte, error) {
+	return []byte(c), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts any string accepted by Parse.
+func (c *CountryCode) UnmarshalText(text []byte) error {
+	code, err := Parse(string(text))
+	if err != nil {
+		return err
 	}
+	*c = code
+	return nil
 }
@@ -0,0 +1,124 @@
+package countrycode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ilder-as/go-barentswatch-ais/countrycode"
+)
+
+func TestCountryCode_Lookups(t *testing.T) {
+	c := countrycode.Norway
+	if name := c.ToCountryName(); name != "Norway" {
+		t.Errorf("expected name Norway, got %q", name)
+	}
+	if a3 := c.Alpha3(); a3 != "NOR" {
+		t.Errorf("expected alpha-3 NOR, got %q", a3)
+	}
+	if n := c.Numeric(); n != 578 {
+		t.Errorf("expected numeric 578, got %d", n)
+	}
+	if mids := c.MIDs(); len(mids) != 3 || mids[0] != 257 {
+		t.Errorf("unexpected MIDs %v", mids)
+	}
+
+	// Ascension Island has a MID, but no ISO 3166-1 codes of its own
+	if a, n := countrycode.AscensionIs.Alpha3(), countrycode.AscensionIs.Numeric(); a != "" || n != 0 {
+		t.Errorf("expected Ascension Island without alpha-3 and numeric codes, got %q and %d", a, n)
+	}
+
+	if name := countrycode.CountryCode("XX").ToCountryName(); name != "Unknown" {
+		t.Errorf("expected unknown country, got %q", name)
+	}
+}
+
+func TestParse(t *testing.T) {
+	cases := map[string]countrycode.CountryCode{
+		"NO":             countrycode.Norway,
+		"no":             countrycode.Norway,
+		"NOR":            countrycode.Norway,
+		"578":            countrycode.Norway,
+		"norway":         countrycode.Norway,
+		"GBR":            countrycode.UnitedKingdom,
+		"United Kingdom": countrycode.UnitedKingdom,
+		"UK":             countrycode.UK,
+		"004":            countrycode.Afghanistan,
+	}
+	for in, expected := range cases {
+		c, err := countrycode.Parse(in)
+		if err != nil {
+			t.Errorf("unable to parse %q: %s", in, err)
+		} else if c != expected {
+			t.Errorf("expected %q to parse as %s, got %s", in, expected, c)
+		}
+	}
+
+	for _, in := range []string{"Atlantis", "", "0"} {
+		if _, err := countrycode.Parse(in); err == nil {
+			t.Errorf("expected error for unknown country %q", in)
+		}
+	}
+}
+
+func TestFromMMSI(t *testing.T) {
+	cases := map[int]countrycode.CountryCode{
+		257123456: countrycode.Norway,
+		265123456: countrycode.Sweden,
+		2579999:   countrycode.Norway, // 00MIDXXXX
+		25799999:  countrycode.Norway, // 0MIDXXXXX
+		111257123: countrycode.Norway,
+		992576000: countrycode.Norway,
+		982651234: countrycode.Sweden,
+		826512345: countrycode.Sweden,
+		232001000: countrycode.UnitedKingdom,
+	}
+	for mmsi, expected := range cases {
+		c, ok := countrycode.FromMMSI(mmsi)
+		if !ok || c != expected {
+			t.Errorf("expected MMSI %d to belong to %s, got %s", mmsi, expected, c)
+		}
+	}
+
+	if _, ok := countrycode.FromMMSI(999999999); ok {
+		t.Error("expected no country for unallocated MID")
+	}
+}
+
+func TestRegion(t *testing.T) {
+	if !countrycode.Norway.In(countrycode.RegionEEA, countrycode.RegionNordic) {
+		t.Error("expected Norway to be in the EEA and the Nordics")
+	}
+	if countrycode.Norway.In(countrycode.RegionEU) {
+		t.Error("expected Norway not to be in the EU")
+	}
+	if n := len(countrycode.RegionEU.Members()); n != 27 {
+		t.Errorf("expected 27 EU members, got %d", n)
+	}
+	for _, c := range countrycode.RegionEU.Members() {
+		if !countrycode.RegionEEA.Contains(c) {
+			t.Errorf("expected EU member %s to be in the EEA", c)
+		}
+	}
+}
+
+func TestCountryCode_Text(t *testing.T) {
+	data, err := json.Marshal([]countrycode.CountryCode{countrycode.Norway, countrycode.Sweden})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `["NO","SE"]` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+
+	if data, err := json.Marshal([]countrycode.CountryCode{"XX", ""}); err != nil || string(data) != `["XX",""]` {
+		t.Errorf("expected unknown country codes to be marshalled as they are, got %s, %v", data, err)
+	}
+
+	var codes []countrycode.CountryCode
+	if err := json.Unmarshal([]byte(`["NOR", "sweden"]`), &codes); err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 2 || codes[0] != countrycode.Norway || codes[1] != countrycode.Sweden {
+		t.Errorf("unexpected country codes %v", codes)
+	}
+}
@@ -0,0 +1,70 @@
+package countrycode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Region is a named group of countries.
+type Region string
+
+const (
+	// RegionEU is the member states of the European Union.
+	RegionEU Region = "EU"
+	// RegionEEA is the member states of the European Economic Area, i.e. the EU member states as well as Iceland,
+	// Liechtenstein and Norway.
+	RegionEEA Region = "EEA"
+	// RegionNordic is the Nordic countries, including the Faroe Islands and Greenland.
+	RegionNordic Region = "Nordic"
+)
+
+var regions = map[Region][]CountryCode{
+	RegionEU: {
+		Austria, Belgium, Bulgaria, Croatia, Cyprus, CzechRepublic, Denmark, Estonia, Finland, France, Germany, Greece,
+		Hungary, Ireland, Italy, Latvia, Lithuania, Luxembourg, Malta, Netherlands, Poland, Portugal, Romania, Slovakia,
+		Slovenia, Spain, Sweden,
+	},
+	RegionEEA: {
+		Austria, Belgium, Bulgaria, Croatia, Cyprus, CzechRepublic, Denmark, Estonia, Finland, France, Germany, Greece,
+		Hungary, Iceland, Ireland, Italy, Latvia, Liechtenstein, Lithuania, Luxembourg, Malta, Netherlands, Norway,
+		Poland, Portugal, Romania, Slovakia, Slovenia, Spain, Sweden,
+	},
+	RegionNordic: {
+		Denmark, FaroeIs, Finland, Greenland, Iceland, Norway, Sweden,
+	},
+}
+
+// Members returns the country codes of the region, e.g. for use as the CountryCodes of a filter.
+func (r Region) Members() []CountryCode {
+	return append([]CountryCode(nil), regions[r]...)
+}
+
+// Contains is true iff the country code is a member of the region.
+func (r Region) Contains(c CountryCode) bool {
+	for _, member := range regions[r] {
+		if member == c {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseRegion returns the region with the given name. Names are matched case-insensitively.
+func ParseRegion(s string) (Region, error) {
+	for r := range regions {
+		if strings.EqualFold(string(r), strings.TrimSpace(s)) {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("unknown region: %q", s)
+}
+
+// In is true iff the country code is a member of any of the supplied regions.
+func (c CountryCode) In(regions ...Region) bool {
+	for _, r := range regions {
+		if r.Contains(c) {
+			return true
+		}
+	}
+	return false
+}
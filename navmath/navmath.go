@@ -0,0 +1,194 @@
+// Package navmath implements the navigation math used across the module: distances and bearings along great circles
+// and rhumb lines, destination points, cross-track and along-track distances, and conversions between the units of
+// AIS and those of SI.
+//
+// Coordinates are in degrees, bearings in degrees clockwise from true north in [0, 360), and distances in metres, on
+// a sphere of radius EarthRadius:
+//
+//	from := navmath.Point{Latitude: 69.65, Longitude: 18.96}
+//	to := navmath.Point{Latitude: 70.66, Longitude: 23.68}
+//	nm := navmath.MetresToNauticalMiles(navmath.Distance(from, to))
+//	course := navmath.InitialBearing(from, to)
+//
+// The response types with coordinates, such as ais.Position, have DistanceTo and BearingTo methods built on this
+// package.
+package navmath
+
+import "math"
+
+// EarthRadius is the mean radius of the earth in metres.
+const EarthRadius = 6371008.8
+
+// Units of AIS in SI units.
+const (
+	// MetresPerNauticalMile is the length of a nautical mile in metres.
+	MetresPerNauticalMile = 1852.0
+	// MetresPerSecondPerKnot is a speed of one knot in metres per second.
+	MetresPerSecondPerKnot = MetresPerNauticalMile / 3600
+)
+
+// NauticalMilesToMetres converts a distance in nautical miles to metres.
+func NauticalMilesToMetres(nm float64) float64 {
+	return nm * MetresPerNauticalMile
+}
+
+// MetresToNauticalMiles converts a distance in metres to nautical miles.
+func MetresToNauticalMiles(m float64) float64 {
+	return m / MetresPerNauticalMile
+}
+
+// KnotsToMetresPerSecond converts a speed in knots, such as a speed over ground, to metres per second.
+func KnotsToMetresPerSecond(knots float64) float64 {
+	return knots * MetresPerSecondPerKnot
+}
+
+// MetresPerSecondToKnots converts a speed in metres per second to knots.
+func MetresPerSecondToKnots(ms float64) float64 {
+	return ms / MetresPerSecondPerKnot
+}
+
+// Point is a point on the surface of the earth.
+type Point struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Point returns the receiver, so that a Point can be given wherever a value with coordinates is expected, such as to
+// the DistanceTo methods of the response types.
+func (p Point) Point() (Point, bool) {
+	return p, true
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// normaliseBearing returns the bearing, in radians, in degrees in [0, 360).
+func normaliseBearing(theta float64) float64 {
+	return math.Mod(degrees(theta)+360, 360)
+}
+
+// normaliseLongitude returns the longitude, in radians, in degrees in [-180, 180].
+func normaliseLongitude(lambda float64) float64 {
+	return math.Remainder(degrees(lambda), 360)
+}
+
+// Distance returns the great-circle distance between the points, in metres, by the haversine formula.
+func Distance(a, b Point) float64 {
+	return EarthRadius * angularDistance(a, b)
+}
+
+// angularDistance returns the great-circle distance between the points in radians.
+func angularDistance(a, b Point) float64 {
+	phi1, phi2 := radians(a.Latitude), radians(b.Latitude)
+	dPhi, dLambda := phi2-phi1, radians(b.Longitude-a.Longitude)
+	h := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * math.Asin(math.Sqrt(min(1, h)))
+}
+
+// InitialBearing returns the bearing at a of the great circle from a to b.
+func InitialBearing(a, b Point) float64 {
+	return normaliseBearing(initialBearing(a, b))
+}
+
+// initialBearing returns the bearing at a of the great circle from a to b in radians.
+func initialBearing(a, b Point) float64 {
+	phi1, phi2 := radians(a.Latitude), radians(b.Latitude)
+	dLambda := radians(b.Longitude - a.Longitude)
+	y := math.Sin(dLambda) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLambda)
+	return math.Atan2(y, x)
+}
+
+// FinalBearing returns the bearing at b of the great circle from a to b, which differs from the initial bearing
+// unless the great circle is a meridian or the equator.
+func FinalBearing(a, b Point) float64 {
+	return math.Mod(InitialBearing(b, a)+180, 360)
+}
+
+// Destination returns the point reached by following the great circle from p with the initial bearing for the
+// distance in metres.
+func Destination(p Point, bearing, distance float64) Point {
+	phi1, lambda1, theta := radians(p.Latitude), radians(p.Longitude), radians(bearing)
+	delta := distance / EarthRadius
+	phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+	lambda2 := lambda1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1),
+		math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2))
+	return Point{Latitude: degrees(phi2), Longitude: normaliseLongitude(lambda2)}
+}
+
+// CrossTrackDistance returns the distance in metres from p to the great circle through start and end. It is positive
+// when p is to the right of the great circle, as seen travelling from start to end, and negative when it is to the
+// left.
+func CrossTrackDistance(p, start, end Point) float64 {
+	delta13 := angularDistance(start, p)
+	theta13, theta12 := initialBearing(start, p), initialBearing(start, end)
+	return EarthRadius * math.Asin(math.Sin(delta13)*math.Sin(theta13-theta12))
+}
+
+// AlongTrackDistance returns the distance in metres from start, along the great circle through start and end, to the
+// point on it closest to p. It is negative when that point is behind start.
+func AlongTrackDistance(p, start, end Point) float64 {
+	delta13 := angularDistance(start, p)
+	theta13, theta12 := initialBearing(start, p), initialBearing(start, end)
+	deltaXt := math.Asin(math.Sin(delta13) * math.Sin(theta13-theta12))
+	deltaAt := math.Acos(max(-1, min(1, math.Cos(delta13)/math.Cos(deltaXt))))
+	if math.Cos(theta12-theta13) < 0 {
+		deltaAt = -deltaAt
+	}
+	return EarthRadius * deltaAt
+}
+
+// mercator returns the difference in latitude of the points on a Mercator projection.
+func mercator(phi1, phi2 float64) float64 {
+	return math.Log(math.Tan(math.Pi/4+phi2/2) / math.Tan(math.Pi/4+phi1/2))
+}
+
+// stretch returns the ratio of the difference in latitude to the projected difference in latitude, which is the cosine
+// of the latitude on east–west rhumb lines.
+func stretch(phi1, dPhi, dPsi float64) float64 {
+	if math.Abs(dPsi) > 1e-12 {
+		return dPhi / dPsi
+	}
+	return math.Cos(phi1)
+}
+
+// RhumbDistance returns the distance in metres from a to b along the rhumb line, the line of constant bearing, which
+// is longer than the great circle except along meridians and the equator. Rhumb lines cross the antimeridian when
+// that is shorter.
+func RhumbDistance(a, b Point) float64 {
+	phi1, phi2 := radians(a.Latitude), radians(b.Latitude)
+	dPhi := phi2 - phi1
+	dLambda := radians(math.Remainder(b.Longitude-a.Longitude, 360))
+	q := stretch(phi1, dPhi, mercator(phi1, phi2))
+	return EarthRadius * math.Hypot(dPhi, q*dLambda)
+}
+
+// RhumbBearing returns the constant bearing of the rhumb line from a to b.
+func RhumbBearing(a, b Point) float64 {
+	phi1, phi2 := radians(a.Latitude), radians(b.Latitude)
+	dLambda := radians(math.Remainder(b.Longitude-a.Longitude, 360))
+	return normaliseBearing(math.Atan2(dLambda, mercator(phi1, phi2)))
+}
+
+// RhumbDestination returns the point reached by following the rhumb line from p with the bearing for the distance in
+// metres.
+func RhumbDestination(p Point, bearing, distance float64) Point {
+	phi1, lambda1, theta := radians(p.Latitude), radians(p.Longitude), radians(bearing)
+	delta := distance / EarthRadius
+	dPhi := delta * math.Cos(theta)
+	phi2 := phi1 + dPhi
+	// Rhumb lines which pass a pole continue on the other side of it
+	if phi2 > math.Pi/2 {
+		phi2 = math.Pi - phi2
+	} else if phi2 < -math.Pi/2 {
+		phi2 = -math.Pi - phi2
+	}
+	q := stretch(phi1, dPhi, mercator(phi1, phi2))
+	lambda2 := lambda1 + delta*math.Sin(theta)/q
+	return Point{Latitude: degrees(phi2), Longitude: normaliseLongitude(lambda2)}
+}
@@ -0,0 +1,105 @@
+package navmath_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ilder-as/go-barentswatch-ais/navmath"
+)
+
+// degree is the length of a degree of a great circle in metres.
+const degree = navmath.EarthRadius * math.Pi / 180
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestDistanceAndBearing(t *testing.T) {
+	tests := []struct {
+		name           string
+		a, b           navmath.Point
+		distance       float64
+		initial, final float64
+	}{
+		{"equator", navmath.Point{0, 0}, navmath.Point{0, 1}, degree, 90, 90},
+		{"meridian", navmath.Point{60, 10}, navmath.Point{59, 10}, degree, 180, 180},
+		{"antimeridian", navmath.Point{0, 179.5}, navmath.Point{0, -179.5}, degree, 90, 90},
+		{"baghdad osaka", navmath.Point{35, 45}, navmath.Point{35, 135}, 7_871_780, 60.16, 119.84},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if d := navmath.Distance(test.a, test.b); !near(d, test.distance, 1000) {
+				t.Errorf("expected a distance of %f, got %f", test.distance, d)
+			}
+			if b := navmath.InitialBearing(test.a, test.b); !near(b, test.initial, 0.01) {
+				t.Errorf("expected an initial bearing of %f, got %f", test.initial, b)
+			}
+			if b := navmath.FinalBearing(test.a, test.b); !near(b, test.final, 0.01) {
+				t.Errorf("expected a final bearing of %f, got %f", test.final, b)
+			}
+		})
+	}
+}
+
+func TestDestination(t *testing.T) {
+	from, to := navmath.Point{Latitude: 69.65, Longitude: 18.96}, navmath.Point{Latitude: 78.22, Longitude: 15.65}
+	p := navmath.Destination(from, navmath.InitialBearing(from, to), navmath.Distance(from, to))
+	if !near(p.Latitude, to.Latitude, 1e-9) || !near(p.Longitude, to.Longitude, 1e-9) {
+		t.Errorf("expected %+v, got %+v", to, p)
+	}
+	if p := navmath.Destination(navmath.Point{Latitude: 0, Longitude: 179.5}, 90, degree); !near(p.Longitude, -179.5, 1e-9) {
+		t.Errorf("expected the longitude to wrap at the antimeridian, got %+v", p)
+	}
+}
+
+func TestRhumb(t *testing.T) {
+	from, to := navmath.Point{Latitude: 60, Longitude: 5}, navmath.Point{Latitude: 60, Longitude: 25}
+	// Along a parallel, the rhumb line is due east and as long as the parallel, which is longer than the great circle
+	if b := navmath.RhumbBearing(from, to); !near(b, 90, 1e-9) {
+		t.Errorf("expected a bearing of 90, got %f", b)
+	}
+	if d := navmath.RhumbDistance(from, to); !near(d, 20*degree/2, 1e-6) || d <= navmath.Distance(from, to) {
+		t.Errorf("expected the length of the parallel, got %f", d)
+	}
+
+	to = navmath.Point{Latitude: 71.1, Longitude: -170}
+	p := navmath.RhumbDestination(from, navmath.RhumbBearing(from, to), navmath.RhumbDistance(from, to))
+	if !near(p.Latitude, to.Latitude, 1e-9) || !near(p.Longitude, to.Longitude, 1e-9) {
+		t.Errorf("expected %+v, got %+v", to, p)
+	}
+	if b := navmath.RhumbBearing(navmath.Point{Longitude: 179}, navmath.Point{Longitude: -179}); !near(b, 90, 1e-9) {
+		t.Errorf("expected the rhumb line to cross the antimeridian, got a bearing of %f", b)
+	}
+}
+
+func TestTrackDistances(t *testing.T) {
+	start, end := navmath.Point{Latitude: 0, Longitude: 0}, navmath.Point{Latitude: 0, Longitude: 10}
+	tests := []struct {
+		name         string
+		p            navmath.Point
+		cross, along float64
+	}{
+		{"left", navmath.Point{Latitude: 1, Longitude: 5}, -degree, 5 * degree},
+		{"right", navmath.Point{Latitude: -1, Longitude: 5}, degree, 5 * degree},
+		{"behind", navmath.Point{Latitude: 0, Longitude: -2}, 0, -2 * degree},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if d := navmath.CrossTrackDistance(test.p, start, end); !near(d, test.cross, 1) {
+				t.Errorf("expected a cross-track distance of %f, got %f", test.cross, d)
+			}
+			if d := navmath.AlongTrackDistance(test.p, start, end); !near(d, test.along, 1) {
+				t.Errorf("expected an along-track distance of %f, got %f", test.along, d)
+			}
+		})
+	}
+}
+
+func TestUnits(t *testing.T) {
+	if v := navmath.KnotsToMetresPerSecond(10); !near(v, 5.144, 1e-3) || !near(navmath.MetresPerSecondToKnots(v), 10, 1e-9) {
+		t.Errorf("unexpected speed %f", v)
+	}
+	if d := navmath.NauticalMilesToMetres(2); d != 3704 || navmath.MetresToNauticalMiles(d) != 2 {
+		t.Errorf("unexpected distance %f", d)
+	}
+}
@@ -0,0 +1,179 @@
+package shiptype
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Category is a coarse grouping of ship types, such as all tankers or all passenger vessels, regardless of hazardous
+// category.
+type Category int
+
+const (
+	CategoryUnknown Category = iota
+	CategoryWingInGround
+	CategoryFishing
+	CategoryTugTow
+	CategorySpecialCraft
+	CategoryHighSpeedCraft
+	CategoryPassenger
+	CategoryCargo
+	CategoryTanker
+	CategoryOther
+)
+
+var categoryNames = [...]string{
+	CategoryUnknown:        "Unknown",
+	CategoryWingInGround:   "WingInGround",
+	CategoryFishing:        "Fishing",
+	CategoryTugTow:         "TugTow",
+	CategorySpecialCraft:   "SpecialCraft",
+	CategoryHighSpeedCraft: "HighSpeedCraft",
+	CategoryPassenger:      "Passenger",
+	CategoryCargo:          "Cargo",
+	CategoryTanker:         "Tanker",
+	CategoryOther:          "Other",
+}
+
+// Categories returns all categories, except CategoryUnknown.
+func Categories() []Category {
+	return []Category{
+		CategoryWingInGround,
+		CategoryFishing,
+		CategoryTugTow,
+		CategorySpecialCraft,
+		CategoryHighSpeedCraft,
+		CategoryPassenger,
+		CategoryCargo,
+		CategoryTanker,
+		CategoryOther,
+	}
+}
+
+// Category returns the category the ship type belongs to.
+//
+// Ship types which are not available, reserved for future use in the 1-19 range, or out of bounds belong to
+// CategoryUnknown. Dredging, diving and military operations are grouped with the vessels in the 50-59 range (except
+// tugs) as special craft, while sailing and pleasure craft are grouped with the 90-99 range as other.
+func (s ShipType) Category() Category {
+	switch {
+	case s >= 20 && s <= 29:
+		return CategoryWingInGround
+	case s == Fishing:
+		return CategoryFishing
+	case s == Towing, s == TowingLengthExceeds200MOrBreadthExceeds25M, s == Tug:
+		return CategoryTugTow
+	case s >= 33 && s <= 35, s >= 50 && s <= 59:
+		return CategorySpecialCraft
+	case s >= 40 && s <= 49:
+		return CategoryHighSpeedCraft
+	case s >= 60 && s <= 69:
+		return CategoryPassenger
+	case s >= 70 && s <= 79:
+		return CategoryCargo
+	case s >= 80 && s <= 89:
+		return CategoryTanker
+	case s >= 36 && s <= 39, s >= 90 && s <= 99:
+		return CategoryOther
+	default:
+		return CategoryUnknown
+	}
+}
+
+// ShipTypesIn returns all ship types belonging to any of the supplied categories, in ascending order. The result can
+// be used directly as the ShipTypes of a filter, e.g. ShipTypesIn(CategoryTanker) for all tankers.
+func ShipTypesIn(categories ...Category) []ShipType {
+	var res []ShipType
+	for s := ShipType(0); s.Valid(); s++ {
+		c := s.Category()
+		for _, category := range categories {
+			if c == category {
+				res = append(res, s)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// String returns the name of the category, e.g. "Tanker".
+func (c Category) String() string {
+	if c < 0 || int(c) >= len(categoryNames) {
+		return fmt.Sprintf("Category(%d)", int(c))
+	}
+	return categoryNames[c]
+}
+
+// ParseCategory returns the category with the given name. Names are matched case-insensitively.
+func ParseCategory(s string) (Category, error) {
+	s = strings.TrimSpace(s)
+	for i, name := range categoryNames {
+		if strings.EqualFold(name, s) {
+			return Category(i), nil
+		}
+	}
+	return CategoryUnknown, fmt.Errorf("unknown ship type category: %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Category) MarshalText() ([]byte, error) {
+	if c < 0 || int(c) >= len(categoryNames) {
+		return nil, fmt.Errorf("ship type category out of bounds: %d", int(c))
+	}
+	return []byte(categoryNames[c]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Category) UnmarshalText(text []byte) error {
+	category, err := ParseCategory(string(text))
+	if err != nil {
+		return err
+	}
+	*c = category
+	return nil
+}
+
+// HazardCategory is the hazardous category (A-D) of ship types carrying dangerous goods, harmful substances or marine
+// pollutants.
+type HazardCategory int
+
+const (
+	HazardNone HazardCategory = iota
+	HazardA
+	HazardB
+	HazardC
+	HazardD
+)
+
+// HazardCategory returns the hazardous category of the ship type, or HazardNone if the ship type does not declare one.
+//
+// Hazardous categories are declared by the second digit 1-4 in the wing in ground, high speed craft, passenger, cargo,
+// tanker and other type ranges, e.g. TankerHazardousCategoryB.
+func (s ShipType) HazardCategory() HazardCategory {
+	if !s.Valid() {
+		return HazardNone
+	}
+	switch s / 10 {
+	case 2, 4, 6, 7, 8, 9:
+		if d := s % 10; d >= 1 && d <= 4 {
+			return HazardCategory(d)
+		}
+	}
+	return HazardNone
+}
+
+// String returns the letter of the hazardous category, or an empty string for HazardNone.
+func (h HazardCategory) String() string {
+	switch h {
+	case HazardA:
+		return "A"
+	case HazardB:
+		return "B"
+	case HazardC:
+		return "C"
+	case HazardD:
+		return "D"
+	default:
+		return ""
+	}
+}
@@ -1,5 +1,12 @@
 package shiptype
 
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type ShipType int
 
 const (
@@ -105,6 +112,181 @@ const (
 	OtherTypeNoAdditionalInformation
 )
 
+// names holds the identifier of each ship type, indexed by ship type code. It is used for text marshalling.
+var names = [...]string{
+	"NotAvailable",
+	"ReservedForFutureUse1",
+	"ReservedForFutureUse2",
+	"ReservedForFutureUse3",
+	"ReservedForFutureUse4",
+	"ReservedForFutureUse5",
+	"ReservedForFutureUse6",
+	"ReservedForFutureUse7",
+	"ReservedForFutureUse8",
+	"ReservedForFutureUse9",
+	"ReservedForFutureUse10",
+	"ReservedForFutureUse11",
+	"ReservedForFutureUse12",
+	"ReservedForFutureUse13",
+	"ReservedForFutureUse14",
+	"ReservedForFutureUse15",
+	"ReservedForFutureUse16",
+	"ReservedForFutureUse17",
+	"ReservedForFutureUse18",
+	"ReservedForFutureUse19",
+	"WingInGroundAllShips",
+	"WingInGroundHazardousCategoryA",
+	"WingInGroundHazardousCategoryB",
+	"WingInGroundHazardousCategoryC",
+	"WingInGroundHazardousCategoryD",
+	"WingInGroundReservedForFutureUse1",
+	"WingInGroundReservedForFutureUse2",
+	"WingInGroundReservedForFutureUse3",
+	"WingInGroundReservedForFutureUse4",
+	"WingInGroundReservedForFutureUse5",
+	"Fishing",
+	"Towing",
+	"TowingLengthExceeds200MOrBreadthExceeds25M",
+	"DredgingOrUnderwaterOps",
+	"DivingOps",
+	"MilitaryOps",
+	"Sailing",
+	"PleasureCraft",
+	"Reserved1",
+	"Reserved2",
+	"HighSpeedCraftAllShips",
+	"HighSpeedCraftHazardousCategoryA",
+	"HighSpeedCraftHazardousCategoryB",
+	"HighSpeedCraftHazardousCategoryC",
+	"HighSpeedCraftHazardousCategoryD",
+	"HighSpeedCraftReservedForFutureUse1",
+	"HighSpeedCraftReservedForFutureUse2",
+	"HighSpeedCraftReservedForFutureUse3",
+	"HighSpeedCraftReservedForFutureUse4",
+	"HighSpeedCraftNoAdditionalInformation",
+	"PilotVessel",
+	"SearchAndRescueVessel",
+	"Tug",
+	"PortTender",
+	"AntiPollutionEquipment",
+	"LawEnforcement",
+	"SpareLocalVessel1",
+	"SpareLocalVessel2",
+	"MedicalTransport",
+	"NoncombatantShip",
+	"PassengerAllShips",
+	"PassengerHazardousCategoryA",
+	"PassengerHazardousCategoryB",
+	"PassengerHazardousCategoryC",
+	"PassengerHazardousCategoryD",
+	"PassengerReservedForFutureUse1",
+	"PassengerReservedForFutureUse2",
+	"PassengerReservedForFutureUse3",
+	"PassengerReservedForFutureUse4",
+	"PassengerNoAdditionalInformation",
+	"CargoAllShips",
+	"CargoHazardousCategoryA",
+	"CargoHazardousCategoryB",
+	"CargoHazardousCategoryC",
+	"CargoHazardousCategoryD",
+	"CargoReservedForFutureUse1",
+	"CargoReservedForFutureUse2",
+	"CargoReservedForFutureUse3",
+	"CargoReservedForFutureUse4",
+	"CargoNoAdditionalInformation",
+	"TankerAllShips",
+	"TankerHazardousCategoryA",
+	"TankerHazardousCategoryB",
+	"TankerHazardousCategoryC",
+	"TankerHazardousCategoryD",
+	"TankerReservedForFutureUse1",
+	"TankerReservedForFutureUse2",
+	"TankerReservedForFutureUse3",
+	"TankerReservedForFutureUse4",
+	"TankerNoAdditionalInformation",
+	"OtherTypeAllShips",
+	"OtherTypeHazardousCategoryA",
+	"OtherTypeHazardousCategoryB",
+	"OtherTypeHazardousCategoryC",
+	"OtherTypeHazardousCategoryD",
+	"OtherTypeReservedForFutureUse1",
+	"OtherTypeReservedForFutureUse2",
+	"OtherTypeReservedForFutureUse3",
+	"OtherTypeReservedForFutureUse4",
+	"OtherTypeNoAdditionalInformation",
+}
+
+// Valid is true iff the ship type is within the range of codes defined by ITU-R M.1371 (0-99).
+func (s ShipType) Valid() bool {
+	return s >= 0 && int(s) < len(names)
+}
+
+// String returns the name of the ship type, e.g. "TankerHazardousCategoryA", or the numeric code if the ship type is
+// out of bounds.
+func (s ShipType) String() string {
+	if !s.Valid() {
+		return strconv.Itoa(int(s))
+	}
+	return names[s]
+}
+
+// Parse returns the ship type with the given name or numeric code. Names are matched case-insensitively.
+func Parse(s string) (ShipType, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		if !ShipType(n).Valid() {
+			return NotAvailable, fmt.Errorf("ship type out of bounds: %d", n)
+		}
+		return ShipType(n), nil
+	}
+	for i, name := range names {
+		if strings.EqualFold(name, s) {
+			return ShipType(i), nil
+		}
+	}
+	return NotAvailable, fmt.Errorf("unknown ship type: %q", s)
+}
+
+// MarshalText implements encoding.TextMarshaler. Ship types are marshalled as their names, so that they are readable
+// in e.g. configuration files and text exports.
+func (s ShipType) MarshalText() ([]byte, error) {
+	if !s.Valid() {
+		return nil, fmt.Errorf("ship type out of bounds: %d", int(s))
+	}
+	return []byte(names[s]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It accepts both names and numeric codes, see Parse.
+func (s *ShipType) UnmarshalText(text []byte) error {
+	t, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*s = t
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts both JSON numbers, as sent by the API, and strings, where
+// strings are parsed as by Parse. Ship types marshal to JSON as their names, by MarshalText.
+func (s *ShipType) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		return s.UnmarshalText([]byte(str))
+	}
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	if !ShipType(n).Valid() {
+		return fmt.Errorf("ship type out of bounds: %d", n)
+	}
+	*s = ShipType(n)
+	return nil
+}
+
 func (s ShipType) Description() string {
 	var desc string
 
@@ -0,0 +1,110 @@
+package shiptype_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ilder-as/go-barentswatch-ais/shiptype"
+)
+
+func TestShipType_Category(t *testing.T) {
+	cases := map[shiptype.ShipType]shiptype.Category{
+		shiptype.NotAvailable:                     shiptype.CategoryUnknown,
+		shiptype.ReservedForFutureUse5:            shiptype.CategoryUnknown,
+		shiptype.WingInGroundHazardousCategoryA:   shiptype.CategoryWingInGround,
+		shiptype.Fishing:                          shiptype.CategoryFishing,
+		shiptype.Tug:                              shiptype.CategoryTugTow,
+		shiptype.MilitaryOps:                      shiptype.CategorySpecialCraft,
+		shiptype.PilotVessel:                      shiptype.CategorySpecialCraft,
+		shiptype.HighSpeedCraftAllShips:           shiptype.CategoryHighSpeedCraft,
+		shiptype.PassengerNoAdditionalInformation: shiptype.CategoryPassenger,
+		shiptype.CargoHazardousCategoryD:          shiptype.CategoryCargo,
+		shiptype.TankerAllShips:                   shiptype.CategoryTanker,
+		shiptype.PleasureCraft:                    shiptype.CategoryOther,
+		shiptype.ShipType(100):                    shiptype.CategoryUnknown,
+	}
+	for s, expected := range cases {
+		if c := s.Category(); c != expected {
+			t.Errorf("expected %s to be in category %s, got %s", s, expected, c)
+		}
+	}
+}
+
+func TestShipTypesIn(t *testing.T) {
+	tankers := shiptype.ShipTypesIn(shiptype.CategoryTanker)
+	if len(tankers) != 10 || tankers[0] != shiptype.TankerAllShips || tankers[9] != shiptype.TankerNoAdditionalInformation {
+		t.Errorf("unexpected tanker ship types: %v", tankers)
+	}
+
+	if n := len(shiptype.ShipTypesIn(shiptype.CategoryTugTow, shiptype.CategoryFishing)); n != 4 {
+		t.Errorf("expected 4 tug, tow and fishing ship types, got %d", n)
+	}
+
+	total := 0
+	for _, c := range append(shiptype.Categories(), shiptype.CategoryUnknown) {
+		total += len(shiptype.ShipTypesIn(c))
+	}
+	if total != 100 {
+		t.Errorf("expected categories to cover all 100 ship types, covered %d", total)
+	}
+}
+
+func TestShipType_HazardCategory(t *testing.T) {
+	cases := map[shiptype.ShipType]shiptype.HazardCategory{
+		shiptype.TankerHazardousCategoryB:       shiptype.HazardB,
+		shiptype.WingInGroundHazardousCategoryD: shiptype.HazardD,
+		shiptype.CargoAllShips:                  shiptype.HazardNone,
+		shiptype.DivingOps:                      shiptype.HazardNone,
+		shiptype.SearchAndRescueVessel:          shiptype.HazardNone,
+	}
+	for s, expected := range cases {
+		if h := s.HazardCategory(); h != expected {
+			t.Errorf("expected %s to have hazard category %q, got %q", s, expected, h)
+		}
+	}
+}
+
+func TestShipType_Text(t *testing.T) {
+	text, err := shiptype.TankerHazardousCategoryA.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "TankerHazardousCategoryA" {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	for _, in := range []string{"TankerHazardousCategoryA", "tankerhazardouscategorya", "81"} {
+		var s shiptype.ShipType
+		if err := s.UnmarshalText([]byte(in)); err != nil {
+			t.Errorf("unable to unmarshal %q: %s", in, err)
+		} else if s != shiptype.TankerHazardousCategoryA {
+			t.Errorf("expected %q to unmarshal to TankerHazardousCategoryA, got %s", in, s)
+		}
+	}
+
+	var s shiptype.ShipType
+	if err := s.UnmarshalText([]byte("Submarine")); err == nil {
+		t.Error("expected error for unknown ship type")
+	}
+}
+
+func TestShipType_JSON(t *testing.T) {
+	data, err := json.Marshal([]shiptype.ShipType{shiptype.Fishing, shiptype.Tug})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `["Fishing","Tug"]` {
+		t.Errorf("expected ship types to marshal as names, got %s", data)
+	}
+
+	var types []shiptype.ShipType
+	if err := json.Unmarshal([]byte(`[30, "Tug"]`), &types); err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 2 || types[0] != shiptype.Fishing || types[1] != shiptype.Tug {
+		t.Errorf("unexpected ship types %v", types)
+	}
+	if err := json.Unmarshal([]byte(`[1000]`), &types); err == nil {
+		t.Error("expected error for unknown ship type code")
+	}
+}